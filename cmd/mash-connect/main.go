@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"os"
+	"strings"
+	"time"
+)
+
+const credentialsFileOpt = "credentials-file"
+const credentialsPasswordEnvOpt = "credentials-password-env"
+const tokenFileOpt = "token-file"
+const endpointOpt = "endpoint"
+const shellOpt = "shell"
+const areaIdOpt = "area-id"
+const apiKeyOpt = "api-key"
+const secretOpt = "secret"
+const usernameOpt = "username"
+const passwordOpt = "password"
+const helpOpt = "help"
+
+var credentialsFile string
+var envCredentialsPassword string
+var tokenFile string
+var endpoint string
+var shell string
+var cliCredentials v3client.MasheryV3Credentials
+var showHelp bool
+
+type CommandFunc func() error
+
+var commands = map[string]CommandFunc{
+	"init":    initCommand,
+	"show":    showCommand,
+	"export":  exportCommand,
+	"refresh": refreshCommand,
+}
+
+// profile derives the credentials from the environment, the credentials file, and the command line, together with
+// the token endpoint and the TLS options of the profile selected in the credentials file.
+func profile() (v3client.CredentialsProfile, error) {
+	if p, err := v3client.DeriveAccessProfile(credentialsFile, os.Getenv(envCredentialsPassword), &cliCredentials); err != nil {
+		return p, fmt.Errorf("credentials file %s could not be used: %s", credentialsFile, err)
+	} else {
+		return p, nil
+	}
+}
+
+// oauthHelper creates the helper connecting to the token endpoint of the profile with its TLS options. The endpoint
+// supplied on the command line takes precedence.
+func oauthHelper(p *v3client.CredentialsProfile) (*v3client.V3OAuthHelper, error) {
+	params, err := p.OAuthHelperParams()
+	if err != nil {
+		return nil, fmt.Errorf("token endpoint connection could not be configured: %s", err)
+	}
+
+	if len(endpoint) > 0 {
+		params.MasheryTokenEndpoint = endpoint
+	}
+	return v3client.NewOAuthHelper(params), nil
+}
+
+// loadToken reads the saved token; it is an error if no token was saved.
+func loadToken() (*masherytypes.TimedAccessTokenResponse, error) {
+	if resp, err := v3client.LoadV3TokenResponse(tokenFile); err != nil {
+		return nil, fmt.Errorf("token file %s could not be read: %s", tokenFile, err)
+	} else if resp == nil {
+		return nil, fmt.Errorf("no access token is saved in %s; run mash-connect init", tokenFile)
+	} else {
+		return resp, nil
+	}
+}
+
+func saveToken(resp *masherytypes.TimedAccessTokenResponse) error {
+	if err := v3client.PersistV3TokenResponse(resp, tokenFile); err != nil {
+		return fmt.Errorf("token could not be saved to %s: %s", tokenFile, err)
+	}
+
+	fmt.Printf("Access token saved to %s; expires at %s", tokenFile, resp.ExpiryTime().Format(time.RFC1123))
+	fmt.Println()
+	return nil
+}
+
+func initCommand() error {
+	p, err := profile()
+	if err != nil {
+		return err
+	} else if !p.FullySpecified() {
+		return errors.New("credentials are incomplete: area id, API key, secret, username and password are required")
+	}
+
+	helper, err := oauthHelper(&p)
+	if err != nil {
+		return err
+	}
+
+	if resp, err := helper.RetrieveAccessTokenFor(&p.MasheryV3Credentials); err != nil {
+		return fmt.Errorf("access token could not be obtained: %s", err)
+	} else {
+		return saveToken(resp)
+	}
+}
+
+func showCommand() error {
+	resp, err := loadToken()
+	if err != nil {
+		return err
+	}
+
+	left := resp.TimeLeft()
+	if left == 0 {
+		return fmt.Errorf("access token has expired at %s; run mash-connect refresh or mash-connect init",
+			resp.ExpiryTime().Format(time.RFC1123))
+	}
+
+	fmt.Printf("Area:       %s", resp.Scope)
+	fmt.Println()
+	fmt.Printf("Expires at: %s", resp.ExpiryTime().Format(time.RFC1123))
+	fmt.Println()
+	fmt.Printf("Time left:  %s", time.Duration(left)*time.Second)
+	fmt.Println()
+	return nil
+}
+
+func exportCommand() error {
+	formatter, ok := shellFormatters[shell]
+	if !ok {
+		return fmt.Errorf("unsupported shell %s; supported shells are %s", shell, strings.Join(supportedShells(), ", "))
+	}
+
+	resp, err := loadToken()
+	if err != nil {
+		return err
+	} else if resp.TimeLeft() == 0 {
+		return errors.New("access token has expired; run mash-connect refresh or mash-connect init")
+	}
+
+	if stmt, err := formatter(v3client.AccessTokenEnv, resp.AccessToken); err != nil {
+		return err
+	} else {
+		fmt.Println(stmt)
+		return nil
+	}
+}
+
+func refreshCommand() error {
+	resp, err := loadToken()
+	if err != nil {
+		return err
+	} else if len(resp.RefreshToken) == 0 {
+		return errors.New("saved token has no refresh token; run mash-connect init")
+	}
+
+	p, err := profile()
+	if err != nil {
+		return err
+	} else if len(p.ApiKey) == 0 || len(p.Secret) == 0 {
+		return errors.New("API key and secret are required to refresh the token")
+	}
+
+	helper, err := oauthHelper(&p)
+	if err != nil {
+		return err
+	}
+
+	if refreshed, err := helper.ExchangeRefreshToken(&p.MasheryV3Credentials, resp.RefreshToken); err != nil {
+		return fmt.Errorf("access token could not be refreshed: %s", err)
+	} else {
+		return saveToken(refreshed)
+	}
+}
+
+func main() {
+	flag.StringVar(&credentialsFile, credentialsFileOpt, v3client.DefaultCredentialsFile(), "Encrypted YAML file with the Mashery V3 credentials")
+	flag.StringVar(&envCredentialsPassword, credentialsPasswordEnvOpt, "MASHERY_CREDENTIALS_PASSWORD", "An environment variable containing the passphrase of the credentials file")
+	flag.StringVar(&tokenFile, tokenFileOpt, v3client.DefaultSavedAccessTokenFilePath(), "File the access token is saved to")
+	flag.StringVar(&endpoint, endpointOpt, "", "A non-standard token endpoint to connect to; defaults to the token endpoint of the profile, or "+v3client.MasheryTokenEndpoint)
+	flag.StringVar(&shell, shellOpt, "bash", "Shell to format the export statements for: "+strings.Join(supportedShells(), ", "))
+	flag.StringVar(&cliCredentials.AreaId, areaIdOpt, "", "Mashery area id")
+	flag.StringVar(&cliCredentials.ApiKey, apiKeyOpt, "", "Mashery V3 API key")
+	flag.StringVar(&cliCredentials.Secret, secretOpt, "", "Mashery V3 API key secret")
+	flag.StringVar(&cliCredentials.Username, usernameOpt, "", "Mashery user name")
+	flag.StringVar(&cliCredentials.Password, passwordOpt, "", "Mashery user password")
+	flag.BoolVar(&showHelp, helpOpt, false, "Show help options")
+	flag.Parse()
+
+	if showHelp {
+		flag.PrintDefaults()
+		os.Exit(0)
+	}
+
+	if flag.NArg() != 1 {
+		fmt.Println("Sub-command required: init, show, export or refresh")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Printf("Unrecognized command %s", flag.Arg(0))
+		fmt.Println()
+		os.Exit(1)
+	}
+
+	if err := cmd(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ShellFormatter Formats the statement setting the environment variable in a specific shell. An error is returned
+// where the value cannot be expressed safely in the shell.
+type ShellFormatter func(name, value string) (string, error)
+
+var shellFormatters = map[string]ShellFormatter{
+	"sh":         posixExport,
+	"bash":       posixExport,
+	"zsh":        posixExport,
+	"fish":       fishExport,
+	"powershell": powershellExport,
+	"cmd":        cmdExport,
+}
+
+// supportedShells returns the names of the shells the export statements can be formatted for
+func supportedShells() []string {
+	rv := make([]string, 0, len(shellFormatters))
+	for k := range shellFormatters {
+		rv = append(rv, k)
+	}
+	sort.Strings(rv)
+	return rv
+}
+
+func posixExport(name, value string) (string, error) {
+	return fmt.Sprintf("export %s='%s'", name, strings.ReplaceAll(value, "'", `'\''`)), nil
+}
+
+func fishExport(name, value string) (string, error) {
+	v := strings.ReplaceAll(value, `\`, `\\`)
+	v = strings.ReplaceAll(v, "'", `\'`)
+	return fmt.Sprintf("set -gx %s '%s'", name, v), nil
+}
+
+func powershellExport(name, value string) (string, error) {
+	return fmt.Sprintf("$env:%s = '%s'", name, strings.ReplaceAll(value, "'", "''")), nil
+}
+
+// cmdExport Within the quotes of the set statement, cmd.exe takes ^, &, |, < and > literally. A double quote would
+// end the quoting, while % is expanded even within the quotes and has no escape that works both at the prompt and
+// in a batch file; values containing these are rejected.
+func cmdExport(name, value string) (string, error) {
+	if strings.ContainsAny(value, `"%`) {
+		return "", errors.New("value containing \" or % cannot be set safely in cmd; use powershell instead")
+	}
+	return fmt.Sprintf(`set "%s=%s"`, name, value), nil
+}
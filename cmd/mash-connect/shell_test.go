@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func exported(t *testing.T, shell, value string) string {
+	stmt, err := shellFormatters[shell]("MASHERY_V3_TOKEN", value)
+	assert.Nil(t, err)
+	return stmt
+}
+
+func TestShellExportQuotesValue(t *testing.T) {
+	assert.Equal(t, `export MASHERY_V3_TOKEN='a'\''b'`, exported(t, "bash", "a'b"))
+	assert.Equal(t, `set -gx MASHERY_V3_TOKEN 'a\'b\\c'`, exported(t, "fish", `a'b\c`))
+	assert.Equal(t, `$env:MASHERY_V3_TOKEN = 'a''b'`, exported(t, "powershell", "a'b"))
+	assert.Equal(t, `set "MASHERY_V3_TOKEN=abc"`, exported(t, "cmd", "abc"))
+}
+
+func TestCmdExportRejectsUnquotableValues(t *testing.T) {
+	// Within the quotes, the caret and the ampersand are literal
+	assert.Equal(t, `set "MASHERY_V3_TOKEN=a^b&c"`, exported(t, "cmd", "a^b&c"))
+
+	for _, v := range []string{`a"b`, "a%b%"} {
+		_, err := shellFormatters["cmd"]("MASHERY_V3_TOKEN", v)
+		assert.NotNil(t, err)
+	}
+}
+
+func TestSupportedShellsAreSorted(t *testing.T) {
+	assert.Equal(t, []string{"bash", "cmd", "fish", "powershell", "sh", "zsh"}, supportedShells())
+}
@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"log/slog"
 	"os"
 	"strings"
 	"time"
@@ -25,6 +27,15 @@ const qpsOps = "qps"
 const outputJsonOps = "as-json"
 const helpOpt = "help"
 const verboseTrafficOpt = "verbose-traffic"
+const harFileOpt = "har-file"
+const tlsPinFileOpt = "tls-pin-file"
+const clientCertOpt = "client-cert"
+const clientKeyOpt = "client-key"
+const caBundleOpt = "ca-bundle"
+const dryRunOpt = "dry-run"
+const profileOpt = "profile"
+const credentialsFileOpt = "credentials-file"
+const credentialsPasswordEnvOpt = "credentials-password-env"
 
 var qps int64
 var travelTimeComp string
@@ -36,6 +47,16 @@ var envVaultTokenResource string
 var globalOptOutputJson bool
 var showHelp bool
 var showVerboseTraffic bool
+var harFile string
+var tlsPinFile string
+var clientCertFile string
+var clientKeyFile string
+var caBundleFile string
+var dryRun bool
+var harRecorder *transport.HarRecorder
+var profile string
+var credentialsFile string
+var envCredentialsPassword string
 var jsonEncoder *json.Encoder
 
 type ExecutorFunc func(context.Context, v3client.Client, []string) int
@@ -59,7 +80,9 @@ func authorizer() (transport.Authorizer, error) {
 	vaultToken := transport.VaultToken(os.Getenv(envVaultToken))
 
 	if len(vaultTokenResource) > 0 && len(vaultToken) > 0 && len(endpoint) == 0 {
-		return transport.NewVaultTokenResourceAuthorizer(vaultTokenResource, vaultToken), nil
+		return transport.NewVaultTokenResourceAuthorizerWithParams(vaultTokenResource, vaultToken, transport.HTTPClientParams{
+			TLS: tlsOptions(),
+		}), nil
 	}
 
 	if len(vaultToken) > 0 && len(endpoint) > 0 {
@@ -94,15 +117,48 @@ func kvArrayToMap(in []string) map[string]string {
 	return rv
 }
 
-func trafficListener(_ context.Context, req *transport.WrappedRequest, res *transport.WrappedResponse, err error) {
+// trafficListener returns the listener logging the exchanges with Mashery where the verbose traffic is requested,
+// and recording them into the HAR file, if specified.
+func trafficListener() transport.ExchangeListener {
+	var logListener, harListener transport.ExchangeListener
+
 	if showVerboseTraffic {
-		fmt.Printf("-> %s %s\n", req.Request.Method, req.Request.URL.String())
-		if req.Body != nil {
-			str, _ := json.Marshal(req.Body)
-			fmt.Println(string(str))
+		logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		logListener = transport.NewTrafficLogger(logger, transport.TrafficBodies).Listen
+	}
+	if len(harFile) > 0 {
+		harRecorder = transport.NewHarRecorder(harFile)
+		harListener = harRecorder.Listen
+	}
+
+	return transport.CombineListeners(logListener, harListener)
+}
+
+// tlsOptions returns the client certificate and the certificate authorities specified on the command line
+func tlsOptions() transport.TLSOptions {
+	rv := transport.TLSOptions{
+		CABundleFile: caBundleFile,
+	}
+	if len(clientCertFile) > 0 || len(clientKeyFile) > 0 {
+		rv.ClientCertificate = &transport.ClientCertificate{
+			CertFile: clientCertFile,
+			KeyFile:  clientKeyFile,
 		}
-		fmt.Println("<-")
-		fmt.Println(string(res.MustBody()))
+	}
+	return rv
+}
+
+// tlsConfig returns the TLS configuration verifying the pins of the pin file, if specified; otherwise nil, selecting
+// the default pins.
+func tlsConfig() (*tls.Config, error) {
+	if len(tlsPinFile) == 0 {
+		return nil, nil
+	}
+
+	if pinner, err := transport.LoadTLSPinner(tlsPinFile); err != nil {
+		return nil, err
+	} else {
+		return pinner.CreateTLSConfig(), nil
 	}
 }
 
@@ -117,6 +173,15 @@ func main() {
 	flag.BoolVar(&globalOptOutputJson, outputJsonOps, false, "Output JSON rather than a pretty-printed template")
 	flag.BoolVar(&showHelp, helpOpt, false, "Show help options")
 	flag.BoolVar(&showVerboseTraffic, verboseTrafficOpt, false, "Show verbose traffic")
+	flag.StringVar(&harFile, harFileOpt, "", "Record the traffic with Mashery into the specified HAR file")
+	flag.StringVar(&tlsPinFile, tlsPinFileOpt, "", "Verify Mashery certificates against the pins in the specified JSON or YAML file")
+	flag.StringVar(&clientCertFile, clientCertOpt, "", "PEM file of the client certificate presented for mutual TLS")
+	flag.StringVar(&clientKeyFile, clientKeyOpt, "", "PEM file of the client certificate key")
+	flag.StringVar(&caBundleFile, caBundleOpt, "", "PEM file of the additional certificate authorities to trust")
+	flag.BoolVar(&dryRun, dryRunOpt, false, "Do not send changes to Mashery; print the planned changes instead")
+	flag.StringVar(&profile, profileOpt, os.Getenv(v3client.ProfileEnv), "Connect with the named profile of the credentials file; defaults to "+v3client.ProfileEnv)
+	flag.StringVar(&credentialsFile, credentialsFileOpt, v3client.DefaultCredentialsFile(), "Encrypted credentials file holding the profiles")
+	flag.StringVar(&envCredentialsPassword, credentialsPasswordEnvOpt, "MASHERY_CREDENTIALS_PASSWORD", "An environment variable containing the passphrase of the credentials file")
 	flag.Parse()
 
 	if showHelp {
@@ -131,9 +196,9 @@ func main() {
 		os.Exit(1)
 	}
 
-	execFunc := locateSubCommandExecutor(subCmd)
+	finder := locateSubCommand(subCmd)
 
-	if execFunc == nil {
+	if finder == nil {
 		fmt.Println("Unrecognized command")
 		for _, p := range subCmd {
 			fmt.Println(p)
@@ -141,9 +206,14 @@ func main() {
 		os.Exit(1)
 	}
 
+	execFunc := finder.Executor
+	if finder.Offline {
+		os.Exit(execFunc(context.TODO(), nil, subCmd))
+	}
+
 	// Arguments have been parsed correctly.
-	if tknProvider, err := authorizer(); err != nil {
-		fmt.Printf("Access token provider is not ready: %s", err)
+	if params, err := clientParams(); err != nil {
+		fmt.Printf("Client is not ready: %s", err)
 		fmt.Println()
 		os.Exit(1)
 	} else {
@@ -154,29 +224,113 @@ func main() {
 			dur = 173 * time.Millisecond
 		}
 
-		cl := v3client.NewHttpClient(v3client.Params{
-			MashEndpoint:  endpoint,
-			Authorizer:    tknProvider,
-			QPS:           qps,
-			AvgNetLatency: dur,
-			HTTPClientParams: transport.HTTPClientParams{
-				ExchangeListener: trafficListener,
-			},
-		})
+		var plan *transport.DryRunPlan
+		if dryRun {
+			plan = transport.NewDryRunPlan()
+		}
+
+		params.AvgNetLatency = dur
+		params.ExchangeListener = trafficListener()
+		params.DryRun = plan
+
+		cl, clErr := v3client.NewValidatedHttpClient(params)
+		if clErr != nil {
+			fmt.Printf("Client is not ready: %s", clErr)
+			fmt.Println()
+			os.Exit(1)
+		}
 
 		exitCode := execFunc(ctx, cl, subCmd)
+		if harRecorder != nil {
+			if err := harRecorder.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "HAR file could not be written: %s\n", err)
+			}
+		}
+		if plan != nil {
+			// The plan is printed into stderr so that it does not interfere with the JSON output.
+			fmt.Fprintln(os.Stderr, logHeader)
+			_ = plan.Print(os.Stderr)
+		}
 		os.Exit(exitCode)
 	}
 }
 
-func locateSubCommandExecutor(subCmd []string) ExecutorFunc {
+// clientParams returns the parameters of the client: those of the selected profile, overridden by the endpoint,
+// QPS and TLS options supplied on the command line; otherwise the ones specified by the command line.
+func clientParams() (v3client.Params, error) {
+	if len(profile) > 0 {
+		if params, err := profileParams(); err != nil {
+			return params, fmt.Errorf("profile %s could not be loaded: %s", profile, err)
+		} else {
+			return params, nil
+		}
+	}
+
+	if tknProvider, err := authorizer(); err != nil {
+		return v3client.Params{}, err
+	} else if tlsCfg, err := tlsConfig(); err != nil {
+		return v3client.Params{}, fmt.Errorf("TLS pins could not be loaded: %s", err)
+	} else {
+		return v3client.Params{
+			MashEndpoint: endpoint,
+			Authorizer:   tknProvider,
+			QPS:          qps,
+			HTTPClientParams: transport.HTTPClientParams{
+				TLSConfig: tlsCfg,
+				TLS:       tlsOptions(),
+			},
+		}, nil
+	}
+}
+
+// profileParams returns the parameters of the selected profile. The command-line options override the settings of
+// the profile before the parameters are built, so that the access tokens are retrieved with the same TLS options.
+func profileParams() (v3client.Params, error) {
+	profiles, err := v3client.LoadCredentialsProfiles(credentialsFile, os.Getenv(envCredentialsPassword))
+	if err != nil {
+		return v3client.Params{}, err
+	}
+
+	p, err := profiles.Profile(profile)
+	if err != nil {
+		return v3client.Params{}, err
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case endpointOpt:
+			p.Endpoint = endpoint
+		case qpsOps:
+			p.MaxQPS = int(qps)
+		case caBundleOpt:
+			p.TLS.CABundleFile = caBundleFile
+		case clientCertOpt:
+			p.TLS.ClientCertFile = clientCertFile
+		case clientKeyOpt:
+			p.TLS.ClientKeyFile = clientKeyFile
+		case tlsPinFileOpt:
+			p.TLS.PinFile = tlsPinFile
+		}
+	})
+
+	return p.Params()
+}
+
+func locateSubCommand(subCmd []string) *SubcommandFinder {
 	specificity := 0
-	var execFunc ExecutorFunc
+	var rv *SubcommandFinder
 
 	for _, p := range subCommandFinders {
 		if match := p.Matches(subCmd); match > specificity {
-			execFunc = p.Executor
+			rv = p
 		}
 	}
-	return execFunc
+	return rv
+}
+
+func locateSubCommandExecutor(subCmd []string) ExecutorFunc {
+	if finder := locateSubCommand(subCmd); finder != nil {
+		return finder.Executor
+	}
+	return nil
 }
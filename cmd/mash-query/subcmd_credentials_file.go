@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"gopkg.in/yaml.v2"
+	"io/fs"
+)
+
+var subCmdCredentialsEncrypt *SubcommandTemplate[CredentialsFileArg, string]
+var subCmdCredentialsDecrypt *SubcommandTemplate[CredentialsFileArg, string]
+var subCmdCredentialsRekey *SubcommandTemplate[CredentialsFileArg, string]
+var subCmdCredentialsEdit *SubcommandTemplate[CredentialsFileArg, string]
+
+type CredentialsFileArg struct {
+	File          string
+	Passphrase    string
+	NewPassphrase string
+
+	// Profile edited by the edit command; empty selects the top-level identity
+	Profile string
+	// Values set by the edit command
+	Edit v3client.CredentialsProfile
+}
+
+func initCredentialsFileFlagSet(arg *CredentialsFileArg, fs *flag.FlagSet) {
+	fs.StringVar(&arg.File, "file", v3client.DefaultCredentialsFile(), "Credentials file")
+	fs.StringVar(&arg.Passphrase, "passphrase", "", "Passphrase of the credentials file")
+}
+
+func initCredentialsRekeyFlagSet(arg *CredentialsFileArg, fs *flag.FlagSet) {
+	initCredentialsFileFlagSet(arg, fs)
+	fs.StringVar(&arg.NewPassphrase, "new-passphrase", "", "New passphrase of the credentials file")
+}
+
+func initCredentialsEditFlagSet(arg *CredentialsFileArg, fs *flag.FlagSet) {
+	initCredentialsFileFlagSet(arg, fs)
+	fs.StringVar(&arg.Edit.AreaId, "area-id", "", "Set the area id")
+	fs.StringVar(&arg.Edit.ApiKey, "api-key", "", "Set the API key")
+	fs.StringVar(&arg.Edit.Secret, "secret", "", "Set the API key secret")
+	fs.StringVar(&arg.Edit.Username, "username", "", "Set the user name")
+	fs.StringVar(&arg.Edit.Password, "password", "", "Set the user password")
+	fs.IntVar(&arg.Edit.MaxQPS, "max-qps", 0, "Set the maximum QPS of the API key")
+	fs.StringVar(&arg.Profile, "profile", "", "Edit the named profile, creating it if necessary")
+	fs.StringVar(&arg.Edit.Endpoint, "endpoint", "", "Set the V3 API endpoint")
+	fs.StringVar(&arg.Edit.TokenEndpoint, "token-endpoint", "", "Set the token endpoint")
+	fs.StringVar(&arg.Edit.TLS.CABundleFile, "ca-bundle", "", "Set the PEM file of the additional certificate authorities")
+	fs.StringVar(&arg.Edit.TLS.ClientCertFile, "client-cert", "", "Set the PEM file of the client certificate")
+	fs.StringVar(&arg.Edit.TLS.ClientKeyFile, "client-key", "", "Set the PEM file of the client certificate key")
+	fs.StringVar(&arg.Edit.TLS.PinFile, "tls-pin-file", "", "Set the file of the TLS pins")
+}
+
+func credentialsFileEnvFlags(arg *CredentialsFileArg) []EnvFlag {
+	return []EnvFlag{
+		{Dest: &arg.Passphrase, EnvVar: "MASHERY_CREDENTIALS_PASSWORD", Option: "passphrase"},
+	}
+}
+
+func credentialsRekeyEnvFlags(arg *CredentialsFileArg) []EnvFlag {
+	return append(credentialsFileEnvFlags(arg),
+		EnvFlag{Dest: &arg.NewPassphrase, EnvVar: "MASHERY_CREDENTIALS_NEW_PASSWORD", Option: "new-passphrase"})
+}
+
+func validateCredentialsFile(arg *CredentialsFileArg) error {
+	if len(arg.File) == 0 {
+		return errors.New("credentials file is required")
+	} else if len(arg.Passphrase) == 0 {
+		return errors.New("passphrase is required")
+	}
+	return nil
+}
+
+func validateCredentialsRekey(arg *CredentialsFileArg) error {
+	if err := validateCredentialsFile(arg); err != nil {
+		return err
+	} else if len(arg.NewPassphrase) == 0 {
+		return errors.New("new passphrase is required")
+	}
+	return nil
+}
+
+func execCredentialsEncrypt(_ context.Context, _ v3client.Client, arg CredentialsFileArg) (string, error) {
+	if err := v3client.EncryptInPlace(arg.File, arg.Passphrase); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Credentials file %s encrypted", arg.File), nil
+}
+
+func execCredentialsDecrypt(_ context.Context, _ v3client.Client, arg CredentialsFileArg) (string, error) {
+	if dat, err := v3client.ReadCiphertext(arg.File, arg.Passphrase); err != nil {
+		return "", err
+	} else {
+		return string(dat), nil
+	}
+}
+
+func execCredentialsRekey(_ context.Context, _ v3client.Client, arg CredentialsFileArg) (string, error) {
+	if err := v3client.RekeyCredentialsFile(arg.File, arg.Passphrase, arg.NewPassphrase); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Credentials file %s re-encrypted with the new passphrase", arg.File), nil
+}
+
+func execCredentialsEdit(_ context.Context, _ v3client.Client, arg CredentialsFileArg) (string, error) {
+	// The file that doesn't exist yet is created.
+	profiles, err := v3client.LoadCredentialsProfiles(arg.File, arg.Passphrase)
+	if errors.Is(err, fs.ErrNotExist) {
+		profiles = &v3client.CredentialsProfiles{}
+	} else if err != nil {
+		return "", err
+	}
+
+	profile := &profiles.CredentialsProfile
+	if len(arg.Profile) > 0 {
+		if profiles.Profiles == nil {
+			profiles.Profiles = map[string]*v3client.CredentialsProfile{}
+		}
+		if profile = profiles.Profiles[arg.Profile]; profile == nil {
+			profile = &v3client.CredentialsProfile{}
+			profiles.Profiles[arg.Profile] = profile
+		}
+	}
+
+	applyProfileEdit(profile, &arg.Edit)
+
+	if err = v3client.SaveCredentialsProfiles(arg.File, profiles, arg.Passphrase); err != nil {
+		return "", err
+	}
+
+	// The secrets are not echoed.
+	summary, _ := yaml.Marshal(map[string]interface{}{
+		"areaId":   profile.AreaId,
+		"apiKey":   profile.ApiKey,
+		"username": profile.Username,
+		"maxQPS":   profile.MaxQPS,
+		"endpoint": profile.Endpoint,
+		"profiles": profiles.ProfileNames(),
+	})
+	return fmt.Sprintf("Credentials file %s updated\n%s", arg.File, summary), nil
+}
+
+// applyProfileEdit sets the values supplied on the command line
+func applyProfileEdit(profile *v3client.CredentialsProfile, edit *v3client.CredentialsProfile) {
+	profile.Inherit(&edit.MasheryV3Credentials)
+	if edit.MaxQPS > 0 {
+		profile.MaxQPS = edit.MaxQPS
+	}
+
+	for _, v := range []struct{ dest, src *string }{
+		{&profile.Endpoint, &edit.Endpoint},
+		{&profile.TokenEndpoint, &edit.TokenEndpoint},
+		{&profile.TLS.CABundleFile, &edit.TLS.CABundleFile},
+		{&profile.TLS.ClientCertFile, &edit.TLS.ClientCertFile},
+		{&profile.TLS.ClientKeyFile, &edit.TLS.ClientKeyFile},
+		{&profile.TLS.PinFile, &edit.TLS.PinFile},
+	} {
+		if len(*v.src) > 0 {
+			*v.dest = *v.src
+		}
+	}
+}
+
+func credentialsFileCommand(cmd string, flags func(*CredentialsFileArg, *flag.FlagSet), envFlags func(*CredentialsFileArg) []EnvFlag,
+	validator func(*CredentialsFileArg) error, exec func(context.Context, v3client.Client, CredentialsFileArg) (string, error)) *SubcommandTemplate[CredentialsFileArg, string] {
+
+	return &SubcommandTemplate[CredentialsFileArg, string]{
+		Command:        []string{"credentials", cmd},
+		Arg:            CredentialsFileArg{},
+		FlagSetInit:    flags,
+		EnvFlagSetInit: envFlags,
+		Validator:      validator,
+		Executor:       exec,
+		Template:       mustTemplate("{{ . }}"),
+	}
+}
+
+func enableOfflineSubcommand(cmd *SubcommandFinder) {
+	cmd.Offline = true
+	enableSubcommand(cmd)
+}
+
+func init() {
+	subCmdCredentialsEncrypt = credentialsFileCommand("encrypt", initCredentialsFileFlagSet, credentialsFileEnvFlags, validateCredentialsFile, execCredentialsEncrypt)
+	subCmdCredentialsDecrypt = credentialsFileCommand("decrypt", initCredentialsFileFlagSet, credentialsFileEnvFlags, validateCredentialsFile, execCredentialsDecrypt)
+	subCmdCredentialsRekey = credentialsFileCommand("rekey", initCredentialsRekeyFlagSet, credentialsRekeyEnvFlags, validateCredentialsRekey, execCredentialsRekey)
+	subCmdCredentialsEdit = credentialsFileCommand("edit", initCredentialsEditFlagSet, credentialsFileEnvFlags, validateCredentialsFile, execCredentialsEdit)
+
+	enableOfflineSubcommand(subCmdCredentialsEncrypt.Finder())
+	enableOfflineSubcommand(subCmdCredentialsDecrypt.Finder())
+	enableOfflineSubcommand(subCmdCredentialsRekey.Finder())
+	enableOfflineSubcommand(subCmdCredentialsEdit.Finder())
+}
@@ -55,6 +55,8 @@ type SubcommandTemplate[TArg, TOut any] struct {
 type SubcommandFinder struct {
 	Command  []string
 	Executor ExecutorFunc
+	// Offline commands do not call Mashery, and are executed without the client.
+	Offline bool
 }
 
 func (st *SubcommandTemplate[TArg, TOut]) ExecuteCLI(ctx context.Context, client v3client.Client, subCmd []string) int {
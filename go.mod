@@ -2,6 +2,7 @@ module github.com/aliakseiyanchuk/mashery-v3-go-client
 
 require (
 	github.com/stretchr/testify v1.7.0
+	golang.org/x/crypto v0.33.0
 	gopkg.in/yaml.v2 v2.4.0
 )
 
@@ -12,4 +13,4 @@ require (
 	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 )
 
-go 1.20
+go 1.21
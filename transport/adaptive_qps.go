@@ -0,0 +1,126 @@
+package transport
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AdaptiveQPSFloor Lowest rate the AdaptiveQPS reduces the limiter to where MinQPS is not positive. A zero rate
+// would make the limiter unlimited.
+const AdaptiveQPSFloor = 0.1
+
+// AdaptiveQPS Tunes the rate of the limiter to the allowance observed from Mashery: the rate is increased by
+// Increase after each successful call and multiplied by DecreaseFactor whenever ERR_403_DEVELOPER_OVER_QPS is
+// received, staying within MinQPS and MaxQPS. Add Middleware right after the ThrottleFunc, so that each attempt
+// is observed; the retries are made with the reduced rate.
+type AdaptiveQPS struct {
+	Limiter *TokenBucketRateLimiter
+
+	// MinQPS is the lowest rate; values below AdaptiveQPSFloor select the floor.
+	MinQPS float64
+	MaxQPS float64
+	// Increase is added to the rate after each successful call. Zero selects 0.1.
+	Increase float64
+	// DecreaseFactor multiplies the rate on each over-QPS response. Values outside of (0, 1) select 0.5.
+	DecreaseFactor float64
+	// Cooldown is the time after a decrease during which further over-QPS responses are attributed to the calls
+	// made at the previous rate, and do not decrease the rate again.
+	Cooldown time.Duration
+
+	mutex        sync.Mutex
+	lastDecrease time.Time
+	increases    uint64
+	decreases    uint64
+}
+
+// NewAdaptiveQPS Creates the tuner of the limiter's rate, bounded by minQPS and maxQPS. The rate is increased by
+// 0.1 QPS per successful call, and halved on each over-QPS response.
+func NewAdaptiveQPS(limiter *TokenBucketRateLimiter, minQPS, maxQPS float64) *AdaptiveQPS {
+	return &AdaptiveQPS{
+		Limiter:        limiter,
+		MinQPS:         minQPS,
+		MaxQPS:         maxQPS,
+		Increase:       0.1,
+		DecreaseFactor: 0.5,
+		Cooldown:       time.Second,
+	}
+}
+
+// QPS Returns the rate the calls are currently paced to
+func (a *AdaptiveQPS) QPS() float64 {
+	return a.Limiter.QPS()
+}
+
+// Adjustments Returns the number of times the rate was increased and decreased
+func (a *AdaptiveQPS) Adjustments() (increases uint64, decreases uint64) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	return a.increases, a.decreases
+}
+
+// Middleware Returns the middleware function observing the outcome of each attempt.
+func (a *AdaptiveQPS) Middleware() ChainedMiddlewareFunc {
+	return func(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
+		wr, err := next(ctx, c)
+		if err == nil && wr != nil {
+			a.observe(wr)
+		}
+		return wr, err
+	}
+}
+
+func (a *AdaptiveQPS) observe(wr *WrappedResponse) {
+	if wr.StatusCode == http.StatusForbidden && wr.Header.Get("X-Mashery-Error-Code") == "ERR_403_DEVELOPER_OVER_QPS" {
+		a.decrease()
+	} else if wr.StatusCode < 300 || wr.StatusCode == http.StatusNotFound {
+		a.increase()
+	}
+}
+
+func (a *AdaptiveQPS) increase() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if qps := a.Limiter.QPS(); qps < a.MaxQPS {
+		a.Limiter.SetQPS(math.Min(a.MaxQPS, qps+a.increaseStep()))
+		a.increases++
+	}
+}
+
+func (a *AdaptiveQPS) decrease() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := time.Now()
+	if now.Sub(a.lastDecrease) < a.Cooldown {
+		return
+	}
+
+	if qps, minQPS := a.Limiter.QPS(), a.minQPS(); qps > minQPS {
+		a.Limiter.SetQPS(math.Max(minQPS, qps*a.decreaseFactor()))
+		a.decreases++
+	}
+	a.lastDecrease = now
+}
+
+func (a *AdaptiveQPS) minQPS() float64 {
+	return math.Max(AdaptiveQPSFloor, a.MinQPS)
+}
+
+func (a *AdaptiveQPS) increaseStep() float64 {
+	if a.Increase > 0 {
+		return a.Increase
+	}
+	return 0.1
+}
+
+func (a *AdaptiveQPS) decreaseFactor() float64 {
+	if a.DecreaseFactor > 0 && a.DecreaseFactor < 1 {
+		return a.DecreaseFactor
+	}
+	return 0.5
+}
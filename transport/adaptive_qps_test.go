@@ -0,0 +1,88 @@
+package transport_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func respondWith(code int, errorCode string) transport.MiddlewareFunc {
+	return func(ctx context.Context, c *transport.HttpTransport) (*transport.WrappedResponse, error) {
+		hdr := http.Header{}
+		if len(errorCode) > 0 {
+			hdr.Set("X-Mashery-Error-Code", errorCode)
+		}
+		return &transport.WrappedResponse{StatusCode: code, Header: hdr}, nil
+	}
+}
+
+func TestAdaptiveQPSIncreasesAdditively(t *testing.T) {
+	a := transport.NewAdaptiveQPS(transport.NewTokenBucketRateLimiter(2, 1), 1, 2.5)
+	mw := a.Middleware()
+
+	for i := 0; i < 3; i++ {
+		_, err := mw(context.Background(), nil, respondWith(200, ""))
+		assert.Nil(t, err)
+	}
+	assert.InDelta(t, 2.3, a.QPS(), 0.001)
+
+	// The rate does not exceed the maximum
+	for i := 0; i < 10; i++ {
+		_, _ = mw(context.Background(), nil, respondWith(200, ""))
+	}
+	assert.Equal(t, 2.5, a.QPS())
+
+	increases, decreases := a.Adjustments()
+	assert.Equal(t, uint64(5), increases)
+	assert.Equal(t, uint64(0), decreases)
+}
+
+func TestAdaptiveQPSDecreasesMultiplicatively(t *testing.T) {
+	a := transport.NewAdaptiveQPS(transport.NewTokenBucketRateLimiter(8, 1), 1, 10)
+	a.Cooldown = 50 * time.Millisecond
+	mw := a.Middleware()
+
+	_, _ = mw(context.Background(), nil, respondWith(403, "ERR_403_DEVELOPER_OVER_QPS"))
+	assert.Equal(t, 4.0, a.QPS())
+
+	// Responses to the calls made at the previous rate do not cut the rate again.
+	_, _ = mw(context.Background(), nil, respondWith(403, "ERR_403_DEVELOPER_OVER_QPS"))
+	assert.Equal(t, 4.0, a.QPS())
+
+	for _, expected := range []float64{2, 1, 1} {
+		time.Sleep(60 * time.Millisecond)
+		_, _ = mw(context.Background(), nil, respondWith(403, "ERR_403_DEVELOPER_OVER_QPS"))
+		assert.Equal(t, expected, a.QPS())
+	}
+
+	// Other errors leave the rate unchanged
+	_, _ = mw(context.Background(), nil, respondWith(403, "ERR_403_DEVELOPER_OVER_RATE"))
+	_, _ = mw(context.Background(), nil, respondWith(500, ""))
+	assert.Equal(t, 1.0, a.QPS())
+}
+
+func TestAdaptiveQPSNeverReachesZeroRate(t *testing.T) {
+	a := &transport.AdaptiveQPS{Limiter: transport.NewTokenBucketRateLimiter(1, 1)}
+	mw := a.Middleware()
+
+	// A zero DecreaseFactor and MinQPS would otherwise make the limiter unlimited.
+	for _, expected := range []float64{0.5, 0.25, 0.125, transport.AdaptiveQPSFloor, transport.AdaptiveQPSFloor} {
+		_, _ = mw(context.Background(), nil, respondWith(403, "ERR_403_DEVELOPER_OVER_QPS"))
+		assert.Equal(t, expected, a.QPS())
+	}
+}
+
+func TestMetricsReportEffectiveQPS(t *testing.T) {
+	a := transport.NewAdaptiveQPS(transport.NewTokenBucketRateLimiter(3, 1), 1, 10)
+	m := transport.NewMetrics()
+	m.EffectiveQPS = a.QPS
+
+	buf := bytes.Buffer{}
+	assert.Nil(t, m.WritePrometheus(&buf))
+	assert.Contains(t, buf.String(), "# TYPE mashery_client_effective_qps gauge\nmashery_client_effective_qps 3\n")
+	assert.Equal(t, 3.0, m.Snapshot()["effectiveQPS"])
+}
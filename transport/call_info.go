@@ -0,0 +1,103 @@
+package transport
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const callInfoKey = ".call.info"
+
+// CallInfo Details of the logical call passing through the pipeline. The leaf executor fills in the method and
+// the URL of each request it sends, making them available to the middleware functions even if the exchange
+// fails on the network level.
+type CallInfo struct {
+	// AppContext is the application context of the fetch specification that initiated the call.
+	AppContext string
+	// Resource is the Mashery resource path, without the query string.
+	Resource string
+	// Query is the query string of the fetch specification.
+	Query url.Values
+	// CorrelationId identifies the logical call; it is sent with each request in CorrelationIdHeader.
+	CorrelationId string
+
+	mutex sync.Mutex
+
+	method       string
+	url          *url.URL
+	attempts     int
+	throttleWait time.Duration
+}
+
+// Method HTTP method of the most recent request, or of the planned request if none was sent yet
+func (ci *CallInfo) Method() string {
+	ci.mutex.Lock()
+	defer ci.mutex.Unlock()
+
+	return ci.method
+}
+
+// URL of the most recent request
+func (ci *CallInfo) URL() *url.URL {
+	ci.mutex.Lock()
+	defer ci.mutex.Unlock()
+
+	return ci.url
+}
+
+// Attempts Number of requests sent to Mashery as part of this call
+func (ci *CallInfo) Attempts() int {
+	ci.mutex.Lock()
+	defer ci.mutex.Unlock()
+
+	return ci.attempts
+}
+
+// ThrottleWait Total time the call has spent waiting for a call slot
+func (ci *CallInfo) ThrottleWait() time.Duration {
+	ci.mutex.Lock()
+	defer ci.mutex.Unlock()
+
+	return ci.throttleWait
+}
+
+func (ci *CallInfo) addThrottleWait(d time.Duration) {
+	ci.mutex.Lock()
+	defer ci.mutex.Unlock()
+
+	ci.throttleWait += d
+}
+
+func (ci *CallInfo) observe(method string, u *url.URL) {
+	ci.mutex.Lock()
+	defer ci.mutex.Unlock()
+
+	ci.method = method
+	ci.url = u
+	ci.attempts++
+}
+
+// CallInfoFromContext Returns the information of the call being executed, or nil if the context is not
+// a part of a pipeline execution.
+func CallInfoFromContext(ctx context.Context) *CallInfo {
+	if v := ctx.Value(callInfoKey); v != nil {
+		if ci, ok := v.(*CallInfo); ok {
+			return ci
+		}
+	}
+
+	return nil
+}
+
+// contextWithCallInfo starts the information of the call. The method is the one the leaf executor is expected
+// to use; it is replaced by the actual method once the request is sent.
+func contextWithCallInfo(ctx context.Context, method string, spec CommonFetchSpec) context.Context {
+	return context.WithValue(ctx, callInfoKey, &CallInfo{
+		AppContext:    spec.AppContext,
+		Resource:      spec.Resource,
+		Query:         cloneQueryString(spec.Query),
+		CorrelationId: correlationIdFrom(ctx),
+		method:        method,
+	})
+}
@@ -0,0 +1,107 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const callOptionsKey = ".call.options"
+
+// CallPriority Lane the rate limiter schedules the call in. Calls waiting in the interactive lane are given
+// the call slots before the calls waiting in the batch lane.
+type CallPriority int
+
+const (
+	PriorityInteractive CallPriority = iota
+	PriorityBatch
+)
+
+func (p CallPriority) String() string {
+	switch p {
+	case PriorityInteractive:
+		return "interactive"
+	case PriorityBatch:
+		return "batch"
+	default:
+		return fmt.Sprintf("priority(%d)", int(p))
+	}
+}
+
+// CallOptions Options of the calls made with the context. Zero values select the defaults: the interactive lane,
+// unbounded throttle wait and no timeout other than the one of the context.
+type CallOptions struct {
+	Priority CallPriority
+	// MaxThrottleWait is the longest the call may wait for a call slot. Where the wait would be longer, the call
+	// fails immediately with ThrottleWaitExceededError.
+	MaxThrottleWait time.Duration
+	// Timeout bounds each call made to Mashery, including the throttle wait and the retries. The Timeout of the
+	// HTTPClientParams still applies to each exchange.
+	Timeout time.Duration
+}
+
+// ThrottleWaitExceededError Returned where the call would have to wait for a call slot longer than the
+// MaxThrottleWait of its options.
+type ThrottleWaitExceededError struct {
+	Wait    time.Duration
+	MaxWait time.Duration
+}
+
+func (e *ThrottleWaitExceededError) Error() string {
+	return fmt.Sprintf("call slot is available in %s, which exceeds the maximum throttle wait of %s", e.Wait, e.MaxWait)
+}
+
+// WithCallOptions Returns the context where the calls observe the supplied options, replacing any options set before.
+func WithCallOptions(ctx context.Context, opts CallOptions) context.Context {
+	return context.WithValue(ctx, callOptionsKey, opts)
+}
+
+// WithPriority Returns the context where the calls are scheduled in the supplied lane.
+func WithPriority(ctx context.Context, p CallPriority) context.Context {
+	opts := CallOptionsFromContext(ctx)
+	opts.Priority = p
+	return WithCallOptions(ctx, opts)
+}
+
+// WithMaxThrottleWait Returns the context where the calls fail instead of waiting for a call slot longer than d.
+func WithMaxThrottleWait(ctx context.Context, d time.Duration) context.Context {
+	opts := CallOptionsFromContext(ctx)
+	opts.MaxThrottleWait = d
+	return WithCallOptions(ctx, opts)
+}
+
+// WithCallTimeout Returns the context where each call to Mashery has to complete within d.
+func WithCallTimeout(ctx context.Context, d time.Duration) context.Context {
+	opts := CallOptionsFromContext(ctx)
+	opts.Timeout = d
+	return WithCallOptions(ctx, opts)
+}
+
+// CallOptionsFromContext Returns the options of the calls made with the context
+func CallOptionsFromContext(ctx context.Context) CallOptions {
+	if v := ctx.Value(callOptionsKey); v != nil {
+		if opts, ok := v.(CallOptions); ok {
+			return opts
+		}
+	}
+
+	return CallOptions{}
+}
+
+// checkThrottleWait returns the error where the call cannot wait for the call slot as long as required: either the
+// wait exceeds the maximum of the call options, or the context would expire first.
+func checkThrottleWait(ctx context.Context, wait time.Duration) error {
+	if wait <= 0 {
+		return nil
+	}
+
+	if opts := CallOptionsFromContext(ctx); opts.MaxThrottleWait > 0 && wait > opts.MaxThrottleWait {
+		return &ThrottleWaitExceededError{Wait: wait, MaxWait: opts.MaxThrottleWait}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+		return context.DeadlineExceeded
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package transport_test
+
+import (
+	"context"
+	"errors"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3simulator"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCallTimeoutAbandonsExchange(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	cancelled := false
+	sim.Interceptor = func(r *http.Request) *http.Response {
+		select {
+		case <-r.Context().Done():
+			cancelled = true
+		case <-time.After(2 * time.Second):
+		}
+		return nil
+	}
+
+	cl := v3simulator.NewClient(sim)
+
+	ctx := transport.WithCallTimeout(context.Background(), 50*time.Millisecond)
+
+	start := time.Now()
+	_, _, _ = cl.GetService(ctx, masherytypes.ServiceIdentityFrom("svc"))
+	assert.Less(t, time.Since(start), time.Second)
+	assert.True(t, cancelled)
+}
+
+func TestMaxThrottleWaitFailsClientCall(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	cl := v3simulator.NewClient(sim, func(p *v3client.Params) {
+		p.QPS = 1
+	})
+
+	ctx := transport.WithCallOptions(context.Background(), transport.CallOptions{
+		Priority:        transport.PriorityInteractive,
+		MaxThrottleWait: 100 * time.Millisecond,
+	})
+
+	_, _, err := cl.GetService(ctx, masherytypes.ServiceIdentityFrom("svc"))
+	assert.Nil(t, err)
+
+	_, _, err = cl.GetService(ctx, masherytypes.ServiceIdentityFrom("svc"))
+	var waitErr *transport.ThrottleWaitExceededError
+	assert.True(t, errors.As(err, &waitErr))
+}
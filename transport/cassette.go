@@ -0,0 +1,382 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+const redactedValue = "REDACTED"
+
+// CassetteRequest Recorded request, normalized for deterministic matching.
+type CassetteRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Query  url.Values  `json:"query,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// CassetteResponse Recorded response
+type CassetteResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+type CassetteEntry struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// CassetteScrubber Defines which parts of the exchange must not be written into the cassette.
+type CassetteScrubber struct {
+	Headers     []string
+	QueryParams []string
+	// BodyFields are the names of JSON or form fields whose values are redacted at any depth.
+	BodyFields []string
+}
+
+// DefaultCassetteScrubber Scrubs authorization headers, V2 signatures, package key secrets, passwords and tokens.
+func DefaultCassetteScrubber() CassetteScrubber {
+	return CassetteScrubber{
+		Headers:     []string{"Authorization", "Proxy-Authorization", "X-Vault-Token"},
+		QueryParams: []string{"sig"},
+		BodyFields:  []string{"secret", "passwdNew", "password", "access_token", "refresh_token"},
+	}
+}
+
+// Cassette A sequence of recorded exchanges that can be saved to and loaded from a file.
+type Cassette struct {
+	Entries  []CassetteEntry  `json:"entries"`
+	Scrubber CassetteScrubber `json:"-"`
+
+	mutex sync.Mutex
+	used  []bool
+}
+
+func NewCassette() *Cassette {
+	return &Cassette{
+		Scrubber: DefaultCassetteScrubber(),
+	}
+}
+
+// LoadCassette Loads the cassette previously saved with Save
+func LoadCassette(path string) (*Cassette, error) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := NewCassette()
+	if err = json.Unmarshal(dat, rv); err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (c *Cassette) Save(path string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if dat, err := json.MarshalIndent(c, "", "  "); err != nil {
+		return err
+	} else {
+		return os.WriteFile(path, dat, 0600)
+	}
+}
+
+// Record Adds the exchange to the cassette. Exchanges that failed on the network level are not recorded.
+// The method has the signature of the ExchangeListener.
+func (c *Cassette) Record(_ context.Context, req *WrappedRequest, res *WrappedResponse, err error) {
+	if err != nil || req == nil || res == nil {
+		return
+	}
+
+	body, bodyErr := res.Body()
+	if bodyErr != nil {
+		return
+	}
+
+	entry := CassetteEntry{
+		Request: c.normalizeRequest(req.Request, requestBody(req)),
+		Response: CassetteResponse{
+			StatusCode: res.StatusCode,
+			Header:     c.Scrubber.scrubHeader(res.Header),
+			Body:       c.Scrubber.scrubBody(res.Header.Get("Content-Type"), body),
+		},
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.Entries = append(c.Entries, entry)
+}
+
+// Replay Finds the first not yet replayed entry matching the request.
+func (c *Cassette) Replay(r *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(r)
+	if err != nil {
+		return nil, err
+	}
+	key := c.normalizeRequest(r, body)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if len(c.used) != len(c.Entries) {
+		c.used = make([]bool, len(c.Entries))
+	}
+
+	for idx, e := range c.Entries {
+		if !c.used[idx] && e.Request.matches(key) {
+			c.used[idx] = true
+			return e.Response.toHttpResponse(r), nil
+		}
+	}
+
+	return nil, errors.New(fmt.Sprintf("cassette contains no unused recording for %s %s", key.Method, r.URL.String()))
+}
+
+// Rewind Allows the entries in the cassette to be replayed again.
+func (c *Cassette) Rewind() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.used = nil
+}
+
+func (c *Cassette) normalizeRequest(r *http.Request, body []byte) CassetteRequest {
+	rv := CassetteRequest{
+		Method: strings.ToUpper(r.Method),
+		Path:   r.URL.Path,
+		Header: c.Scrubber.scrubHeader(r.Header),
+		Body:   c.Scrubber.scrubBody(r.Header.Get("Content-Type"), body),
+	}
+
+	if qs := r.URL.Query(); len(qs) > 0 {
+		rv.Query = c.Scrubber.scrubQuery(qs)
+	}
+
+	return rv
+}
+
+func (cr CassetteRequest) matches(other CassetteRequest) bool {
+	return cr.Method == other.Method &&
+		cr.Path == other.Path &&
+		cr.Query.Encode() == other.Query.Encode() &&
+		cr.Body == other.Body
+}
+
+func (cr CassetteResponse) toHttpResponse(r *http.Request) *http.Response {
+	hdr := http.Header{}
+	for k, v := range cr.Header {
+		hdr[k] = v
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", cr.StatusCode, http.StatusText(cr.StatusCode)),
+		StatusCode:    cr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        hdr,
+		Body:          io.NopCloser(strings.NewReader(cr.Body)),
+		ContentLength: int64(len(cr.Body)),
+		Request:       r,
+	}
+}
+
+// ScrubJSON Returns the JSON document with the values of the BodyFields redacted. Documents that are not valid
+// JSON are returned unchanged.
+func (s CassetteScrubber) ScrubJSON(dat []byte) []byte {
+	return []byte(s.scrubBody("application/json", dat))
+}
+
+func (s CassetteScrubber) scrubHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+
+	rv := h.Clone()
+	for _, name := range s.Headers {
+		if len(rv.Values(name)) > 0 {
+			rv.Set(name, redactedValue)
+		}
+	}
+
+	return rv
+}
+
+func (s CassetteScrubber) scrubQuery(qs url.Values) url.Values {
+	rv := url.Values{}
+	for k, v := range qs {
+		rv[k] = v
+	}
+
+	for _, name := range s.QueryParams {
+		if rv.Has(name) {
+			rv.Set(name, redactedValue)
+		}
+	}
+
+	return rv
+}
+
+func (s CassetteScrubber) scrubBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		if form, err := url.ParseQuery(string(body)); err == nil {
+			for _, name := range s.BodyFields {
+				if form.Has(name) {
+					form.Set(name, redactedValue)
+				}
+			}
+			return form.Encode()
+		}
+	}
+
+	var js interface{}
+	if err := json.Unmarshal(body, &js); err != nil {
+		return string(body)
+	}
+
+	// Marshalling the parsed value orders the keys, which makes the matching insensitive to the field ordering.
+	dat, _ := json.Marshal(s.scrubJSON(js))
+	return string(dat)
+}
+
+func (s CassetteScrubber) scrubJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			if s.isSensitiveField(k) {
+				if val != nil {
+					t[k] = redactedValue
+				}
+			} else {
+				t[k] = s.scrubJSON(val)
+			}
+		}
+	case []interface{}:
+		for i, val := range t {
+			t[i] = s.scrubJSON(val)
+		}
+	}
+
+	return v
+}
+
+func (s CassetteScrubber) isSensitiveField(name string) bool {
+	for _, f := range s.BodyFields {
+		if f == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// requestBody returns the body that was sent with this request
+func requestBody(req *WrappedRequest) []byte {
+	if dat, err := readRequestBody(req.Request); err == nil && len(dat) > 0 {
+		return dat
+	} else if req.Body != nil {
+		dat, _ = json.Marshal(req.Body)
+		return dat
+	}
+
+	return nil
+}
+
+// readRequestBody reads the request body without consuming it.
+func readRequestBody(r *http.Request) ([]byte, error) {
+	if r.GetBody != nil {
+		if rdr, err := r.GetBody(); err != nil {
+			return nil, err
+		} else {
+			defer rdr.Close()
+			return io.ReadAll(rdr)
+		}
+	} else if r.Body != nil && r.Body != http.NoBody {
+		dat, err := io.ReadAll(r.Body)
+		_ = r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(dat))
+		return dat, err
+	}
+
+	return nil, nil
+}
+
+// RecordingExecutor Executor that passes the requests to the delegate and records the exchanges into a cassette.
+type RecordingExecutor struct {
+	Delegate HttpExecutor
+	Cassette *Cassette
+}
+
+func NewRecordingExecutor(delegate HttpExecutor, c *Cassette) *RecordingExecutor {
+	return &RecordingExecutor{
+		Delegate: delegate,
+		Cassette: c,
+	}
+}
+
+func (re *RecordingExecutor) Do(r *http.Request) (*http.Response, error) {
+	// The body is buffered before the call, as the delegate will consume it.
+	if _, err := readRequestBody(r); err != nil {
+		return nil, err
+	}
+
+	resp, err := re.Delegate.Do(r)
+	if err != nil {
+		return resp, err
+	}
+
+	wr := &WrappedResponse{
+		Request:    &WrappedRequest{Request: r},
+		Response:   resp,
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+	}
+	re.Cassette.Record(r.Context(), wr.Request, wr, nil)
+
+	// Body of the response was read by the recorder; the caller receives a buffered copy.
+	body, readErr := wr.Body()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, readErr
+}
+
+func (re *RecordingExecutor) CloseIdleConnections() {
+	re.Delegate.CloseIdleConnections()
+}
+
+// ReplayingExecutor Executor that serves responses from a cassette without making any network calls.
+type ReplayingExecutor struct {
+	Cassette *Cassette
+}
+
+func NewReplayingExecutor(c *Cassette) *ReplayingExecutor {
+	return &ReplayingExecutor{
+		Cassette: c,
+	}
+}
+
+func (re *ReplayingExecutor) Do(r *http.Request) (*http.Response, error) {
+	return re.Cassette.Replay(r)
+}
+
+func (re *ReplayingExecutor) CloseIdleConnections() {
+	// Nothing to close
+}
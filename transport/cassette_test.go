@@ -0,0 +1,118 @@
+package transport_test
+
+import (
+	"context"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v2client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3simulator"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func cassetteClient(exec transport.HttpExecutor) v3client.Client {
+	return v3simulator.NewClient(exec, func(p *v3client.Params) {
+		p.Authorizer = transport.NewBearerAuthorizer("secret-token")
+	})
+}
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	svcId, _ := sim.Create("/services", masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	_, _ = sim.Create("/packageKeys", masherytypes.PackageKey{})
+
+	cassette := transport.NewCassette()
+	recCl := cassetteClient(transport.NewRecordingExecutor(sim, cassette))
+
+	ctx := context.Background()
+	svc, exists, err := recCl.GetService(ctx, masherytypes.ServiceIdentityFrom(svcId))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+
+	keys, err := recCl.ListPackageKeys(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(keys))
+
+	cassetteFile := filepath.Join(t.TempDir(), "cassette.json")
+	assert.Nil(t, cassette.Save(cassetteFile))
+
+	dat, _ := os.ReadFile(cassetteFile)
+	assert.False(t, strings.Contains(string(dat), "secret-token"))
+	assert.False(t, strings.Contains(string(dat), *keys[0].Secret))
+
+	loaded, err := transport.LoadCassette(cassetteFile)
+	assert.Nil(t, err)
+
+	replayCl := cassetteClient(transport.NewReplayingExecutor(loaded))
+	replayed, exists, err := replayCl.GetService(ctx, masherytypes.ServiceIdentityFrom(svcId))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, svc.Name, replayed.Name)
+
+	// The recording was already consumed
+	_, _, err = replayCl.GetService(ctx, masherytypes.ServiceIdentityFrom(svcId))
+	assert.NotNil(t, err)
+
+	loaded.Rewind()
+	_, _, err = replayCl.GetService(ctx, masherytypes.ServiceIdentityFrom(svcId))
+	assert.Nil(t, err)
+
+	_, _, err = replayCl.GetService(ctx, masherytypes.ServiceIdentityFrom("unknown"))
+	assert.NotNil(t, err)
+}
+
+type fixedV2Executor struct{}
+
+func (f fixedV2Executor) Do(r *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"jsonrpc":"2.0","id":1,"result":{"total_items":0}}`)),
+		Request:    r,
+	}, nil
+}
+
+func (f fixedV2Executor) CloseIdleConnections() {
+}
+
+func TestCassetteReplaysV2Traffic(t *testing.T) {
+	cassette := transport.NewCassette()
+
+	auth := v2client.NewV2Authorizer("key")
+	auth.UpdateSignature("first-signature")
+
+	recCl := v2client.NewHTTPClient(v2client.Params{
+		AreaNID:    10,
+		Authorizer: auth,
+		QPS:        1000,
+		HTTPClientParams: transport.HTTPClientParams{
+			ExplicitHttpExecutor: transport.NewRecordingExecutor(fixedV2Executor{}, cassette),
+		},
+	})
+
+	_, err := recCl.Invoke(context.Background(), "object.query", "SELECT * FROM members")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(cassette.Entries))
+	assert.Equal(t, "REDACTED", cassette.Entries[0].Request.Query.Get("sig"))
+
+	// A different signature is calculated at replay time.
+	auth.UpdateSignature("second-signature")
+	replayCl := v2client.NewHTTPClient(v2client.Params{
+		AreaNID:    10,
+		Authorizer: auth,
+		QPS:        1000,
+		HTTPClientParams: transport.HTTPClientParams{
+			ExplicitHttpExecutor: transport.NewReplayingExecutor(cassette),
+		},
+	})
+
+	res, err := replayCl.Invoke(context.Background(), "object.query", "SELECT * FROM members")
+	assert.Nil(t, err)
+	assert.Equal(t, 200, res.HttpStatusCode)
+}
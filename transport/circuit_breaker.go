@@ -0,0 +1,332 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// CircuitState State of a circuit in the CircuitBreaker
+type CircuitState int
+
+const (
+	// CircuitClosed calls are passed to Mashery
+	CircuitClosed CircuitState = iota
+	// CircuitOpen calls fail immediately with CircuitOpenError
+	CircuitOpen
+	// CircuitHalfOpen a limited number of trial calls is passed to Mashery to probe whether it has recovered
+	CircuitHalfOpen
+)
+
+func (cs CircuitState) String() string {
+	switch cs {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(cs))
+	}
+}
+
+// CircuitKey Identifies a circuit: each endpoint host and application context has its own.
+type CircuitKey struct {
+	Host       string
+	AppContext string
+}
+
+// CircuitOpenError Returned for the calls rejected while the circuit is open.
+type CircuitOpenError struct {
+	Key     CircuitKey
+	RetryAt time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit for %s (%s) is open until %s", e.Key.Host, e.Key.AppContext, e.RetryAt.Format(time.RFC3339))
+}
+
+// CircuitStateListener receives the notification of the circuit changing its state.
+type CircuitStateListener func(key CircuitKey, from, to CircuitState)
+
+// CircuitBreakerSettings Conditions tripping the circuit and the duration it stays open.
+type CircuitBreakerSettings struct {
+	// ConsecutiveFailures trips the circuit after this number of failures in a row. Zero disables the condition.
+	ConsecutiveFailures int
+	// FailureRate trips the circuit when the share (0..1) of failures among the last WindowSize calls
+	// reaches this value. Zero disables the condition.
+	FailureRate float64
+	// WindowSize is the number of most recent calls considered by FailureRate. The rate is not evaluated
+	// until the window is full.
+	WindowSize int
+	// OpenDuration is the time the circuit stays open before the trial calls are allowed.
+	OpenDuration time.Duration
+	// HalfOpenCalls is the number of successful trial calls required to close the circuit.
+	HalfOpenCalls int
+
+	// IsFailure decides whether the outcome of the call counts as a failure. Where not set, network errors
+	// and 5xx responses are failures; the calls rejected locally (throttle wait, exhausted key pool) or abandoned
+	// by the caller are not.
+	IsFailure func(wr *WrappedResponse, err error) bool
+	// OnStateChange, if set, is called each time the circuit changes its state.
+	OnStateChange CircuitStateListener
+}
+
+// DefaultCircuitBreakerSettings Trips after 5 consecutive failures or 50% failures among the last 20 calls,
+// and probes Mashery again after 30 seconds.
+func DefaultCircuitBreakerSettings() CircuitBreakerSettings {
+	return CircuitBreakerSettings{
+		ConsecutiveFailures: 5,
+		FailureRate:         0.5,
+		WindowSize:          20,
+		OpenDuration:        time.Second * 30,
+		HalfOpenCalls:       1,
+	}
+}
+
+func defaultIsFailure(wr *WrappedResponse, err error) bool {
+	if wr == nil || wr.Response == nil {
+		return exchangeError(err)
+	}
+
+	return wr.StatusCode >= 500
+}
+
+type circuit struct {
+	state       CircuitState
+	consecutive int
+	outcomes    []bool
+	next        int
+	filled      bool
+	openedAt    time.Time
+	trials      int
+	successes   int
+}
+
+func (c *circuit) record(failure bool, window int) {
+	if failure {
+		c.consecutive++
+	} else {
+		c.consecutive = 0
+	}
+
+	if window <= 0 {
+		return
+	}
+	if len(c.outcomes) != window {
+		c.outcomes = make([]bool, window)
+		c.next = 0
+		c.filled = false
+	}
+
+	c.outcomes[c.next] = failure
+	c.next = (c.next + 1) % window
+	if c.next == 0 {
+		c.filled = true
+	}
+}
+
+func (c *circuit) failureRate() (float64, bool) {
+	if !c.filled {
+		return 0, false
+	}
+
+	failures := 0
+	for _, f := range c.outcomes {
+		if f {
+			failures++
+		}
+	}
+	return float64(failures) / float64(len(c.outcomes)), true
+}
+
+func (c *circuit) reset() {
+	c.consecutive = 0
+	c.outcomes = nil
+	c.next = 0
+	c.filled = false
+	c.trials = 0
+	c.successes = 0
+}
+
+// CircuitBreaker Stops sending calls to Mashery when it appears to be unavailable, failing them fast instead.
+// Use Middleware to add the breaker to the pipeline; it should be placed after the retry middleware, so that
+// the final outcome of the retried call is considered.
+type CircuitBreaker struct {
+	Settings CircuitBreakerSettings
+
+	mutex    sync.Mutex
+	circuits map[CircuitKey]*circuit
+	now      func() time.Time
+}
+
+func NewCircuitBreaker(settings CircuitBreakerSettings) *CircuitBreaker {
+	if settings.HalfOpenCalls < 1 {
+		settings.HalfOpenCalls = 1
+	}
+	if settings.IsFailure == nil {
+		settings.IsFailure = defaultIsFailure
+	}
+
+	return &CircuitBreaker{
+		Settings: settings,
+		circuits: map[CircuitKey]*circuit{},
+		now:      time.Now,
+	}
+}
+
+// State Returns the current state of the circuit
+func (cb *CircuitBreaker) State(key CircuitKey) CircuitState {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if c, ok := cb.circuits[key]; ok {
+		if c.state == CircuitOpen && !cb.now().Before(c.openedAt.Add(cb.Settings.OpenDuration)) {
+			return CircuitHalfOpen
+		}
+		return c.state
+	}
+
+	return CircuitClosed
+}
+
+// Middleware Returns the middleware function applying this breaker.
+func (cb *CircuitBreaker) Middleware() ChainedMiddlewareFunc {
+	return func(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
+		key := CircuitKey{Host: endpointHost(c.MashEndpoint)}
+		if ci := CallInfoFromContext(ctx); ci != nil {
+			key.AppContext = ci.AppContext
+		}
+
+		if err := cb.acquire(key); err != nil {
+			return nil, err
+		}
+
+		wr, err := next(ctx, c)
+		if ctx.Err() != nil {
+			// Cancellation by the caller says nothing about the health of Mashery.
+			cb.release(key)
+		} else {
+			cb.complete(key, cb.Settings.IsFailure(wr, err))
+		}
+
+		return wr, err
+	}
+}
+
+func endpointHost(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil && len(u.Host) > 0 {
+		return u.Host
+	}
+	return endpoint
+}
+
+// acquire checks whether the call may proceed.
+func (cb *CircuitBreaker) acquire(key CircuitKey) error {
+	cb.mutex.Lock()
+
+	c, ok := cb.circuits[key]
+	if !ok {
+		c = &circuit{}
+		cb.circuits[key] = c
+	}
+
+	var rv error
+	var changedFrom CircuitState
+	changed := false
+
+	switch c.state {
+	case CircuitOpen:
+		retryAt := c.openedAt.Add(cb.Settings.OpenDuration)
+		if cb.now().Before(retryAt) {
+			rv = &CircuitOpenError{Key: key, RetryAt: retryAt}
+		} else {
+			changedFrom, changed = c.state, true
+			c.state = CircuitHalfOpen
+			c.trials = 1
+			c.successes = 0
+		}
+	case CircuitHalfOpen:
+		if c.trials >= cb.Settings.HalfOpenCalls {
+			rv = &CircuitOpenError{Key: key, RetryAt: cb.now().Add(cb.Settings.OpenDuration)}
+		} else {
+			c.trials++
+		}
+	}
+
+	cb.mutex.Unlock()
+
+	if changed {
+		cb.notify(key, changedFrom, CircuitHalfOpen)
+	}
+	return rv
+}
+
+// release returns the trial slot of a call whose outcome is not counted.
+func (cb *CircuitBreaker) release(key CircuitKey) {
+	cb.mutex.Lock()
+	defer cb.mutex.Unlock()
+
+	if c := cb.circuits[key]; c != nil && c.state == CircuitHalfOpen && c.trials > 0 {
+		c.trials--
+	}
+}
+
+func (cb *CircuitBreaker) complete(key CircuitKey, failure bool) {
+	cb.mutex.Lock()
+
+	c := cb.circuits[key]
+	from := c.state
+	to := from
+
+	switch c.state {
+	case CircuitHalfOpen:
+		if failure {
+			to = CircuitOpen
+		} else if c.successes++; c.successes >= cb.Settings.HalfOpenCalls {
+			to = CircuitClosed
+		}
+	case CircuitClosed:
+		c.record(failure, cb.Settings.WindowSize)
+		if cb.shouldTrip(c) {
+			to = CircuitOpen
+		}
+	}
+
+	if to != from {
+		c.reset()
+		c.state = to
+		if to == CircuitOpen {
+			c.openedAt = cb.now()
+		}
+	}
+
+	cb.mutex.Unlock()
+
+	if to != from {
+		cb.notify(key, from, to)
+	}
+}
+
+func (cb *CircuitBreaker) shouldTrip(c *circuit) bool {
+	if cb.Settings.ConsecutiveFailures > 0 && c.consecutive >= cb.Settings.ConsecutiveFailures {
+		return true
+	}
+
+	if cb.Settings.FailureRate > 0 {
+		if rate, ok := c.failureRate(); ok && rate >= cb.Settings.FailureRate {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (cb *CircuitBreaker) notify(key CircuitKey, from, to CircuitState) {
+	if cb.Settings.OnStateChange != nil {
+		cb.Settings.OnStateChange(key, from, to)
+	}
+}
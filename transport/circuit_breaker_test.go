@@ -0,0 +1,187 @@
+package transport_test
+
+import (
+	"context"
+	"errors"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3simulator"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type stateChange struct {
+	key      transport.CircuitKey
+	from, to transport.CircuitState
+}
+
+type stateRecorder struct {
+	mutex   sync.Mutex
+	changes []stateChange
+}
+
+func (sr *stateRecorder) listen(key transport.CircuitKey, from, to transport.CircuitState) {
+	sr.mutex.Lock()
+	defer sr.mutex.Unlock()
+
+	sr.changes = append(sr.changes, stateChange{key, from, to})
+}
+
+func breakerClient(sim *v3simulator.Simulator, cb *transport.CircuitBreaker) v3client.Client {
+	return v3simulator.NewClient(sim, func(p *v3client.Params) {
+		p.CircuitBreaker = cb
+	})
+}
+
+// failServices makes the simulator fail the calls to the services while outage is set
+func failServices(sim *v3simulator.Simulator, outage *int32) *int32 {
+	var calls int32
+	sim.Interceptor = func(r *http.Request) *http.Response {
+		if strings.Contains(r.URL.Path, "/services") {
+			atomic.AddInt32(&calls, 1)
+			if atomic.LoadInt32(outage) > 0 {
+				return failingResponse(r, 503, "")
+			}
+		}
+		return nil
+	}
+	return &calls
+}
+
+func TestCircuitBreakerTripsOnConsecutiveFailures(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	outage := int32(1)
+	calls := failServices(sim, &outage)
+
+	rec := &stateRecorder{}
+	settings := transport.DefaultCircuitBreakerSettings()
+	settings.ConsecutiveFailures = 3
+	settings.FailureRate = 0
+	settings.OpenDuration = time.Millisecond * 100
+	settings.OnStateChange = rec.listen
+
+	cb := transport.NewCircuitBreaker(settings)
+	cl := breakerClient(sim, cb)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := cl.ListServices(ctx)
+		assert.NotNil(t, err)
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+
+	_, err := cl.ListServices(ctx)
+	var openErr *transport.CircuitOpenError
+	assert.True(t, errors.As(err, &openErr))
+	assert.Equal(t, "localhost", openErr.Key.Host)
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+	assert.Equal(t, transport.CircuitOpen, cb.State(openErr.Key))
+
+	// Other application contexts are not affected
+	_, err = cl.ListMembers(ctx)
+	assert.Nil(t, err)
+
+	time.Sleep(settings.OpenDuration)
+	atomic.StoreInt32(&outage, 0)
+
+	_, err = cl.ListServices(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, transport.CircuitClosed, cb.State(openErr.Key))
+
+	assert.Equal(t, 3, len(rec.changes))
+	assert.Equal(t, transport.CircuitOpen, rec.changes[0].to)
+	assert.Equal(t, transport.CircuitHalfOpen, rec.changes[1].to)
+	assert.Equal(t, transport.CircuitClosed, rec.changes[2].to)
+}
+
+func TestCircuitBreakerReopensOnFailedTrial(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	outage := int32(1)
+	failServices(sim, &outage)
+
+	settings := transport.DefaultCircuitBreakerSettings()
+	settings.ConsecutiveFailures = 1
+	settings.OpenDuration = time.Millisecond * 50
+
+	cb := transport.NewCircuitBreaker(settings)
+	cl := breakerClient(sim, cb)
+	ctx := context.Background()
+
+	_, err := cl.ListServices(ctx)
+	assert.NotNil(t, err)
+
+	time.Sleep(settings.OpenDuration)
+	_, err = cl.ListServices(ctx)
+	var openErr *transport.CircuitOpenError
+	assert.False(t, errors.As(err, &openErr))
+
+	_, err = cl.ListServices(ctx)
+	assert.True(t, errors.As(err, &openErr))
+}
+
+func TestCircuitBreakerTripsOnFailureRate(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	var seq int32
+	sim.Interceptor = func(r *http.Request) *http.Response {
+		// Every second call fails, so the consecutive count never exceeds 1
+		if atomic.AddInt32(&seq, 1)%2 == 0 {
+			return failingResponse(r, 502, "")
+		}
+		return nil
+	}
+
+	settings := transport.DefaultCircuitBreakerSettings()
+	settings.ConsecutiveFailures = 0
+	settings.FailureRate = 0.5
+	settings.WindowSize = 4
+
+	cb := transport.NewCircuitBreaker(settings)
+	cl := breakerClient(sim, cb)
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		_, _ = cl.ListServices(ctx)
+	}
+
+	_, err := cl.ListServices(ctx)
+	var openErr *transport.CircuitOpenError
+	assert.True(t, errors.As(err, &openErr))
+}
+
+func TestCircuitStateString(t *testing.T) {
+	assert.Equal(t, "closed", transport.CircuitClosed.String())
+	assert.Equal(t, "open", transport.CircuitOpen.String())
+	assert.Equal(t, "half-open", transport.CircuitHalfOpen.String())
+}
+
+func TestCircuitBreakerIgnoresThrottleWaitExceeded(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+
+	rec := &stateRecorder{}
+	settings := transport.DefaultCircuitBreakerSettings()
+	settings.ConsecutiveFailures = 2
+	settings.FailureRate = 0
+	settings.OnStateChange = rec.listen
+
+	cl := v3simulator.NewClient(sim, func(p *v3client.Params) {
+		p.QPS = 1
+		p.CircuitBreaker = transport.NewCircuitBreaker(settings)
+	})
+	ctx := transport.WithMaxThrottleWait(context.Background(), 10*time.Millisecond)
+
+	_, err := cl.ListServices(ctx)
+	assert.Nil(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err = cl.ListServices(ctx)
+		var waitErr *transport.ThrottleWaitExceededError
+		assert.True(t, errors.As(err, &waitErr))
+	}
+
+	assert.Equal(t, 0, len(rec.changes))
+}
@@ -2,9 +2,10 @@ package transport
 
 import (
 	"crypto/tls"
-	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/errwrap"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -17,8 +18,17 @@ type HTTPClientParams struct {
 	TLSConfig               *tls.Config
 	TLSConfigDelegateSystem bool
 	Timeout                 time.Duration
+	// TLS adds the client certificate and the certificate authorities to the TLSConfig
+	TLS TLSOptions
 
-	ProxyServer          *url.URL
+	// Proxy configures the proxies the client connects through.
+	Proxy ProxyConfig
+
+	// ProxyServer is the explicit proxy used where Proxy does not specify one; a socks5 URL selects the SOCKS5
+	// proxy. Retained for compatibility; Proxy should be used instead.
+	ProxyServer *url.URL
+	// ProxyAuthType and ProxyAuthCredentials make up the Proxy-Authorization header where Proxy does not
+	// specify the Authorization. Retained for compatibility; Proxy should be used instead.
 	ProxyAuthType        string
 	ProxyAuthCredentials string
 
@@ -27,29 +37,58 @@ type HTTPClientParams struct {
 	ExplicitHttpExecutor HttpExecutor
 }
 
-type httpProxyFunction func(*http.Request) (*url.URL, error)
+// ProxyConfig Returns the proxy configuration, with the compatibility fields applied.
+func (p *HTTPClientParams) ProxyConfig() ProxyConfig {
+	rv := p.Proxy
 
-func (p *HTTPClientParams) determineProxyAuth() http.Header {
-	if len(p.ProxyAuthType) > 0 && len(p.ProxyAuthCredentials) > 0 {
-		rv := http.Header{}
-		rv.Add("Proxy-Authorization", fmt.Sprintf("%s %s", p.ProxyAuthType, p.ProxyAuthCredentials))
+	if p.ProxyServer != nil && rv.HTTPProxy == nil && rv.HTTPSProxy == nil && len(rv.SOCKS5Address) == 0 {
+		if strings.HasPrefix(p.ProxyServer.Scheme, "socks5") {
+			rv.SOCKS5Address = p.ProxyServer.Host
+			if p.ProxyServer.User != nil {
+				rv.SOCKS5Username = p.ProxyServer.User.Username()
+				rv.SOCKS5Password, _ = p.ProxyServer.User.Password()
+			}
+		} else {
+			rv.HTTPProxy = p.ProxyServer
+		}
+	}
 
-		return rv
-	} else {
-		return nil
+	if rv.Authorization == nil && len(p.ProxyAuthType) > 0 && len(p.ProxyAuthCredentials) > 0 {
+		rv.Authorization = &ProxyAuthorization{
+			Scheme: ProxyAuthScheme(p.ProxyAuthType),
+			Token:  p.ProxyAuthCredentials,
+		}
 	}
+
+	return rv
 }
 
-func (p *HTTPClientParams) determineProxyMethod() httpProxyFunction {
-	if p.ProxyServer != nil {
-		return http.ProxyFromEnvironment
+// EffectiveTLSConfig Returns the TLSConfig with the TLS options applied. Where the options cannot be applied, e.g.
+// the certificate files cannot be read, the executors created with these params fail every request; use Validate
+// to detect this before the executor is created.
+func (p *HTTPClientParams) EffectiveTLSConfig() (*tls.Config, error) {
+	if p.TLS.IsEmpty() {
+		return p.TLSConfig, nil
+	}
+
+	if rv, err := p.TLS.Apply(p.TLSConfig); err != nil {
+		return nil, &errwrap.WrappedError{
+			Context: "apply tls options",
+			Cause:   err,
+		}
 	} else {
-		return p.yieldFixedProxyServer
+		return rv, nil
 	}
 }
 
-func (p *HTTPClientParams) yieldFixedProxyServer(_ *http.Request) (*url.URL, error) {
-	return p.ProxyServer, nil
+// Validate Checks that the executor can be created from these params, i.e. that the TLS options can be applied.
+func (p *HTTPClientParams) Validate() error {
+	if p.ExplicitHttpExecutor != nil {
+		return nil
+	}
+
+	_, err := p.EffectiveTLSConfig()
+	return err
 }
 
 func (p *HTTPClientParams) CreateHttpExecutor() HttpExecutor {
@@ -58,12 +97,16 @@ func (p *HTTPClientParams) CreateHttpExecutor() HttpExecutor {
 		return p.ExplicitHttpExecutor
 	}
 
+	tlsConfig, err := p.EffectiveTLSConfig()
+	if err != nil {
+		return NewFailingExecutor(err)
+	}
+
+	proxy := p.ProxyConfig()
 	return &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig:    p.TLSConfig,
-			Proxy:              p.determineProxyMethod(),
-			ProxyConnectHeader: p.determineProxyAuth(),
-		},
+		Transport: proxy.roundTripper(&http.Transport{
+			TLSClientConfig: tlsConfig,
+		}),
 		Timeout: p.Timeout,
 	}
 }
@@ -0,0 +1,198 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/errwrap"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ClientCertificate The client certificate and key presented for mutual TLS, read from the PEM files or bytes.
+// Where read from files, the certificate is reloaded when either file changes, so that renewed certificates are
+// picked up by the new connections without restarting the client.
+type ClientCertificate struct {
+	CertFile string
+	KeyFile  string
+
+	CertPEM []byte
+	KeyPEM  []byte
+}
+
+// TLSOptions Mutual TLS and trust options applied on top of the TLSConfig of the HTTPClientParams
+type TLSOptions struct {
+	ClientCertificate *ClientCertificate
+
+	// CABundleFile and CABundlePEM are the PEM certificates of the additional certificate authorities, e.g. the
+	// private CA of an egress gateway. They are appended to the root pool of the TLSConfig, or the system pool where
+	// the TLSConfig does not specify one, retaining the pins of the TLSConfig. The bundle is read when the client
+	// is created.
+	CABundleFile string
+	CABundlePEM  []byte
+}
+
+// IsEmpty Whether no options are specified
+func (o *TLSOptions) IsEmpty() bool {
+	return o.ClientCertificate == nil && len(o.CABundleFile) == 0 && len(o.CABundlePEM) == 0
+}
+
+// Apply Returns the copy of the base configuration with the options applied. A nil base stands for the default
+// configuration trusting the system pool.
+func (o *TLSOptions) Apply(base *tls.Config) (*tls.Config, error) {
+	var rv *tls.Config
+	if base != nil {
+		rv = base.Clone()
+	} else {
+		rv = &tls.Config{}
+	}
+
+	if len(o.CABundleFile) > 0 || len(o.CABundlePEM) > 0 {
+		pool, err := o.rootPool(rv.RootCAs)
+		if err != nil {
+			return nil, err
+		}
+		rv.RootCAs = pool
+	}
+
+	if o.ClientCertificate != nil {
+		reloader, err := newClientCertificateReloader(*o.ClientCertificate)
+		if err != nil {
+			return nil, err
+		}
+		rv.Certificates = nil
+		rv.GetClientCertificate = reloader.getClientCertificate
+	}
+
+	return rv, nil
+}
+
+func (o *TLSOptions) rootPool(base *x509.CertPool) (*x509.CertPool, error) {
+	var pool *x509.CertPool
+	if base != nil {
+		pool = base.Clone()
+	} else if sysPool, err := x509.SystemCertPool(); err == nil {
+		pool = sysPool
+	} else {
+		pool = x509.NewCertPool()
+	}
+
+	bundle := o.CABundlePEM
+	if len(o.CABundleFile) > 0 {
+		dat, err := os.ReadFile(o.CABundleFile)
+		if err != nil {
+			return nil, &errwrap.WrappedError{
+				Context: "read ca bundle",
+				Cause:   err,
+			}
+		}
+		bundle = append(append([]byte{}, bundle...), '\n')
+		bundle = append(bundle, dat...)
+	}
+
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, errors.New("ca bundle does not contain any pem certificates")
+	}
+	return pool, nil
+}
+
+type clientCertificateReloader struct {
+	source ClientCertificate
+
+	mutex    sync.Mutex
+	cert     *tls.Certificate
+	certStat fileStamp
+	keyStat  fileStamp
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func stampOf(path string) (fileStamp, error) {
+	if fi, err := os.Stat(path); err != nil {
+		return fileStamp{}, err
+	} else {
+		return fileStamp{modTime: fi.ModTime(), size: fi.Size()}, nil
+	}
+}
+
+func newClientCertificateReloader(src ClientCertificate) (*clientCertificateReloader, error) {
+	rv := &clientCertificateReloader{source: src}
+
+	if len(src.CertFile) == 0 && len(src.KeyFile) == 0 {
+		cert, err := tls.X509KeyPair(src.CertPEM, src.KeyPEM)
+		if err != nil {
+			return nil, &errwrap.WrappedError{
+				Context: "parse client certificate",
+				Cause:   err,
+			}
+		}
+		rv.cert = &cert
+		return rv, nil
+	} else if len(src.CertFile) == 0 || len(src.KeyFile) == 0 {
+		return nil, errors.New("client certificate requires both certificate and key files")
+	}
+
+	if _, err := rv.current(); err != nil {
+		return nil, err
+	}
+	return rv, nil
+}
+
+// current returns the certificate, reloading it from the files if either of them has changed since it was read.
+// Where the reload fails, e.g. because the files are being replaced, the previously loaded certificate is kept.
+func (r *clientCertificateReloader) current() (*tls.Certificate, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.source.CertFile) == 0 {
+		return r.cert, nil
+	}
+
+	certStat, certErr := stampOf(r.source.CertFile)
+	keyStat, keyErr := stampOf(r.source.KeyFile)
+	if certErr == nil && keyErr == nil && r.cert != nil && certStat == r.certStat && keyStat == r.keyStat {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.source.CertFile, r.source.KeyFile)
+	if err != nil {
+		if r.cert != nil {
+			return r.cert, nil
+		}
+		return nil, &errwrap.WrappedError{
+			Context: "load client certificate",
+			Cause:   err,
+		}
+	}
+
+	r.cert, r.certStat, r.keyStat = &cert, certStat, keyStat
+	return r.cert, nil
+}
+
+func (r *clientCertificateReloader) getClientCertificate(_ *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.current()
+}
+
+// failingExecutor fails every request with the error of the client configuration
+type failingExecutor struct {
+	err error
+}
+
+func (f *failingExecutor) Do(_ *http.Request) (*http.Response, error) {
+	return nil, f.err
+}
+
+func (f *failingExecutor) CloseIdleConnections() {
+	// Nothing to close
+}
+
+// NewFailingExecutor Returns the executor failing every request with the supplied error, used in place of the
+// executor that could not be configured.
+func NewFailingExecutor(err error) HttpExecutor {
+	return &failingExecutor{err: err}
+}
@@ -0,0 +1,183 @@
+package transport_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// issueClientCert returns the PEM certificate and key of the client certificate issued by the test CA
+func (pki *testPKI) issueClientCert(t *testing.T, commonName string) ([]byte, []byte) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, pki.caCert, &key.PublicKey, pki.caKey)
+	assert.Nil(t, err)
+
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+// mutualTLSServer starts the server requiring the client certificates issued by the test CA. The server responds
+// with the common name of the client.
+func (pki *testPKI) mutualTLSServer(t *testing.T) *httptest.Server {
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(pki.caCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	server.TLS = pki.server.TLS.Clone()
+	server.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+	server.TLS.ClientCAs = clientCAs
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func caBundleOf(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+func callForCommonName(t *testing.T, exec transport.HttpExecutor, url string) string {
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	resp, err := exec.Do(req)
+	if !assert.Nil(t, err) {
+		return ""
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestMutualTLSWithPEMBytes(t *testing.T) {
+	pki := newTestPKI(t)
+	server := pki.mutualTLSServer(t)
+	certPEM, keyPEM := pki.issueClientCert(t, "client-a")
+
+	params := transport.HTTPClientParams{
+		TLSConfig: &tls.Config{ServerName: "api.example.test"},
+		TLS: transport.TLSOptions{
+			ClientCertificate: &transport.ClientCertificate{CertPEM: certPEM, KeyPEM: keyPEM},
+			CABundlePEM:       caBundleOf(pki.caCert),
+		},
+		Proxy: transport.ProxyConfig{Direct: true},
+	}
+
+	exec := params.CreateHttpExecutor()
+	defer exec.CloseIdleConnections()
+	assert.Equal(t, "client-a", callForCommonName(t, exec, server.URL))
+
+	// Without the CA bundle, the server is not trusted.
+	params.TLS.CABundlePEM = nil
+	untrusting := params.CreateHttpExecutor()
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	_, err := untrusting.Do(req)
+	assert.NotNil(t, err)
+}
+
+func TestCABundleRetainsPins(t *testing.T) {
+	pki := newTestPKI(t)
+
+	pinner := (&transport.TLSPinner{}).Add(wrongSPKI)
+	cfg := pinner.CreateTLSConfig()
+	cfg.ServerName = "api.example.test"
+
+	params := transport.HTTPClientParams{
+		TLSConfig: cfg,
+		TLS:       transport.TLSOptions{CABundlePEM: caBundleOf(pki.caCert)},
+		Proxy:     transport.ProxyConfig{Direct: true},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, pki.server.URL, nil)
+	_, err := params.CreateHttpExecutor().Do(req)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "no matching chains")
+
+	params.TLSConfig = (&transport.TLSPinner{}).Add(spkiPin(pki.caCert)).CreateTLSConfig()
+	params.TLSConfig.ServerName = "api.example.test"
+	_, err = params.CreateHttpExecutor().Do(req)
+	assert.Nil(t, err)
+}
+
+func TestClientCertificateReloadsOnFileChange(t *testing.T) {
+	pki := newTestPKI(t)
+	server := pki.mutualTLSServer(t)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	caFile := filepath.Join(dir, "ca.pem")
+
+	certPEM, keyPEM := pki.issueClientCert(t, "client-a")
+	assert.Nil(t, os.WriteFile(certFile, certPEM, 0600))
+	assert.Nil(t, os.WriteFile(keyFile, keyPEM, 0600))
+	assert.Nil(t, os.WriteFile(caFile, caBundleOf(pki.caCert), 0600))
+
+	params := transport.HTTPClientParams{
+		TLSConfig: &tls.Config{ServerName: "api.example.test"},
+		TLS: transport.TLSOptions{
+			ClientCertificate: &transport.ClientCertificate{CertFile: certFile, KeyFile: keyFile},
+			CABundleFile:      caFile,
+		},
+		Proxy: transport.ProxyConfig{Direct: true},
+	}
+
+	exec := params.CreateHttpExecutor()
+	defer exec.CloseIdleConnections()
+	assert.Equal(t, "client-a", callForCommonName(t, exec, server.URL))
+
+	certPEM, keyPEM = pki.issueClientCert(t, "client-b")
+	assert.Nil(t, os.WriteFile(certFile, certPEM, 0600))
+	assert.Nil(t, os.WriteFile(keyFile, keyPEM, 0600))
+	later := time.Now().Add(time.Minute)
+	assert.Nil(t, os.Chtimes(certFile, later, later))
+	assert.Nil(t, os.Chtimes(keyFile, later, later))
+
+	// The new connection presents the renewed certificate.
+	exec.CloseIdleConnections()
+	assert.Equal(t, "client-b", callForCommonName(t, exec, server.URL))
+}
+
+func TestInvalidTLSOptionsFailRequests(t *testing.T) {
+	params := transport.HTTPClientParams{
+		TLS: transport.TLSOptions{
+			ClientCertificate: &transport.ClientCertificate{CertFile: "/non/existent.crt", KeyFile: "/non/existent.key"},
+		},
+	}
+
+	_, err := params.EffectiveTLSConfig()
+	assert.NotNil(t, err)
+	assert.NotNil(t, params.Validate())
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.mashery.com/", nil)
+	_, err = params.CreateHttpExecutor().Do(req)
+	assert.NotNil(t, err)
+
+	params.TLS = transport.TLSOptions{CABundlePEM: []byte("not a pem")}
+	_, err = params.EffectiveTLSConfig()
+	assert.NotNil(t, err)
+}
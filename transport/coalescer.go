@@ -0,0 +1,166 @@
+package transport
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RequestCoalescer Collapses identical GET calls that are in flight at the same time into a single exchange
+// with Mashery. The calls are identical if they address the same endpoint, resource and query with the same
+// authorization. Every waiting caller receives its own WrappedResponse sharing the body read by the exchange.
+//
+// The middleware should be placed after the throttling and retry middleware, so that the coalesced callers
+// neither wait for a call slot nor repeat the exchange.
+type RequestCoalescer struct {
+	mutex sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+type coalescedCall struct {
+	done    chan struct{}
+	wr      *WrappedResponse
+	body    []byte
+	bodyErr error
+	err     error
+}
+
+func NewRequestCoalescer() *RequestCoalescer {
+	return &RequestCoalescer{
+		calls: map[string]*coalescedCall{},
+	}
+}
+
+// InFlight Number of distinct calls currently in flight
+func (rc *RequestCoalescer) InFlight() int {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	return len(rc.calls)
+}
+
+// Middleware Returns the middleware function applying this coalescer.
+func (rc *RequestCoalescer) Middleware() ChainedMiddlewareFunc {
+	return func(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
+		ci := CallInfoFromContext(ctx)
+		if ci == nil || ci.Method() != http.MethodGet {
+			return next(ctx, c)
+		}
+
+		key, err := rc.callKey(ctx, c, ci)
+		if err != nil {
+			return nil, err
+		}
+
+		rc.mutex.Lock()
+		if call, ok := rc.calls[key]; ok {
+			rc.mutex.Unlock()
+			return rc.wait(ctx, c, next, call)
+		}
+
+		call := &coalescedCall{done: make(chan struct{})}
+		rc.calls[key] = call
+		rc.mutex.Unlock()
+
+		// The waiters are released even if the exchange panics; they will then observe no response.
+		defer rc.release(key, call)
+
+		wr, err := next(ctx, c)
+		call.wr, call.err = wr, err
+		if wr != nil && wr.Response != nil {
+			// The body is read before the waiters are released, so that each of them can be given a copy.
+			call.body, call.bodyErr = wr.Body()
+		}
+
+		return wr, err
+	}
+}
+
+func (rc *RequestCoalescer) release(key string, call *coalescedCall) {
+	rc.mutex.Lock()
+	delete(rc.calls, key)
+	rc.mutex.Unlock()
+
+	close(call.done)
+}
+
+func (rc *RequestCoalescer) wait(ctx context.Context, c *HttpTransport, next MiddlewareFunc, call *coalescedCall) (*WrappedResponse, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-call.done:
+	}
+
+	// The call that made the exchange was cancelled by its caller; this caller has to make its own.
+	if call.err != nil && (errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded)) {
+		return next(ctx, c)
+	}
+
+	if call.wr == nil {
+		if call.err == nil {
+			return nil, errors.New("coalesced call completed without a response")
+		}
+		return nil, call.err
+	}
+
+	return sharedResponse(call.wr, call.body, call.bodyErr), call.err
+}
+
+// sharedResponse returns a copy of the response whose body was already read
+func sharedResponse(src *WrappedResponse, body []byte, bodyErr error) *WrappedResponse {
+	rv := &WrappedResponse{
+		Request:       src.Request,
+		Response:      src.Response,
+		StatusCode:    src.StatusCode,
+		Header:        src.Header.Clone(),
+		CorrelationId: src.CorrelationId,
+	}
+	rv.once.Do(func() {
+		rv.readBody, rv.readError = body, bodyErr
+	})
+
+	return rv
+}
+
+// CoalescingIdentity Implemented by the authorizers whose authorization is not known until the call is dispatched,
+// e.g. the KeyPool. The calls are coalesced by the identity instead of the authorization headers.
+type CoalescingIdentity interface {
+	CoalescingIdentity() string
+}
+
+// callKey identifies the call by the endpoint, resource, query and a digest of the authorization.
+func (rc *RequestCoalescer) callKey(ctx context.Context, c *HttpTransport, ci *CallInfo) (string, error) {
+	digest := sha256.New()
+	if ident, ok := c.Authorizer.(CoalescingIdentity); ok {
+		digest.Write([]byte(ident.CoalescingIdentity()))
+	} else if c.Authorizer != nil {
+		hdr, err := c.Authorizer.HeaderAuthorization(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		keys := make([]string, 0, len(hdr))
+		for k := range hdr {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			digest.Write([]byte(k))
+			digest.Write([]byte{0})
+			digest.Write([]byte(hdr[k]))
+			digest.Write([]byte{0})
+		}
+	}
+
+	return strings.Join([]string{
+		c.MashEndpoint,
+		ci.Resource,
+		ci.Query.Encode(),
+		hex.EncodeToString(digest.Sum(nil)),
+	}, "\n"), nil
+}
@@ -0,0 +1,152 @@
+package transport_test
+
+import (
+	"context"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3simulator"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func coalescingClient(sim *v3simulator.Simulator, token string, coalescer *transport.RequestCoalescer) v3client.Client {
+	return v3simulator.NewClient(sim, func(p *v3client.Params) {
+		p.Authorizer = transport.NewBearerAuthorizer(token)
+		p.Coalescer = coalescer
+	})
+}
+
+// slowGets delays the GET requests, counting them
+func slowGets(sim *v3simulator.Simulator) *int32 {
+	var calls int32
+	sim.Interceptor = func(r *http.Request) *http.Response {
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(100 * time.Millisecond)
+		}
+		return nil
+	}
+	return &calls
+}
+
+func TestCoalescerCollapsesIdenticalGets(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	svcId, _ := sim.Create("/services", masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}, Description: "desc"})
+	calls := slowGets(sim)
+
+	coalescer := transport.NewRequestCoalescer()
+	cl := coalescingClient(sim, "sim-token", coalescer)
+
+	wg := sync.WaitGroup{}
+	results := make([]masherytypes.Service, 10)
+	errs := make([]error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			results[idx], _, errs[idx] = cl.GetService(context.Background(), masherytypes.ServiceIdentityFrom(svcId))
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, errs[i])
+		assert.Equal(t, "desc", results[i].Description)
+	}
+	assert.Equal(t, 0, coalescer.InFlight())
+}
+
+func TestCoalescerKeysOnAuthorizationAndQuery(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	svcId, _ := sim.Create("/services", masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	sim.AccessToken = ""
+	calls := slowGets(sim)
+
+	coalescer := transport.NewRequestCoalescer()
+	first := coalescingClient(sim, "token-1", coalescer)
+	second := coalescingClient(sim, "token-2", coalescer)
+
+	wg := sync.WaitGroup{}
+	run := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+	}
+
+	run(func() { _, _ = first.ListServices(context.Background()) })
+	run(func() { _, _ = second.ListServices(context.Background()) })
+	run(func() { _, _ = first.ListServicesFiltered(context.Background(), map[string]string{"name": "svc"}) })
+	run(func() { _, _, _ = first.GetService(context.Background(), masherytypes.ServiceIdentityFrom(svcId)) })
+	wg.Wait()
+
+	assert.Equal(t, int32(4), atomic.LoadInt32(calls))
+}
+
+func TestCoalescerDoesNotCollapseWrites(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	var posts int32
+	sim.Interceptor = func(r *http.Request) *http.Response {
+		if r.Method == http.MethodPost {
+			atomic.AddInt32(&posts, 1)
+			time.Sleep(50 * time.Millisecond)
+		}
+		return nil
+	}
+
+	cl := coalescingClient(sim, "sim-token", transport.NewRequestCoalescer())
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cl.CreateService(context.Background(), masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&posts))
+}
+
+func TestCoalescerCollapsesGetsDispatchedByKeyPool(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	svcId, _ := sim.Create("/services", masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	sim.AccessToken = ""
+	calls := slowGets(sim)
+
+	pool := transport.NewKeyPool()
+	pool.AddKey("key-a", 5, transport.NewBearerAuthorizer("token-a"))
+	pool.AddKey("key-b", 5, transport.NewBearerAuthorizer("token-b"))
+
+	cl := v3simulator.NewClient(sim, func(p *v3client.Params) {
+		p.KeyPool = pool
+		p.Coalescer = transport.NewRequestCoalescer()
+	})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := cl.GetService(context.Background(), masherytypes.ServiceIdentityFrom(svcId))
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+
+	// Only the key the exchange was dispatched to is marked as used.
+	usage := pool.Usage()
+	assert.Equal(t, uint64(1), usage[0].Calls+usage[1].Calls)
+	assert.True(t, usage[0].LastUsed.IsZero() != usage[1].LastUsed.IsZero())
+}
@@ -0,0 +1,183 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// PlannedOperation A mutating call that was intercepted by the dry run instead of being sent to Mashery.
+type PlannedOperation struct {
+	Method     string          `json:"method"`
+	Resource   string          `json:"resource"`
+	Query      url.Values      `json:"query,omitempty"`
+	AppContext string          `json:"appContext,omitempty"`
+	Body       json.RawMessage `json:"body,omitempty"`
+}
+
+// DryRunPlan Collects the mutating calls (POST, PUT, PATCH and DELETE) the transport would have made. Where a
+// transport has the plan set, GET calls are passed to Mashery as usual, while the mutating calls are recorded
+// and answered with a synthetic success response echoing the request body. The same plan can be shared
+// between transports.
+//
+// Note that V2 API uses POST for the queries as well; a dry run is therefore meaningful for V3 transports only.
+type DryRunPlan struct {
+	mutex      sync.Mutex
+	operations []PlannedOperation
+}
+
+func NewDryRunPlan() *DryRunPlan {
+	return &DryRunPlan{}
+}
+
+// Operations Returns the copy of the operations planned so far, in the order these were intercepted.
+func (p *DryRunPlan) Operations() []PlannedOperation {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	rv := make([]PlannedOperation, len(p.operations))
+	copy(rv, p.operations)
+	return rv
+}
+
+// Len Number of the operations planned so far
+func (p *DryRunPlan) Len() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return len(p.operations)
+}
+
+// Reset Discards the operations planned so far
+func (p *DryRunPlan) Reset() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.operations = nil
+}
+
+func (p *DryRunPlan) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.Operations())
+}
+
+// Print Writes the human-readable plan, one numbered operation followed by its indented body.
+func (p *DryRunPlan) Print(w io.Writer) error {
+	ops := p.Operations()
+	if len(ops) == 0 {
+		_, err := fmt.Fprintln(w, "No changes planned")
+		return err
+	}
+
+	for idx, op := range ops {
+		target := op.Resource
+		if len(op.Query) > 0 {
+			target = fmt.Sprintf("%s?%s", op.Resource, op.Query.Encode())
+		}
+
+		line := fmt.Sprintf("%d. %s %s", idx+1, op.Method, target)
+		if len(op.AppContext) > 0 {
+			line = fmt.Sprintf("%s (%s)", line, op.AppContext)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+
+		if len(op.Body) > 0 {
+			buf := bytes.Buffer{}
+			if err := json.Indent(&buf, op.Body, "   ", "  "); err != nil {
+				buf.Reset()
+				buf.Write(op.Body)
+			}
+			if _, err := fmt.Fprintf(w, "   %s\n", buf.String()); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *DryRunPlan) String() string {
+	sb := strings.Builder{}
+	_ = p.Print(&sb)
+	return sb.String()
+}
+
+// intercepts whether the request is a mutating one that has to be planned rather than sent
+func (p *DryRunPlan) intercepts(r *http.Request) bool {
+	return p.interceptsMethod(r.Method)
+}
+
+func (p *DryRunPlan) interceptsMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// plan records the request and returns the synthetic response to it.
+func (p *DryRunPlan) plan(ctx context.Context, mashEndpoint string, wrq *WrappedRequest) (*WrappedResponse, error) {
+	body := requestBody(wrq)
+
+	op := PlannedOperation{
+		Method:   strings.ToUpper(wrq.Request.Method),
+		Resource: strings.TrimPrefix(wrq.Request.URL.Path, endpointPath(mashEndpoint)),
+		Body:     body,
+	}
+	if qs := wrq.Request.URL.Query(); len(qs) > 0 {
+		op.Query = qs
+	}
+
+	correlationId := ""
+	if ci := CallInfoFromContext(ctx); ci != nil {
+		op.AppContext = ci.AppContext
+		correlationId = ci.CorrelationId
+		if len(ci.Resource) > 0 {
+			op.Resource = ci.Resource
+		}
+	}
+
+	p.mutex.Lock()
+	p.operations = append(p.operations, op)
+	p.mutex.Unlock()
+
+	hdr := http.Header{}
+	hdr.Set("Content-Type", "application/json")
+
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", http.StatusOK, http.StatusText(http.StatusOK)),
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        hdr,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       wrq.Request,
+	}
+
+	return &WrappedResponse{
+		Request:       wrq,
+		Response:      resp,
+		StatusCode:    resp.StatusCode,
+		Header:        resp.Header,
+		CorrelationId: correlationId,
+	}, nil
+}
+
+// endpointPath returns the path of the endpoint, or an empty string if the endpoint is not a valid URL.
+func endpointPath(endpoint string) string {
+	if u, err := url.Parse(endpoint); err == nil {
+		return u.Path
+	}
+
+	return ""
+}
@@ -0,0 +1,102 @@
+package transport_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3simulator"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDryRunPlansMutationsAndPassesReads(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	ctx := context.Background()
+
+	live := v3simulator.NewClient(sim)
+	srv, err := live.CreateService(ctx, masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "existing"}})
+	assert.Nil(t, err)
+
+	plan := transport.NewDryRunPlan()
+	cl := v3simulator.NewClient(sim, func(p *v3client.Params) {
+		p.DryRun = plan
+	})
+
+	// Reads are passed to Mashery
+	fetched, exists, err := cl.GetService(ctx, srv.Identifier())
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "existing", fetched.Name)
+
+	created, err := cl.CreateService(ctx, masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "planned"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "planned", created.Name)
+
+	fetched.Name = "renamed"
+	updated, err := cl.UpdateService(ctx, fetched)
+	assert.Nil(t, err)
+	assert.Equal(t, "renamed", updated.Name)
+
+	assert.Nil(t, cl.DeleteService(ctx, srv.Identifier()))
+
+	// None of the changes have reached Mashery
+	cnt, err := live.CountServices(ctx, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), cnt)
+	stored, _, _ := live.GetService(ctx, srv.Identifier())
+	assert.Equal(t, "existing", stored.Name)
+
+	ops := plan.Operations()
+	assert.Equal(t, 3, len(ops))
+	assert.Equal(t, "POST", ops[0].Method)
+	assert.Equal(t, "/services", ops[0].Resource)
+	assert.NotEmpty(t, ops[0].AppContext)
+	assert.Contains(t, string(ops[0].Body), "planned")
+
+	assert.Equal(t, "PUT", ops[1].Method)
+	assert.Equal(t, "/services/"+srv.Id, ops[1].Resource)
+	assert.Contains(t, string(ops[1].Body), "renamed")
+
+	assert.Equal(t, "DELETE", ops[2].Method)
+	assert.Empty(t, ops[2].Body)
+
+	dat, err := json.Marshal(plan)
+	assert.Nil(t, err)
+	var decoded []transport.PlannedOperation
+	assert.Nil(t, json.Unmarshal(dat, &decoded))
+	assert.Equal(t, ops, decoded)
+
+	printed := plan.String()
+	assert.Contains(t, printed, "1. POST /services")
+	assert.Contains(t, printed, "3. DELETE /services/"+srv.Id)
+
+	plan.Reset()
+	assert.Equal(t, 0, plan.Len())
+	assert.Equal(t, "No changes planned\n", plan.String())
+}
+
+func TestDryRunMutationsBypassPipeline(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	ctx := context.Background()
+
+	passed := map[string]int{}
+	counter := func(ctx context.Context, c *transport.HttpTransport, next transport.MiddlewareFunc) (*transport.WrappedResponse, error) {
+		passed[transport.CallInfoFromContext(ctx).Method()]++
+		return next(ctx, c)
+	}
+
+	cl := v3simulator.NewClient(sim, func(p *v3client.Params) {
+		p.DryRun = transport.NewDryRunPlan()
+		p.Pipeline = []transport.ChainedMiddlewareFunc{transport.ErrorOn404Func, counter}
+	})
+
+	_, err := cl.CreateService(ctx, masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "planned"}})
+	assert.Nil(t, err)
+	_, _, err = cl.GetService(ctx, masherytypes.ServiceIdentityFrom("missing"))
+	assert.Nil(t, err)
+
+	// Only the read has passed the pipeline
+	assert.Equal(t, map[string]int{"GET": 1}, passed)
+}
@@ -0,0 +1,395 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HarNameValue Name-value pair of the HAR headers and query string
+type HarNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HarPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type HarRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HarNameValue `json:"cookies"`
+	Headers     []HarNameValue `json:"headers"`
+	QueryString []HarNameValue `json:"queryString"`
+	PostData    *HarPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type HarContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+}
+
+type HarResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HarNameValue `json:"cookies"`
+	Headers     []HarNameValue `json:"headers"`
+	Content     HarContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type HarTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+type HarEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HarRequest  `json:"request"`
+	Response        HarResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HarTimings  `json:"timings"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+type HarCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type HarLog struct {
+	Version string     `json:"version"`
+	Creator HarCreator `json:"creator"`
+	Entries []HarEntry `json:"entries"`
+}
+
+// HarArchive Top-level object of the HTTP Archive 1.2 file
+type HarArchive struct {
+	Log HarLog `json:"log"`
+}
+
+// DefaultHarFlushInterval Interval at which the HarRecorder writes the archive file where it doesn't specify it
+const DefaultHarFlushInterval = 10 * time.Second
+
+// DefaultHarMaxEntries Number of entries the HarRecorder holds where it doesn't specify it
+const DefaultHarMaxEntries = 1000
+
+// HarRecorder Collects the exchanges with Mashery as an HTTP Archive (HAR 1.2). Secrets are redacted as
+// configured by the Scrubber. Where Path is set, the archive file is written at most once per FlushInterval as the
+// exchanges are recorded, and on Flush and Close; call Close once the exchanges are complete.
+//
+// The recorder holds at most MaxEntries entries. Where Path is set, the archive reaching MaxEntries is written and
+// renamed to Path.1, the previous Path.1 to Path.2, and so on, and the next exchanges are recorded into a new archive.
+// Without the Path, the oldest entries are dropped.
+type HarRecorder struct {
+	Path     string
+	Scrubber CassetteScrubber
+	// FlushInterval is the minimum interval between the writes of the archive file. Zero selects
+	// DefaultHarFlushInterval.
+	FlushInterval time.Duration
+	// MaxEntries is the number of entries the archive is rotated at. Zero selects DefaultHarMaxEntries.
+	MaxEntries int
+
+	mutex     sync.Mutex
+	entries   []HarEntry
+	unsaved   bool
+	lastFlush time.Time
+}
+
+func NewHarRecorder(path string) *HarRecorder {
+	return &HarRecorder{
+		Path:      path,
+		Scrubber:  DefaultCassetteScrubber(),
+		lastFlush: time.Now(),
+	}
+}
+
+// Listen Adds the exchange to the archive. The method has the signature of the ExchangeListener.
+func (hr *HarRecorder) Listen(_ context.Context, req *WrappedRequest, res *WrappedResponse, err error) {
+	if req == nil || req.Request == nil {
+		return
+	}
+
+	started := req.Sent
+	if started.IsZero() {
+		started = time.Now()
+	}
+	elapsed := float64(time.Since(started).Microseconds()) / 1000
+
+	entry := HarEntry{
+		StartedDateTime: started.Format("2006-01-02T15:04:05.000Z07:00"),
+		Time:            elapsed,
+		Request:         hr.harRequest(req),
+		Timings:         HarTimings{Wait: elapsed},
+	}
+
+	if res != nil && res.Response != nil {
+		entry.Response = hr.harResponse(res)
+	} else {
+		entry.Response = HarResponse{
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     []HarNameValue{},
+			Headers:     []HarNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		}
+	}
+	if err != nil {
+		entry.Comment = err.Error()
+	}
+
+	hr.mutex.Lock()
+	defer hr.mutex.Unlock()
+
+	hr.entries = append(hr.entries, entry)
+	hr.unsaved = true
+	if len(hr.entries) >= hr.maxEntries() {
+		if len(hr.Path) > 0 {
+			_ = hr.rotateLocked()
+		} else {
+			n := copy(hr.entries, hr.entries[len(hr.entries)-hr.maxEntries():])
+			hr.entries = hr.entries[:n]
+		}
+	} else if len(hr.Path) > 0 && time.Since(hr.lastFlush) >= hr.flushInterval() {
+		_ = hr.flushLocked()
+	}
+}
+
+// Flush Writes the archive into the Path, if there are exchanges not yet written
+func (hr *HarRecorder) Flush() error {
+	hr.mutex.Lock()
+	defer hr.mutex.Unlock()
+
+	return hr.flushLocked()
+}
+
+// Close Writes the exchanges not yet written into the Path
+func (hr *HarRecorder) Close() error {
+	return hr.Flush()
+}
+
+func (hr *HarRecorder) flushInterval() time.Duration {
+	if hr.FlushInterval > 0 {
+		return hr.FlushInterval
+	}
+	return DefaultHarFlushInterval
+}
+
+func (hr *HarRecorder) maxEntries() int {
+	if hr.MaxEntries > 0 {
+		return hr.MaxEntries
+	}
+	return DefaultHarMaxEntries
+}
+
+// rotateLocked writes the full archive, and moves it to the first rotated file, shifting the files rotated before.
+func (hr *HarRecorder) rotateLocked() error {
+	if err := hr.flushLocked(); err != nil {
+		return err
+	}
+
+	last := 0
+	for {
+		if _, err := os.Stat(hr.rotatedPath(last + 1)); err != nil {
+			break
+		}
+		last++
+	}
+
+	for i := last; i >= 1; i-- {
+		if err := os.Rename(hr.rotatedPath(i), hr.rotatedPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(hr.Path, hr.rotatedPath(1)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	hr.entries = nil
+	return nil
+}
+
+func (hr *HarRecorder) rotatedPath(idx int) string {
+	return fmt.Sprintf("%s.%d", hr.Path, idx)
+}
+
+func (hr *HarRecorder) flushLocked() error {
+	if len(hr.Path) == 0 || !hr.unsaved {
+		return nil
+	}
+
+	hr.lastFlush = time.Now()
+	if err := hr.saveLocked(hr.Path); err != nil {
+		return err
+	}
+
+	hr.unsaved = false
+	return nil
+}
+
+// Archive Returns the archive of the exchanges recorded since the last rotation
+func (hr *HarRecorder) Archive() HarArchive {
+	hr.mutex.Lock()
+	defer hr.mutex.Unlock()
+
+	return hr.archiveLocked()
+}
+
+func (hr *HarRecorder) archiveLocked() HarArchive {
+	entries := make([]HarEntry, len(hr.entries))
+	copy(entries, hr.entries)
+
+	return HarArchive{
+		Log: HarLog{
+			Version: "1.2",
+			Creator: HarCreator{Name: "mashery-v3-go-client", Version: "1.0"},
+			Entries: entries,
+		},
+	}
+}
+
+// WriteTo Writes the archive as JSON
+func (hr *HarRecorder) WriteTo(w io.Writer) (int64, error) {
+	dat, err := json.MarshalIndent(hr.Archive(), "", "  ")
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(dat)
+	return int64(n), err
+}
+
+// Save Writes the archive into the specified file
+func (hr *HarRecorder) Save(path string) error {
+	hr.mutex.Lock()
+	defer hr.mutex.Unlock()
+
+	return hr.saveLocked(path)
+}
+
+func (hr *HarRecorder) saveLocked(path string) error {
+	dat, err := json.MarshalIndent(hr.archiveLocked(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// The archive is written into a temporary file first, so that readers never observe a partial file.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".har-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(dat); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (hr *HarRecorder) harRequest(req *WrappedRequest) HarRequest {
+	r := req.Request
+
+	rv := HarRequest{
+		Method:      r.Method,
+		URL:         r.URL.String(),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []HarNameValue{},
+		Headers:     harHeaders(hr.Scrubber.scrubHeader(r.Header)),
+		QueryString: []HarNameValue{},
+		HeadersSize: -1,
+	}
+
+	if qs := r.URL.Query(); len(qs) > 0 {
+		scrubbed := hr.Scrubber.scrubQuery(qs)
+		u := *r.URL
+		u.RawQuery = scrubbed.Encode()
+		rv.URL = u.String()
+
+		keys := make([]string, 0, len(scrubbed))
+		for k := range scrubbed {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			for _, v := range scrubbed[k] {
+				rv.QueryString = append(rv.QueryString, HarNameValue{Name: k, Value: v})
+			}
+		}
+	}
+
+	if body := requestBody(req); len(body) > 0 {
+		ct := r.Header.Get("Content-Type")
+		rv.PostData = &HarPostData{
+			MimeType: ct,
+			Text:     hr.Scrubber.scrubBody(ct, body),
+		}
+		rv.BodySize = len(body)
+	}
+
+	return rv
+}
+
+func (hr *HarRecorder) harResponse(res *WrappedResponse) HarResponse {
+	rv := HarResponse{
+		Status:      res.StatusCode,
+		StatusText:  http.StatusText(res.StatusCode),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []HarNameValue{},
+		Headers:     harHeaders(hr.Scrubber.scrubHeader(res.Header)),
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+	if len(res.Response.Proto) > 0 {
+		rv.HTTPVersion = res.Response.Proto
+	}
+
+	ct := res.Header.Get("Content-Type")
+	rv.Content.MimeType = ct
+	if body, err := res.Body(); err == nil {
+		rv.Content.Size = len(body)
+		rv.Content.Text = hr.Scrubber.scrubBody(ct, body)
+		rv.BodySize = len(body)
+	}
+
+	return rv
+}
+
+func harHeaders(h http.Header) []HarNameValue {
+	rv := []HarNameValue{}
+
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range h[k] {
+			rv = append(rv, HarNameValue{Name: k, Value: v})
+		}
+	}
+	return rv
+}
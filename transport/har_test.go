@@ -0,0 +1,149 @@
+package transport_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3simulator"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHarRecorderWritesArchive(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	harFile := filepath.Join(t.TempDir(), "traffic.har")
+	recorder := transport.NewHarRecorder(harFile)
+
+	cl := v3simulator.NewClient(sim, func(p *v3client.Params) {
+		p.Authorizer = transport.NewBearerAuthorizer("secret-token")
+		p.ExchangeListener = recorder.Listen
+	})
+
+	ctx := context.Background()
+	member, err := cl.CreateMember(ctx, masherytypes.Member{Username: "user"})
+	assert.Nil(t, err)
+	app, err := cl.CreateApplication(ctx, member.Identifier(), masherytypes.Application{AddressableV3Object: masherytypes.AddressableV3Object{Name: "app"}})
+	assert.Nil(t, err)
+	key, err := cl.CreateApplicationPackageKey(ctx, app.Identifier(), masherytypes.ApplicationPackageKey{})
+	assert.Nil(t, err)
+
+	_, exists, err := cl.GetService(ctx, masherytypes.ServiceIdentityFrom("missing"))
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	// The archive is written on close
+	_, err = os.Stat(harFile)
+	assert.True(t, os.IsNotExist(err))
+	assert.Nil(t, recorder.Close())
+
+	dat, err := os.ReadFile(harFile)
+	assert.Nil(t, err)
+	assert.False(t, strings.Contains(string(dat), "secret-token"))
+	assert.False(t, strings.Contains(string(dat), *key.Secret))
+
+	archive := transport.HarArchive{}
+	assert.Nil(t, json.Unmarshal(dat, &archive))
+	assert.Equal(t, "1.2", archive.Log.Version)
+	assert.Equal(t, 4, len(archive.Log.Entries))
+
+	first := archive.Log.Entries[0]
+	assert.Equal(t, "POST", first.Request.Method)
+	assert.NotNil(t, first.Request.PostData)
+	assert.Contains(t, first.Request.PostData.Text, "user")
+	assert.Equal(t, 200, first.Response.Status)
+	assert.NotEmpty(t, first.StartedDateTime)
+	assert.Contains(t, first.Response.Content.Text, member.Id)
+
+	last := archive.Log.Entries[3]
+	assert.Equal(t, 404, last.Response.Status)
+	assert.Equal(t, "Not Found", last.Response.StatusText)
+
+	hasAuth := false
+	for _, h := range first.Request.Headers {
+		if h.Name == "Authorization" {
+			hasAuth = true
+			assert.Equal(t, "REDACTED", h.Value)
+		}
+	}
+	assert.True(t, hasAuth)
+}
+
+func TestCombineListeners(t *testing.T) {
+	assert.Nil(t, transport.CombineListeners(nil, nil))
+
+	calls := 0
+	l := func(ctx context.Context, req *transport.WrappedRequest, res *transport.WrappedResponse, err error) {
+		calls++
+	}
+
+	combined := transport.CombineListeners(l, nil, l)
+	combined(context.Background(), nil, nil, nil)
+	assert.Equal(t, 2, calls)
+}
+
+func TestHarRecorderFlushesAtInterval(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	harFile := filepath.Join(t.TempDir(), "traffic.har")
+	recorder := transport.NewHarRecorder(harFile)
+	recorder.FlushInterval = 50 * time.Millisecond
+
+	cl := v3simulator.NewClient(sim, func(p *v3client.Params) {
+		p.ExchangeListener = recorder.Listen
+	})
+
+	ctx := context.Background()
+	_, _ = cl.ListServices(ctx)
+	_, err := os.Stat(harFile)
+	assert.True(t, os.IsNotExist(err))
+
+	time.Sleep(60 * time.Millisecond)
+	_, _ = cl.ListServices(ctx)
+
+	archive := transport.HarArchive{}
+	dat, err := os.ReadFile(harFile)
+	assert.Nil(t, err)
+	assert.Nil(t, json.Unmarshal(dat, &archive))
+	assert.Equal(t, 2, len(archive.Log.Entries))
+}
+
+func TestHarRecorderRotatesAtMaxEntries(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	harFile := filepath.Join(t.TempDir(), "traffic.har")
+	recorder := transport.NewHarRecorder(harFile)
+	recorder.MaxEntries = 2
+
+	cl := v3simulator.NewClient(sim, func(p *v3client.Params) {
+		p.ExchangeListener = recorder.Listen
+	})
+
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		_, _ = cl.ListServices(ctx)
+	}
+	assert.Equal(t, 1, len(recorder.Archive().Log.Entries))
+	assert.Nil(t, recorder.Close())
+
+	for path, entries := range map[string]int{harFile: 1, harFile + ".1": 2, harFile + ".2": 2} {
+		archive := transport.HarArchive{}
+		dat, err := os.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Nil(t, json.Unmarshal(dat, &archive))
+		assert.Equal(t, entries, len(archive.Log.Entries), path)
+	}
+
+	// Without the file, the oldest entries are dropped
+	inMemory := &transport.HarRecorder{MaxEntries: 2}
+	cl = v3simulator.NewClient(sim, func(p *v3client.Params) {
+		p.ExchangeListener = inMemory.Listen
+	})
+	for i := 0; i < 3; i++ {
+		_, _ = cl.ListServices(ctx)
+	}
+	assert.Equal(t, 2, len(inMemory.Archive().Log.Entries))
+}
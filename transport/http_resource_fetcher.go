@@ -36,8 +36,14 @@ func (rfr *ReceivedFeederResponse) IsExpired(debounce time.Duration) bool {
 type VaultToken string
 
 func NewVaultTokenResourceAuthorizer(url string, token VaultToken) Authorizer {
+	return NewVaultTokenResourceAuthorizerWithParams(url, token, HTTPClientParams{})
+}
+
+// NewVaultTokenResourceAuthorizerWithParams Creates the authorizer reading the token from the Vault, connecting as
+// the params specify, e.g. via a proxy.
+func NewVaultTokenResourceAuthorizerWithParams(url string, token VaultToken, params HTTPClientParams) Authorizer {
 	rv := HttpResourceFetcher{
-		client: &http.Client{},
+		client: params.CreateHttpExecutor(),
 		url:    url,
 		headers: map[string]string{
 			"X-Vault-Token": string(token),
@@ -50,7 +56,7 @@ func NewVaultTokenResourceAuthorizer(url string, token VaultToken) Authorizer {
 }
 
 type HttpResourceFetcher struct {
-	client *http.Client
+	client HttpExecutor
 
 	cachedResponse    ReceivedFeederResponse
 	url               string
@@ -27,8 +27,16 @@ type HttpTransport struct {
 
 	Mutex *sync.Mutex
 
+	// RateLimiter paces the calls. Where it is not set, calls are allocated per wall-clock second
+	// using DelayBeforeCall.
+	RateLimiter RateLimiter
+
 	ExchangeListener ExchangeListener
 	Pipeline         MiddlewareFunc
+	// Tracer, if set, receives the spans of the pipeline stages
+	Tracer Tracer
+	// DryRun, if set, records the mutating calls into the plan instead of sending them
+	DryRun *DryRunPlan
 }
 
 func (c *HttpTransport) DelayBeforeCall() time.Duration {
@@ -59,6 +67,27 @@ func (c *HttpTransport) DelayBeforeCall() time.Duration {
 	}
 }
 
+// WaitForCallSlot Blocks until the next call can be made according to the rate limiter or the context is done.
+// The call fails immediately where the wait would exceed the MaxThrottleWait of the call options or the deadline
+// of the context.
+func (c *HttpTransport) WaitForCallSlot(ctx context.Context) error {
+	if c.RateLimiter != nil {
+		return c.RateLimiter.Wait(ctx)
+	}
+
+	delay := c.DelayBeforeCall()
+	if err := checkThrottleWait(ctx, delay); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
+
 func (c *HttpTransport) Fetch(ctx context.Context, res string) (*WrappedResponse, error) {
 	uri := fmt.Sprintf("%s%s", c.MashEndpoint, res)
 
@@ -112,6 +141,11 @@ func (c *HttpTransport) httpExec(ctx context.Context, wrq *WrappedRequest) (*Wra
 		return nil, ctx.Err()
 	}
 
+	// Mutating calls of a dry run are neither authorized nor sent.
+	if c.DryRun != nil && c.DryRun.intercepts(wrq.Request) {
+		return c.DryRun.plan(ctx, c.MashEndpoint, wrq)
+	}
+
 	if c.Authorizer != nil {
 		if tkn, err := c.Authorizer.HeaderAuthorization(ctx); err != nil {
 			return nil, err
@@ -122,14 +156,26 @@ func (c *HttpTransport) httpExec(ctx context.Context, wrq *WrappedRequest) (*Wra
 		}
 	}
 
+	correlationId := ""
+	if ci := CallInfoFromContext(ctx); ci != nil {
+		ci.observe(wrq.Request.Method, wrq.Request.URL)
+		correlationId = ci.CorrelationId
+		wrq.Request.Header.Set(CorrelationIdHeader, correlationId)
+	}
+
+	// The exchange is abandoned once the context is done, e.g. the timeout of the call options has elapsed.
+	wrq.Request = wrq.Request.WithContext(ctx)
+
 	var wrs *WrappedResponse
+	wrq.Sent = time.Now()
 	resp, lastErr := c.HttpExecutor.Do(wrq.Request)
 	if lastErr == nil {
 		wrs = &WrappedResponse{
-			Request:    wrq,
-			Response:   resp,
-			StatusCode: resp.StatusCode,
-			Header:     resp.Header,
+			Request:       wrq,
+			Response:      resp,
+			StatusCode:    resp.StatusCode,
+			Header:        resp.Header,
+			CorrelationId: correlationId,
 		}
 	}
 
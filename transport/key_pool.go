@@ -0,0 +1,286 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const pooledKeyContextKey = ".pooled.key"
+
+// DefaultKeyRatePeriod Period after which the developer rate of a key resets where the KeyPool doesn't specify it
+const DefaultKeyRatePeriod = time.Hour * 24
+
+// AttrApiKey Span attribute carrying the API key of the KeyPool the call was dispatched to
+const AttrApiKey = "mashery.api_key"
+
+// PooledKeyUsage Usage of a single key of the KeyPool
+type PooledKeyUsage struct {
+	ApiKey string
+	QPS    float64
+	// Calls is the number of calls dispatched to the key
+	Calls uint64
+	// OverRate is the number of times the key was rejected with ERR_403_DEVELOPER_OVER_RATE
+	OverRate uint64
+	// Available is the number of calls that can be made with the key without waiting
+	Available float64
+	// SuspendedUntil is the time the key returns into rotation, where it has exceeded its developer rate.
+	SuspendedUntil time.Time
+	LastUsed       time.Time
+}
+
+// Suspended Whether the key is out of rotation
+func (u PooledKeyUsage) Suspended() bool {
+	return time.Now().Before(u.SuspendedUntil)
+}
+
+// KeyPoolExhaustedError Returned where all keys of the pool have exceeded their developer rate.
+type KeyPoolExhaustedError struct {
+	// ResetAt is the earliest time a key returns into rotation
+	ResetAt time.Time
+}
+
+func (e *KeyPoolExhaustedError) Error() string {
+	return fmt.Sprintf("all keys of the pool exceeded the developer rate; the first key resets at %s", e.ResetAt.Format(time.RFC3339))
+}
+
+type pooledKey struct {
+	apiKey     string
+	authorizer Authorizer
+	limiter    *TokenBucketRateLimiter
+
+	calls          uint64
+	overRate       uint64
+	suspendedUntil time.Time
+	lastUsed       time.Time
+}
+
+// KeyPool Dispatches the calls across several API keys, each with its own authorizer and rate limiter, to increase
+// the throughput. Each call is made with the key having the most available capacity. A key that is rejected with
+// ERR_403_DEVELOPER_OVER_RATE is taken out of rotation until its rate period resets, and the call is repeated with
+// another key.
+//
+// The pool is both the Authorizer and the pipeline middleware: the Middleware replaces the ThrottleFunc, and the
+// pool has to be the Authorizer of the transport so that the call is authorized with the key it was dispatched to.
+type KeyPool struct {
+	// RatePeriod is the period of the developer rate of the keys. A key that exceeded its rate is suspended until
+	// the start of the next period in UTC, unless Mashery indicates the time with the Retry-After header.
+	// Zero selects DefaultKeyRatePeriod.
+	RatePeriod time.Duration
+
+	mutex sync.Mutex
+	keys  []*pooledKey
+}
+
+func NewKeyPool() *KeyPool {
+	return &KeyPool{}
+}
+
+// AddKey Adds the key to the pool. The calls made with the key are authorized by the authorizer and paced to qps.
+func (kp *KeyPool) AddKey(apiKey string, qps float64, authorizer Authorizer) {
+	kp.mutex.Lock()
+	defer kp.mutex.Unlock()
+
+	kp.keys = append(kp.keys, &pooledKey{
+		apiKey:     apiKey,
+		authorizer: authorizer,
+		limiter:    NewTokenBucketRateLimiter(qps, 1),
+	})
+}
+
+// Usage Returns the usage of each key, in the order the keys were added
+func (kp *KeyPool) Usage() []PooledKeyUsage {
+	kp.mutex.Lock()
+	defer kp.mutex.Unlock()
+
+	rv := make([]PooledKeyUsage, len(kp.keys))
+	for i, k := range kp.keys {
+		rv[i] = PooledKeyUsage{
+			ApiKey:         k.apiKey,
+			QPS:            k.limiter.QPS(),
+			Calls:          k.calls,
+			OverRate:       k.overRate,
+			Available:      k.limiter.Available(),
+			SuspendedUntil: k.suspendedUntil,
+			LastUsed:       k.lastUsed,
+		}
+	}
+
+	return rv
+}
+
+// Middleware Returns the middleware function dispatching the calls to the keys. It replaces the ThrottleFunc in the
+// pipeline.
+func (kp *KeyPool) Middleware() ChainedMiddlewareFunc {
+	return func(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
+		for attempt := 1; ; attempt++ {
+			key, err := kp.acquire(ctx, c)
+			if err != nil {
+				return nil, err
+			}
+
+			wr, err := next(context.WithValue(ctx, pooledKeyContextKey, key), c)
+			if !kp.observe(key, wr) || attempt >= kp.size() {
+				return wr, err
+			}
+
+			// The body of the discarded response is drained, so that the connection can be reused.
+			if wr != nil {
+				_, _ = wr.Body()
+			}
+		}
+	}
+}
+
+// CoalescingIdentity Identifies the pool, rather than the key the call will be dispatched to, so that the calls
+// are coalesced before the key is selected.
+func (kp *KeyPool) CoalescingIdentity() string {
+	return fmt.Sprintf("key-pool:%p", kp)
+}
+
+func (kp *KeyPool) HeaderAuthorization(ctx context.Context) (map[string]string, error) {
+	if key, err := kp.keyOf(ctx); err != nil {
+		return nil, err
+	} else {
+		return key.authorizer.HeaderAuthorization(ctx)
+	}
+}
+
+func (kp *KeyPool) QueryStringAuthorization(ctx context.Context) (map[string]string, error) {
+	if key, err := kp.keyOf(ctx); err != nil {
+		return nil, err
+	} else {
+		return key.authorizer.QueryStringAuthorization(ctx)
+	}
+}
+
+// Close Closes the authorizers of all keys
+func (kp *KeyPool) Close() {
+	kp.mutex.Lock()
+	defer kp.mutex.Unlock()
+
+	for _, k := range kp.keys {
+		k.authorizer.Close()
+	}
+}
+
+func (kp *KeyPool) size() int {
+	kp.mutex.Lock()
+	defer kp.mutex.Unlock()
+
+	return len(kp.keys)
+}
+
+func (kp *KeyPool) ratePeriod() time.Duration {
+	if kp.RatePeriod > 0 {
+		return kp.RatePeriod
+	}
+	return DefaultKeyRatePeriod
+}
+
+// keyOf returns the key the call was dispatched to. Calls made outside the pipeline use the key with the most
+// available capacity.
+func (kp *KeyPool) keyOf(ctx context.Context) (*pooledKey, error) {
+	if v := ctx.Value(pooledKeyContextKey); v != nil {
+		if key, ok := v.(*pooledKey); ok {
+			return key, nil
+		}
+	}
+
+	kp.mutex.Lock()
+	defer kp.mutex.Unlock()
+
+	return kp.selectKeyLocked()
+}
+
+// selectKeyLocked returns the key in rotation with the most available capacity; of equally available keys, the one
+// used least recently is chosen. The pool is not modified.
+func (kp *KeyPool) selectKeyLocked() (*pooledKey, error) {
+	if len(kp.keys) == 0 {
+		return nil, errors.New("key pool contains no keys")
+	}
+
+	now := time.Now()
+	var rv *pooledKey
+	var rvAvailable float64
+	var resetAt time.Time
+
+	for _, k := range kp.keys {
+		if now.Before(k.suspendedUntil) {
+			if resetAt.IsZero() || k.suspendedUntil.Before(resetAt) {
+				resetAt = k.suspendedUntil
+			}
+			continue
+		}
+
+		available := k.limiter.Available()
+		if rv == nil || available > rvAvailable || (available == rvAvailable && k.lastUsed.Before(rv.lastUsed)) {
+			rv, rvAvailable = k, available
+		}
+	}
+
+	if rv == nil {
+		return nil, &KeyPoolExhaustedError{ResetAt: resetAt}
+	}
+
+	return rv, nil
+}
+
+// acquire selects the key for the call and waits for its call slot.
+func (kp *KeyPool) acquire(ctx context.Context, c *HttpTransport) (*pooledKey, error) {
+	ci := CallInfoFromContext(ctx)
+	_, span := c.startSpan(ctx, SpanThrottle, callAttributes(ci))
+
+	start := time.Now()
+
+	// The key is marked as used when it is selected, so that the concurrent calls are spread across the keys.
+	kp.mutex.Lock()
+	key, err := kp.selectKeyLocked()
+	if err == nil {
+		key.lastUsed = start
+	}
+	kp.mutex.Unlock()
+
+	if err == nil {
+		span.SetAttribute(AttrApiKey, key.apiKey)
+		if err = key.limiter.Wait(ctx); err == nil {
+			kp.mutex.Lock()
+			key.calls++
+			kp.mutex.Unlock()
+		}
+	}
+	if ci != nil {
+		ci.addThrottleWait(time.Since(start))
+	}
+	endSpan(span, nil, err)
+
+	return key, err
+}
+
+// observe takes the key out of rotation where the response indicates it exceeded the developer rate; returns
+// whether it did.
+func (kp *KeyPool) observe(key *pooledKey, wr *WrappedResponse) bool {
+	if wr == nil || wr.StatusCode != http.StatusForbidden || wr.Header.Get("X-Mashery-Error-Code") != "ERR_403_DEVELOPER_OVER_RATE" {
+		return false
+	}
+
+	now := time.Now()
+	period := kp.ratePeriod()
+	until := now.UTC().Truncate(period).Add(period)
+	if ra, ok := parseRetryAfter(wr.Header.Get("Retry-After"), now); ok {
+		until = now.Add(ra)
+	}
+
+	kp.mutex.Lock()
+	defer kp.mutex.Unlock()
+
+	key.overRate++
+	if until.After(key.suspendedUntil) {
+		key.suspendedUntil = until
+	}
+
+	return true
+}
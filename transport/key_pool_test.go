@@ -0,0 +1,147 @@
+package transport_test
+
+import (
+	"context"
+	"errors"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3simulator"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func overRateResponse(r *http.Request) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusForbidden,
+		Header: http.Header{
+			"X-Mashery-Error-Code": []string{"ERR_403_DEVELOPER_OVER_RATE"},
+		},
+		Body:    io.NopCloser(strings.NewReader("")),
+		Request: r,
+	}
+}
+
+func keyPoolClient(sim *v3simulator.Simulator, pool *transport.KeyPool) v3client.Client {
+	return v3simulator.NewClient(sim, func(p *v3client.Params) {
+		p.QPS = 0
+		p.KeyPool = pool
+	})
+}
+
+func TestKeyPoolSpreadsCallsAcrossKeys(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	tokens := map[string]int{}
+	mutex := sync.Mutex{}
+	sim.Interceptor = func(r *http.Request) *http.Response {
+		mutex.Lock()
+		tokens[r.Header.Get("Authorization")]++
+		mutex.Unlock()
+		return nil
+	}
+
+	pool := transport.NewKeyPool()
+	pool.AddKey("key-a", 5, transport.NewBearerAuthorizer("token-a"))
+	pool.AddKey("key-b", 5, transport.NewBearerAuthorizer("token-b"))
+	cl := keyPoolClient(sim, pool)
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		_, _, err := cl.GetService(context.Background(), masherytypes.ServiceIdentityFrom("svc"))
+		assert.Nil(t, err)
+	}
+
+	// 6 calls at the combined 10 QPS take about half a second, rather than a second with a single key.
+	assert.Less(t, time.Since(start), 800*time.Millisecond)
+	assert.Equal(t, 3, tokens["Bearer token-a"])
+	assert.Equal(t, 3, tokens["Bearer token-b"])
+
+	usage := pool.Usage()
+	assert.Equal(t, 2, len(usage))
+	assert.Equal(t, "key-a", usage[0].ApiKey)
+	assert.Equal(t, uint64(3), usage[0].Calls)
+	assert.Equal(t, uint64(3), usage[1].Calls)
+}
+
+func TestKeyPoolSuspendsKeyOverRate(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	sim.Interceptor = func(r *http.Request) *http.Response {
+		if r.Header.Get("Authorization") == "Bearer token-a" {
+			return overRateResponse(r)
+		}
+		return nil
+	}
+
+	pool := transport.NewKeyPool()
+	pool.AddKey("key-a", 100, transport.NewBearerAuthorizer("token-a"))
+	pool.AddKey("key-b", 100, transport.NewBearerAuthorizer("token-b"))
+	cl := keyPoolClient(sim, pool)
+
+	for i := 0; i < 3; i++ {
+		_, _, err := cl.GetService(context.Background(), masherytypes.ServiceIdentityFrom("svc"))
+		assert.Nil(t, err)
+	}
+
+	usage := pool.Usage()
+	assert.Equal(t, uint64(1), usage[0].OverRate)
+	assert.True(t, usage[0].Suspended())
+	assert.True(t, usage[0].SuspendedUntil.After(time.Now()))
+	assert.Equal(t, uint64(1), usage[0].Calls)
+	assert.Equal(t, uint64(3), usage[1].Calls)
+	assert.False(t, usage[1].Suspended())
+}
+
+func TestKeyPoolExhausted(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	sim.Interceptor = func(r *http.Request) *http.Response {
+		return overRateResponse(r)
+	}
+
+	pool := transport.NewKeyPool()
+	pool.AddKey("key-a", 100, transport.NewBearerAuthorizer("token-a"))
+	pool.AddKey("key-b", 100, transport.NewBearerAuthorizer("token-b"))
+	cl := keyPoolClient(sim, pool)
+
+	_, _, err := cl.GetService(context.Background(), masherytypes.ServiceIdentityFrom("svc"))
+	assert.NotNil(t, err)
+
+	_, _, err = cl.GetService(context.Background(), masherytypes.ServiceIdentityFrom("svc"))
+	var exhausted *transport.KeyPoolExhaustedError
+	assert.True(t, errors.As(err, &exhausted))
+	assert.True(t, exhausted.ResetAt.After(time.Now()))
+}
+
+func TestKeyPoolReplacesAuthorizer(t *testing.T) {
+	pool := transport.NewKeyPool()
+	pool.AddKey("key-a", 5, transport.NewBearerAuthorizer("token-a"))
+
+	p := v3client.Params{
+		Authorizer: transport.NewBearerAuthorizer("explicit"),
+		KeyPool:    pool,
+	}
+	p.FillDefaults()
+
+	assert.Equal(t, pool, p.Authorizer)
+}
+
+func TestKeyPoolCannotBeCombinedWithAdaptiveQPS(t *testing.T) {
+	pool := transport.NewKeyPool()
+	pool.AddKey("key-a", 5, transport.NewBearerAuthorizer("token-a"))
+
+	params := v3simulator.ClientParams(v3simulator.NewSimulator(), func(p *v3client.Params) {
+		p.KeyPool = pool
+		p.AdaptiveQPS = transport.NewAdaptiveQPS(transport.NewTokenBucketRateLimiter(2, 1), 1, 5)
+	})
+
+	_, err := v3client.NewValidatedHttpClient(params)
+	assert.NotNil(t, err)
+
+	// The client created without the validation fails its calls
+	_, err = v3client.NewHttpClient(params).ListServices(context.Background())
+	assert.NotNil(t, err)
+}
@@ -7,3 +7,26 @@ import (
 // ExchangeListener receive a notification of a raw exchange: what was actually sent to Mashery, and which response
 // was received
 type ExchangeListener func(ctx context.Context, req *WrappedRequest, res *WrappedResponse, err error)
+
+// CombineListeners Returns the listener notifying each of the supplied listeners in order. Nil listeners
+// are skipped; nil is returned if there are no listeners.
+func CombineListeners(listeners ...ExchangeListener) ExchangeListener {
+	var effective []ExchangeListener
+	for _, l := range listeners {
+		if l != nil {
+			effective = append(effective, l)
+		}
+	}
+
+	if len(effective) == 0 {
+		return nil
+	} else if len(effective) == 1 {
+		return effective[0]
+	}
+
+	return func(ctx context.Context, req *WrappedRequest, res *WrappedResponse, err error) {
+		for _, l := range effective {
+			l(ctx, req, res, err)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets Upper bounds, in seconds, of the latency histogram buckets
+var DefaultLatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// TokenRefreshListener receives the notification of the access token being obtained or refreshed.
+// The source describes how the token was obtained, e.g. "password" or "refresh_token".
+type TokenRefreshListener func(source string, err error)
+
+type callLabels struct {
+	appContext string
+	method     string
+	status     string
+	errorCode  string
+}
+
+type tokenLabels struct {
+	source  string
+	outcome string
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(buckets []float64, v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(buckets))
+	}
+
+	for i, upper := range buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// Metrics Collects the statistics of the calls made through the pipeline. Add Middleware to the end of the
+// pipeline so that the final outcome of each call is observed; the time spent in ThrottleFunc is reported
+// separately from the call latency.
+//
+// Metrics implements http.Handler serving the Prometheus text exposition format.
+type Metrics struct {
+	// Namespace prefixes the names of the metrics
+	Namespace string
+	Buckets   []float64
+	// EffectiveQPS, if set, is reported as the gauge of the rate the calls are paced to, e.g. AdaptiveQPS.QPS
+	EffectiveQPS func() float64
+
+	mutex        sync.Mutex
+	calls        map[callLabels]*histogram
+	throttleWait map[string]*histogram
+	tokenEvents  map[tokenLabels]uint64
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{
+		Namespace:    "mashery_client",
+		Buckets:      DefaultLatencyBuckets,
+		calls:        map[callLabels]*histogram{},
+		throttleWait: map[string]*histogram{},
+		tokenEvents:  map[tokenLabels]uint64{},
+	}
+}
+
+// Middleware Returns the middleware function recording the calls.
+func (m *Metrics) Middleware() ChainedMiddlewareFunc {
+	return func(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
+		start := time.Now()
+		wr, err := next(ctx, c)
+		elapsed := time.Since(start)
+
+		lbl := callLabels{method: "UNKNOWN", status: "error"}
+		var wait time.Duration
+		if ci := CallInfoFromContext(ctx); ci != nil {
+			lbl.appContext = ci.AppContext
+			if method := ci.Method(); len(method) > 0 {
+				lbl.method = method
+			}
+			wait = ci.ThrottleWait()
+		}
+		if wr != nil && wr.Response != nil {
+			lbl.status = strconv.Itoa(wr.StatusCode)
+			lbl.errorCode = wr.Header.Get("X-Mashery-Error-Code")
+		}
+
+		m.recordCall(lbl, elapsed-wait, wait)
+		return wr, err
+	}
+}
+
+func (m *Metrics) recordCall(lbl callLabels, latency, wait time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	h, ok := m.calls[lbl]
+	if !ok {
+		h = &histogram{}
+		m.calls[lbl] = h
+	}
+	h.observe(m.Buckets, latency.Seconds())
+
+	tw, ok := m.throttleWait[lbl.appContext]
+	if !ok {
+		tw = &histogram{}
+		m.throttleWait[lbl.appContext] = tw
+	}
+	tw.observe(m.Buckets, wait.Seconds())
+}
+
+// RecordTokenRefresh Counts the token refresh event. The method has the signature of TokenRefreshListener: set it as
+// the OnTokenRefresh of the OAuthHelperParams creating the token providers, sources and key pools.
+func (m *Metrics) RecordTokenRefresh(source string, err error) {
+	lbl := tokenLabels{source: source, outcome: "success"}
+	if err != nil {
+		lbl.outcome = "failure"
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.tokenEvents[lbl]++
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.WritePrometheus(w)
+}
+
+// WritePrometheus Writes the metrics in the Prometheus text exposition format
+func (m *Metrics) WritePrometheus(out io.Writer) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	w := bufio.NewWriter(out)
+
+	calls := m.sortedCallLabels()
+
+	name := m.Namespace + "_requests_total"
+	fmt.Fprintf(w, "# HELP %s Number of calls made to Mashery.\n# TYPE %s counter\n", name, name)
+	for _, lbl := range calls {
+		fmt.Fprintf(w, "%s{%s} %d\n", name, lbl.render(), m.calls[lbl].count)
+	}
+
+	name = m.Namespace + "_request_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Latency of the calls made to Mashery, excluding the throttle wait.\n# TYPE %s histogram\n", name, name)
+	for _, lbl := range calls {
+		m.writeHistogram(w, name, lbl.render(), m.calls[lbl])
+	}
+
+	name = m.Namespace + "_throttle_wait_seconds"
+	fmt.Fprintf(w, "# HELP %s Time the calls waited for a call slot.\n# TYPE %s histogram\n", name, name)
+	appContexts := make([]string, 0, len(m.throttleWait))
+	for k := range m.throttleWait {
+		appContexts = append(appContexts, k)
+	}
+	sort.Strings(appContexts)
+	for _, ac := range appContexts {
+		m.writeHistogram(w, name, renderLabels("app_context", ac), m.throttleWait[ac])
+	}
+
+	name = m.Namespace + "_token_refresh_total"
+	fmt.Fprintf(w, "# HELP %s Number of access token retrievals.\n# TYPE %s counter\n", name, name)
+	tokens := make([]tokenLabels, 0, len(m.tokenEvents))
+	for k := range m.tokenEvents {
+		tokens = append(tokens, k)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if tokens[i].source != tokens[j].source {
+			return tokens[i].source < tokens[j].source
+		}
+		return tokens[i].outcome < tokens[j].outcome
+	})
+	for _, lbl := range tokens {
+		fmt.Fprintf(w, "%s{%s} %d\n", name, renderLabels("source", lbl.source, "outcome", lbl.outcome), m.tokenEvents[lbl])
+	}
+
+	if m.EffectiveQPS != nil {
+		name = m.Namespace + "_effective_qps"
+		fmt.Fprintf(w, "# HELP %s Rate the calls are paced to.\n# TYPE %s gauge\n", name, name)
+		fmt.Fprintf(w, "%s %s\n", name, strconv.FormatFloat(m.EffectiveQPS(), 'g', -1, 64))
+	}
+
+	return w.Flush()
+}
+
+func (m *Metrics) writeHistogram(w io.Writer, name, labels string, h *histogram) {
+	for i, upper := range m.Buckets {
+		var cnt uint64
+		if h.counts != nil {
+			cnt = h.counts[i]
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, strconv.FormatFloat(upper, 'g', -1, 64), cnt)
+	}
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, h.count)
+}
+
+func (m *Metrics) sortedCallLabels() []callLabels {
+	rv := make([]callLabels, 0, len(m.calls))
+	for k := range m.calls {
+		rv = append(rv, k)
+	}
+	sort.Slice(rv, func(i, j int) bool {
+		return rv[i].render() < rv[j].render()
+	})
+	return rv
+}
+
+func (cl callLabels) render() string {
+	return renderLabels("app_context", cl.appContext, "method", cl.method, "status", cl.status, "error_code", cl.errorCode)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// renderLabels renders the name-value pairs as Prometheus labels
+func renderLabels(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=\"%s\"", pairs[i], labelValueEscaper.Replace(pairs[i+1])))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Snapshot Returns the current values of the metrics as a structure suitable for JSON encoding
+func (m *Metrics) Snapshot() map[string]interface{} {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	calls := []map[string]interface{}{}
+	for _, lbl := range m.sortedCallLabels() {
+		h := m.calls[lbl]
+		calls = append(calls, map[string]interface{}{
+			"appContext":     lbl.appContext,
+			"method":         lbl.method,
+			"status":         lbl.status,
+			"errorCode":      lbl.errorCode,
+			"count":          h.count,
+			"latencySeconds": h.sum,
+		})
+	}
+
+	throttle := map[string]interface{}{}
+	for ac, h := range m.throttleWait {
+		throttle[ac] = map[string]interface{}{
+			"count":       h.count,
+			"waitSeconds": h.sum,
+		}
+	}
+
+	tokens := map[string]uint64{}
+	for lbl, cnt := range m.tokenEvents {
+		tokens[lbl.source+"."+lbl.outcome] = cnt
+	}
+
+	rv := map[string]interface{}{
+		"calls":        calls,
+		"throttleWait": throttle,
+		"tokenRefresh": tokens,
+	}
+	if m.EffectiveQPS != nil {
+		rv["effectiveQPS"] = m.EffectiveQPS()
+	}
+
+	return rv
+}
+
+// PublishExpvar Publishes the metrics snapshot under the specified expvar name. Like expvar.Publish, it panics
+// if the name is already in use.
+func (m *Metrics) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return m.Snapshot()
+	}))
+}
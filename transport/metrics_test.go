@@ -0,0 +1,56 @@
+package transport_test
+
+import (
+	"context"
+	"errors"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3simulator"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetricsRecordCalls(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	failFirst(sim, 1, func(r *http.Request) *http.Response {
+		return failingResponse(r, 403, "ERR_403_DEVELOPER_OVER_RATE")
+	})
+
+	metrics := transport.NewMetrics()
+	cl := v3simulator.NewClient(sim, func(p *v3client.Params) {
+		p.Metrics = metrics
+	})
+
+	ctx := context.Background()
+	_, err := cl.ListServices(ctx)
+	assert.NotNil(t, err)
+	_, err = cl.ListServices(ctx)
+	assert.Nil(t, err)
+
+	metrics.RecordTokenRefresh("password", nil)
+	metrics.RecordTokenRefresh("refresh_token", errors.New("rejected"))
+
+	srv := httptest.NewServer(metrics)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	assert.Nil(t, err)
+	dat, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	text := string(dat)
+
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/plain")
+	assert.Contains(t, text, "# TYPE mashery_client_request_duration_seconds histogram")
+	assert.Contains(t, text, `mashery_client_requests_total{app_context="service",method="GET",status="200",error_code=""} 1`)
+	assert.Contains(t, text, `mashery_client_requests_total{app_context="service",method="GET",status="403",error_code="ERR_403_DEVELOPER_OVER_RATE"} 1`)
+	assert.Contains(t, text, `mashery_client_request_duration_seconds_count{app_context="service",method="GET",status="200",error_code=""} 1`)
+	assert.Contains(t, text, `mashery_client_throttle_wait_seconds_count{app_context="service"} 2`)
+	assert.Contains(t, text, `mashery_client_token_refresh_total{source="password",outcome="success"} 1`)
+	assert.Contains(t, text, `mashery_client_token_refresh_total{source="refresh_token",outcome="failure"} 1`)
+
+	snapshot := metrics.Snapshot()
+	assert.Equal(t, 2, len(snapshot["calls"].([]map[string]interface{})))
+}
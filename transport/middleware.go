@@ -14,12 +14,21 @@ const (
 )
 
 func ThrottleFunc(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case <-time.After(c.DelayBeforeCall()):
-		return next(ctx, c)
+	ci := CallInfoFromContext(ctx)
+	_, span := c.startSpan(ctx, SpanThrottle, callAttributes(ci))
+
+	start := time.Now()
+	err := c.WaitForCallSlot(ctx)
+	if ci != nil {
+		ci.addThrottleWait(time.Since(start))
+	}
+	endSpan(span, nil, err)
+
+	if err != nil {
+		return nil, err
 	}
+
+	return next(ctx, c)
 }
 
 func BackOffOnDeveloperOverQPSFunc(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
@@ -123,7 +132,15 @@ func EnsureBodyWasRead(ctx context.Context, c *HttpTransport, next MiddlewareFun
 func ExecuteFunction(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
 	if f := ctx.Value(LeafExecutor); f != nil {
 		if mf, ok := f.(MiddlewareFunc); ok {
-			return mf(ctx, c)
+			ci := CallInfoFromContext(ctx)
+			spanCtx, span := c.startSpan(ctx, SpanLeaf, callAttributes(ci))
+			wr, err := mf(spanCtx, c)
+			if ci != nil {
+				span.SetAttribute(AttrMethod, ci.Method())
+			}
+			endSpan(span, wr, err)
+
+			return wr, err
 		}
 	}
 
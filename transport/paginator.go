@@ -0,0 +1,280 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/errwrap"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefaultPaginationWorkers Number of pages fetched concurrently where PaginatorOptions don't specify it
+const DefaultPaginationWorkers = 4
+
+// PaginationProgress Progress of the pagination, reported after each fetched page
+type PaginationProgress struct {
+	PagesFetched int
+	TotalPages   int
+	ItemsFetched int
+	// TotalItems is the value of the X-Total-Count header of the first page
+	TotalItems int
+}
+
+// PaginatorOptions Options of the Paginator
+type PaginatorOptions struct {
+	// Workers limits the number of pages fetched concurrently
+	Workers int
+	// Progress, if set, is called after each page is fetched. Calls are serialized.
+	Progress func(PaginationProgress)
+}
+
+// Page A page of objects yielded by the Paginator. Pages after the first one are yielded in the order they are
+// received, which may differ from their Index.
+type Page[T any] struct {
+	Index int
+	Items []T
+	Err   error
+}
+
+// Paginator Fetches the pages of a Mashery collection with a bounded concurrency, yielding each page as soon
+// as it is received. The remaining fetches are cancelled on the first error or when the context is done.
+type Paginator[T any] struct {
+	Spec    ObjectListFetchSpec[T]
+	Options PaginatorOptions
+	// Accept, if set, is applied to each fetched item before the page is yielded.
+	Accept func(*T)
+
+	transport   *HttpTransport
+	exists      bool
+	mutex       sync.Mutex
+	reportMutex sync.Mutex
+	progress    PaginationProgress
+	err         error
+}
+
+func NewPaginator[T any](spec ObjectListFetchSpec[T], c *HttpTransport, opts PaginatorOptions) *Paginator[T] {
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultPaginationWorkers
+	}
+
+	return &Paginator[T]{
+		Spec:      spec,
+		Options:   opts,
+		transport: c,
+	}
+}
+
+// Exists Whether the collection exists, i.e. the first page did not return 404. The value is defined after the
+// channel returned by Pages is closed.
+func (p *Paginator[T]) Exists() bool {
+	return p.exists
+}
+
+// Err Returns the error that stopped the fetching. The value is defined after the channel returned by Pages is
+// closed.
+func (p *Paginator[T]) Err() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.err
+}
+
+// Pages Starts fetching the pages. Each page, or the error fetching it, is sent to the returned channel, which is
+// closed after all pages are fetched, or after the fetching is stopped due to an error. The caller must either
+// read the channel until it is closed, or cancel the context.
+func (p *Paginator[T]) Pages(ctx context.Context) <-chan Page[T] {
+	out := make(chan Page[T])
+	go p.run(ctx, out)
+	return out
+}
+
+// Collect Fetches all pages, returning the objects in the page order. Where the fetching was stopped, the objects
+// retrieved so far are returned together with the error that stopped it.
+func (p *Paginator[T]) Collect(ctx context.Context) ([]T, error) {
+	var pages []Page[T]
+
+	for page := range p.Pages(ctx) {
+		if page.Err == nil {
+			pages = append(pages, page)
+		}
+	}
+
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].Index < pages[j].Index
+	})
+
+	var rv []T
+	if len(pages) > 0 {
+		rv = []T{}
+	}
+	for _, page := range pages {
+		rv = append(rv, page.Items...)
+	}
+
+	return rv, p.Err()
+}
+
+func (p *Paginator[T]) run(ctx context.Context, out chan<- Page[T]) {
+	defer close(out)
+
+	// The pages are the parts of a single logical call, and share its correlation id.
+	ctx = WithCorrelationId(ctx, correlationIdFrom(ctx))
+
+	firstPage, firstPageResponse, err := performGenericObjectCRUDWithResponse[[]T](ctx, p.transport, http.MethodGet, p.Spec.AsObjectFetchSpec(), func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
+		return c.Fetch(ctx, p.Spec.DestResource())
+	})
+
+	if err != nil {
+		p.fail(ctx, out, Page[T]{Err: err})
+		return
+	}
+
+	p.exists = firstPageResponse.StatusCode != 404
+	if firstPage == nil || !p.exists {
+		return
+	}
+
+	pageSize := len(firstPage)
+	totalCount := 0
+	if hdr := firstPageResponse.Header.Get("X-Total-Count"); len(hdr) > 0 {
+		totalCount, _ = strconv.Atoi(hdr)
+	}
+
+	totalPages := 1
+	if pageSize > 0 && totalCount > pageSize {
+		totalPages = (totalCount + pageSize - 1) / pageSize
+	}
+	p.progress.TotalPages = totalPages
+	p.progress.TotalItems = totalCount
+
+	if !p.yield(ctx, out, Page[T]{Index: 0, Items: firstPage}) {
+		p.fail(ctx, out, Page[T]{Err: ctx.Err()})
+		return
+	} else if totalPages == 1 {
+		return
+	}
+
+	fetchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+
+	workers := p.Options.Workers
+	if workers > totalPages-1 {
+		workers = totalPages - 1
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				if page := p.fetchPage(fetchCtx, idx, pageSize); page.Err != nil {
+					cancel()
+					p.fail(ctx, out, page)
+				} else {
+					p.yield(ctx, out, page)
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for idx := 1; idx < totalPages; idx++ {
+		select {
+		case jobs <- idx:
+		case <-fetchCtx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		p.fail(ctx, out, Page[T]{Err: err})
+	}
+}
+
+// fail records the error that stops the fetching. Only the first error is recorded and yielded: the errors of
+// the fetches cancelled as a consequence are not reported.
+func (p *Paginator[T]) fail(ctx context.Context, out chan<- Page[T], page Page[T]) {
+	p.mutex.Lock()
+	first := p.err == nil
+	if first {
+		p.err = page.Err
+	}
+	p.mutex.Unlock()
+
+	if first {
+		select {
+		case out <- page:
+		case <-ctx.Done():
+		}
+	}
+}
+
+func (p *Paginator[T]) fetchPage(ctx context.Context, idx int, pageSize int) Page[T] {
+	offset := idx
+	if p.Spec.Pagination == PerItem {
+		offset *= pageSize
+	}
+
+	pageFetchSpec := p.Spec.ToBuilder()
+	pageFetchSpec.WithMergedQuery(url.Values{
+		"offset": {strconv.Itoa(offset)},
+	})
+
+	spec := pageFetchSpec.Build()
+	data, err := performGenericObjectCRUD(ctx, p.transport, http.MethodGet, spec.AsObjectFetchSpec(), func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
+		return c.Fetch(ctx, spec.DestResource())
+	})
+
+	if err != nil {
+		return Page[T]{Index: idx, Err: err}
+	} else if data == nil {
+		return Page[T]{Index: idx, Err: &errwrap.WrappedError{
+			Context: fmt.Sprintf("fetch all %s->read page %d", p.Spec.AppContext, idx),
+			Cause:   errors.New("nil response received"),
+		}}
+	}
+
+	return Page[T]{Index: idx, Items: data}
+}
+
+// yield sends the successfully fetched page to the consumer, reporting the progress. Returns false if the context
+// was done before the consumer received the page.
+func (p *Paginator[T]) yield(ctx context.Context, out chan<- Page[T], page Page[T]) bool {
+	if page.Err == nil {
+		if p.Accept != nil {
+			for i := range page.Items {
+				p.Accept(&page.Items[i])
+			}
+		}
+
+		// The callback is invoked outside the state mutex, so that it may query the paginator; reportMutex
+		// keeps the calls serialized and in the order of the progress.
+		p.reportMutex.Lock()
+		p.mutex.Lock()
+		p.progress.PagesFetched++
+		p.progress.ItemsFetched += len(page.Items)
+		progress := p.progress
+		p.mutex.Unlock()
+
+		if p.Options.Progress != nil {
+			p.Options.Progress(progress)
+		}
+		p.reportMutex.Unlock()
+	}
+
+	select {
+	case out <- page:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
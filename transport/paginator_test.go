@@ -0,0 +1,190 @@
+package transport_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3simulator"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func paginatingClient(sim *v3simulator.Simulator) v3client.PaginatingClient {
+	return v3simulator.NewClient(sim).(v3client.PaginatingClient)
+}
+
+func simulatorWithMembers(count int) *v3simulator.Simulator {
+	sim := v3simulator.NewSimulator()
+	sim.PageSize = 3
+	for i := 0; i < count; i++ {
+		_, _ = sim.Create("/members", masherytypes.Member{Username: fmt.Sprintf("user-%02d", i)})
+	}
+	return sim
+}
+
+func TestPaginatorStreamsPagesWithBoundedConcurrency(t *testing.T) {
+	sim := simulatorWithMembers(10)
+
+	var inFlight, maxInFlight int32
+	sim.Interceptor = func(r *http.Request) *http.Response {
+		cur := atomic.AddInt32(&inFlight, 1)
+		for {
+			prev := atomic.LoadInt32(&maxInFlight)
+			if cur <= prev || atomic.CompareAndSwapInt32(&maxInFlight, prev, cur) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return nil
+	}
+
+	var progress []transport.PaginationProgress
+	progressMutex := sync.Mutex{}
+
+	p, err := paginatingClient(sim).PaginateMembers(context.Background(), nil, transport.PaginatorOptions{
+		Workers: 2,
+		Progress: func(pp transport.PaginationProgress) {
+			progressMutex.Lock()
+			defer progressMutex.Unlock()
+			progress = append(progress, pp)
+		},
+	})
+	assert.Nil(t, err)
+
+	pages := 0
+	items := 0
+	for page := range p.Pages(context.Background()) {
+		assert.Nil(t, page.Err)
+		pages++
+		items += len(page.Items)
+	}
+
+	assert.Nil(t, p.Err())
+	assert.True(t, p.Exists())
+	assert.Equal(t, 4, pages)
+	assert.Equal(t, 10, items)
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxInFlight), int32(2))
+
+	assert.Equal(t, 4, len(progress))
+	last := progress[len(progress)-1]
+	assert.Equal(t, transport.PaginationProgress{PagesFetched: 4, TotalPages: 4, ItemsFetched: 10, TotalItems: 10}, last)
+}
+
+func TestPaginatorProgressMayQueryPaginator(t *testing.T) {
+	sim := simulatorWithMembers(10)
+
+	var p *transport.Paginator[masherytypes.Member]
+	reports := 0
+
+	p, err := paginatingClient(sim).PaginateMembers(context.Background(), nil, transport.PaginatorOptions{
+		Workers: 2,
+		Progress: func(pp transport.PaginationProgress) {
+			assert.Nil(t, p.Err())
+			reports++
+		},
+	})
+	assert.Nil(t, err)
+
+	members, err := p.Collect(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 10, len(members))
+	assert.Equal(t, 4, reports)
+}
+
+func TestPaginatorCollectsInPageOrder(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	sim.PageSize = 2
+	for i := 0; i < 7; i++ {
+		_, _ = sim.Create("/services", masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: fmt.Sprintf("svc-%d", i)}})
+	}
+
+	p, err := paginatingClient(sim).PaginateServices(context.Background(), nil, transport.PaginatorOptions{Workers: 3})
+	assert.Nil(t, err)
+
+	services, err := p.Collect(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 7, len(services))
+	for i, svc := range services {
+		assert.Equal(t, fmt.Sprintf("svc-%d", i), svc.Name)
+	}
+}
+
+func TestPaginatorPagesShareCorrelationId(t *testing.T) {
+	sim := simulatorWithMembers(10)
+
+	mutex := sync.Mutex{}
+	ids := map[string]int{}
+	sim.Interceptor = func(r *http.Request) *http.Response {
+		mutex.Lock()
+		defer mutex.Unlock()
+		ids[r.Header.Get(transport.CorrelationIdHeader)]++
+		return nil
+	}
+
+	members, err := paginatingClient(sim).(v3client.Client).ListMembers(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 10, len(members))
+
+	assert.Equal(t, 1, len(ids))
+	for id, pages := range ids {
+		assert.NotEmpty(t, id)
+		assert.Equal(t, 4, pages)
+	}
+}
+
+func TestPaginatorStopsOnFirstError(t *testing.T) {
+	sim := simulatorWithMembers(30)
+
+	var calls int32
+	sim.Interceptor = func(r *http.Request) *http.Response {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Query().Get("offset") == "2" {
+			return failingResponse(r, 500, "")
+		}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}
+
+	p, err := paginatingClient(sim).PaginateMembers(context.Background(), nil, transport.PaginatorOptions{Workers: 1})
+	assert.Nil(t, err)
+
+	members, err := p.Collect(context.Background())
+	assert.NotNil(t, err)
+	assert.Equal(t, 6, len(members))
+
+	// The error of the failed page is returned as is
+	_, joined := err.(interface{ Unwrap() []error })
+	assert.False(t, joined)
+	assert.Less(t, atomic.LoadInt32(&calls), int32(5))
+}
+
+func TestPaginatorHonoursContextCancellation(t *testing.T) {
+	sim := simulatorWithMembers(30)
+	sim.Interceptor = func(r *http.Request) *http.Response {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}
+
+	p, err := paginatingClient(sim).PaginateMembers(context.Background(), nil, transport.PaginatorOptions{Workers: 2})
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pages := 0
+	for range p.Pages(ctx) {
+		if pages++; pages == 2 {
+			cancel()
+		}
+	}
+
+	assert.Less(t, pages, 10)
+	assert.ErrorIs(t, p.Err(), context.Canceled)
+}
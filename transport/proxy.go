@@ -0,0 +1,244 @@
+package transport
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/errwrap"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type ProxyAuthScheme string
+
+const (
+	ProxyAuthBasic  = ProxyAuthScheme("Basic")
+	ProxyAuthBearer = ProxyAuthScheme("Bearer")
+)
+
+// ProxyAuthorization Credentials sent to the HTTP(S) proxy in the Proxy-Authorization header
+type ProxyAuthorization struct {
+	Scheme ProxyAuthScheme
+	// Username and Password of the Basic scheme
+	Username string
+	Password string
+	// Token is the token of the Bearer scheme. With the Basic scheme, it is the pre-encoded credentials that are
+	// used where the Username is not set. Other schemes send the Token verbatim.
+	Token string
+}
+
+// HeaderValue Returns the value of the Proxy-Authorization header
+func (pa *ProxyAuthorization) HeaderValue() (string, error) {
+	switch pa.Scheme {
+	case ProxyAuthBasic:
+		if len(pa.Username) > 0 {
+			return fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(pa.Username+":"+pa.Password))), nil
+		} else if len(pa.Token) > 0 {
+			return fmt.Sprintf("Basic %s", pa.Token), nil
+		}
+		return "", errors.New("basic proxy authorization requires a username")
+	case ProxyAuthBearer:
+		if len(pa.Token) == 0 {
+			return "", errors.New("bearer proxy authorization requires a token")
+		}
+		return fmt.Sprintf("Bearer %s", pa.Token), nil
+	default:
+		// Other schemes are passed verbatim
+		if len(pa.Scheme) == 0 || len(pa.Token) == 0 {
+			return "", errors.New(fmt.Sprintf("unsupported proxy authorization scheme '%s'", pa.Scheme))
+		}
+		return fmt.Sprintf("%s %s", pa.Scheme, pa.Token), nil
+	}
+}
+
+// ProxyConfig Configuration of the proxies the HTTP clients connect through. The proxy is chosen as follows:
+//   - no proxy is used if Direct is set, or if the host matches a Bypass pattern;
+//   - the SOCKS5 proxy, if SOCKS5Address is set;
+//   - the explicit HTTPS or HTTP proxy, if set for the scheme of the request;
+//   - otherwise, the proxy derived from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+type ProxyConfig struct {
+	// HTTPProxy is used for http requests, and for https requests where HTTPSProxy is not set.
+	HTTPProxy  *url.URL
+	HTTPSProxy *url.URL
+
+	// SOCKS5Address is the host:port of the SOCKS5 proxy. SOCKS5Username and SOCKS5Password are optional.
+	SOCKS5Address  string
+	SOCKS5Username string
+	SOCKS5Password string
+
+	// Direct disables the proxies, including those of the environment.
+	Direct bool
+	// Bypass lists the hosts that are connected to directly, in the NO_PROXY format: "*", a host name matching
+	// itself and its subdomains, a ".domain" or "*.domain" matching the subdomains only, an IP address or a CIDR
+	// range. Host names and addresses may include a port.
+	Bypass []string
+
+	// Authorization is sent to the HTTP(S) proxies
+	Authorization *ProxyAuthorization
+}
+
+// Validate Checks that the configuration is consistent
+func (pc *ProxyConfig) Validate() error {
+	for _, proxy := range []*url.URL{pc.HTTPProxy, pc.HTTPSProxy} {
+		if proxy != nil && proxy.Scheme != "http" && proxy.Scheme != "https" {
+			return errors.New(fmt.Sprintf("unsupported http proxy scheme '%s'", proxy.Scheme))
+		}
+	}
+
+	if len(pc.SOCKS5Address) > 0 {
+		if _, _, err := net.SplitHostPort(pc.SOCKS5Address); err != nil {
+			return &errwrap.WrappedError{Context: "invalid socks5 proxy address", Cause: err}
+		}
+	}
+
+	if pc.Authorization != nil {
+		if _, err := pc.Authorization.HeaderValue(); err != nil {
+			return err
+		}
+	}
+
+	for _, pattern := range pc.Bypass {
+		if strings.Contains(pattern, "/") {
+			if _, _, err := net.ParseCIDR(strings.TrimSpace(pattern)); err != nil {
+				return &errwrap.WrappedError{Context: "invalid proxy bypass pattern", Cause: err}
+			}
+		}
+	}
+
+	return nil
+}
+
+// ProxyFunc Returns the function selecting the proxy for the request, suitable for http.Transport. An invalid
+// configuration fails every request.
+func (pc *ProxyConfig) ProxyFunc() func(*http.Request) (*url.URL, error) {
+	if err := pc.Validate(); err != nil {
+		return func(_ *http.Request) (*url.URL, error) {
+			return nil, err
+		}
+	}
+
+	return pc.proxyFor
+}
+
+func (pc *ProxyConfig) proxyFor(req *http.Request) (*url.URL, error) {
+	if pc.Direct || pc.Bypassed(req.URL) {
+		return nil, nil
+	}
+
+	if len(pc.SOCKS5Address) > 0 {
+		rv := &url.URL{Scheme: "socks5", Host: pc.SOCKS5Address}
+		if len(pc.SOCKS5Username) > 0 {
+			rv.User = url.UserPassword(pc.SOCKS5Username, pc.SOCKS5Password)
+		}
+		return rv, nil
+	}
+
+	if req.URL.Scheme == "https" && pc.HTTPSProxy != nil {
+		return pc.HTTPSProxy, nil
+	} else if pc.HTTPProxy != nil {
+		return pc.HTTPProxy, nil
+	}
+
+	return http.ProxyFromEnvironment(req)
+}
+
+// Bypassed Whether the URL matches one of the Bypass patterns
+func (pc *ProxyConfig) Bypassed(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if len(port) == 0 {
+		if u.Scheme == "https" {
+			port = "443"
+		} else {
+			port = "80"
+		}
+	}
+
+	for _, pattern := range pc.Bypass {
+		if matchesBypassPattern(strings.ToLower(strings.TrimSpace(pattern)), host, port) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesBypassPattern(pattern string, host string, port string) bool {
+	if len(pattern) == 0 {
+		return false
+	} else if pattern == "*" {
+		return true
+	}
+
+	if _, cidr, err := net.ParseCIDR(pattern); err == nil {
+		ip := net.ParseIP(host)
+		return ip != nil && cidr.Contains(ip)
+	}
+
+	if h, p, err := net.SplitHostPort(pattern); err == nil {
+		if p != port {
+			return false
+		}
+		pattern = h
+	}
+	pattern = strings.Trim(pattern, "[]")
+
+	if ip := net.ParseIP(pattern); ip != nil {
+		return ip.Equal(net.ParseIP(host))
+	}
+
+	pattern = strings.TrimPrefix(pattern, "*")
+	if strings.HasPrefix(pattern, ".") {
+		return strings.HasSuffix(host, pattern)
+	}
+	return host == pattern || strings.HasSuffix(host, "."+pattern)
+}
+
+// authorizationHeader returns the Proxy-Authorization header to send to the HTTP(S) proxies, if configured.
+func (pc *ProxyConfig) authorizationHeader() http.Header {
+	if pc.Authorization == nil {
+		return nil
+	}
+
+	if v, err := pc.Authorization.HeaderValue(); err == nil {
+		rv := http.Header{}
+		rv.Set("Proxy-Authorization", v)
+		return rv
+	}
+	return nil
+}
+
+// roundTripper returns the round tripper that connects via the configured proxies. The Proxy-Authorization is
+// sent in CONNECT requests tunnelling https; it is added to the http requests forwarded by the proxy.
+func (pc *ProxyConfig) roundTripper(tr *http.Transport) http.RoundTripper {
+	tr.Proxy = pc.ProxyFunc()
+
+	if hdr := pc.authorizationHeader(); hdr != nil {
+		tr.ProxyConnectHeader = hdr
+		return &proxyAuthorizingRoundTripper{
+			Transport: tr,
+			header:    hdr,
+		}
+	}
+
+	return tr
+}
+
+type proxyAuthorizingRoundTripper struct {
+	*http.Transport
+	header http.Header
+}
+
+func (rt *proxyAuthorizingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" {
+		if proxy, err := rt.Proxy(req); err == nil && proxy != nil && strings.HasPrefix(proxy.Scheme, "http") {
+			req = req.Clone(req.Context())
+			for k, v := range rt.header {
+				req.Header[k] = v
+			}
+		}
+	}
+
+	return rt.Transport.RoundTrip(req)
+}
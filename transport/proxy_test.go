@@ -0,0 +1,139 @@
+package transport_test
+
+import (
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func mustParseURL(s string) *url.URL {
+	u, _ := url.Parse(s)
+	return u
+}
+
+func proxyFor(t *testing.T, pc transport.ProxyConfig, target string) *url.URL {
+	req, _ := http.NewRequest(http.MethodGet, target, nil)
+	rv, err := pc.ProxyFunc()(req)
+	assert.Nil(t, err)
+	return rv
+}
+
+func TestProxySelection(t *testing.T) {
+	pc := transport.ProxyConfig{
+		HTTPProxy:  mustParseURL("http://http-proxy:3128"),
+		HTTPSProxy: mustParseURL("http://https-proxy:3128"),
+		Bypass:     []string{"internal.corp", ".lan", "10.0.0.0/8", "localhost:8080"},
+	}
+
+	assert.Equal(t, "https-proxy:3128", proxyFor(t, pc, "https://api.mashery.com/v3/rest").Host)
+	assert.Equal(t, "http-proxy:3128", proxyFor(t, pc, "http://api.mashery.com/v3/rest").Host)
+
+	assert.Nil(t, proxyFor(t, pc, "https://internal.corp/"))
+	assert.Nil(t, proxyFor(t, pc, "https://vault.internal.corp/"))
+	assert.NotNil(t, proxyFor(t, pc, "https://notinternal.corp/"))
+	assert.Nil(t, proxyFor(t, pc, "https://host.lan/"))
+	assert.NotNil(t, proxyFor(t, pc, "https://lan/"))
+	assert.Nil(t, proxyFor(t, pc, "https://10.1.2.3/"))
+	assert.NotNil(t, proxyFor(t, pc, "https://11.1.2.3/"))
+	assert.Nil(t, proxyFor(t, pc, "http://localhost:8080/"))
+	assert.NotNil(t, proxyFor(t, pc, "http://localhost:8081/"))
+
+	pc.Direct = true
+	assert.Nil(t, proxyFor(t, pc, "https://api.mashery.com/v3/rest"))
+}
+
+func TestSOCKS5ProxyTakesPrecedence(t *testing.T) {
+	pc := transport.ProxyConfig{
+		HTTPProxy:      mustParseURL("http://http-proxy:3128"),
+		SOCKS5Address:  "socks:1080",
+		SOCKS5Username: "user",
+		SOCKS5Password: "pass",
+	}
+
+	proxy := proxyFor(t, pc, "https://api.mashery.com/v3/rest")
+	assert.Equal(t, "socks5", proxy.Scheme)
+	assert.Equal(t, "socks:1080", proxy.Host)
+	assert.Equal(t, "user", proxy.User.Username())
+	passwd, _ := proxy.User.Password()
+	assert.Equal(t, "pass", passwd)
+}
+
+func TestInvalidProxyConfigFailsRequests(t *testing.T) {
+	pc := transport.ProxyConfig{HTTPProxy: mustParseURL("ftp://proxy:21")}
+	assert.NotNil(t, pc.Validate())
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.mashery.com/", nil)
+	_, err := pc.ProxyFunc()(req)
+	assert.NotNil(t, err)
+
+	assert.NotNil(t, (&transport.ProxyConfig{SOCKS5Address: "no-port"}).Validate())
+	assert.NotNil(t, (&transport.ProxyConfig{Bypass: []string{"10.0.0.0/99"}}).Validate())
+	assert.NotNil(t, (&transport.ProxyConfig{Authorization: &transport.ProxyAuthorization{Scheme: transport.ProxyAuthBearer}}).Validate())
+}
+
+func TestProxyAuthorizationHeader(t *testing.T) {
+	basic := transport.ProxyAuthorization{Scheme: transport.ProxyAuthBasic, Username: "user", Password: "pass"}
+	v, err := basic.HeaderValue()
+	assert.Nil(t, err)
+	assert.Equal(t, "Basic dXNlcjpwYXNz", v)
+
+	bearer := transport.ProxyAuthorization{Scheme: transport.ProxyAuthBearer, Token: "tkn"}
+	v, err = bearer.HeaderValue()
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer tkn", v)
+}
+
+func TestCompatibilityProxyFields(t *testing.T) {
+	params := transport.HTTPClientParams{
+		ProxyServer:          mustParseURL("http://proxy:3128"),
+		ProxyAuthType:        "Basic",
+		ProxyAuthCredentials: "dXNlcjpwYXNz",
+	}
+
+	pc := params.ProxyConfig()
+	assert.Equal(t, "proxy:3128", pc.HTTPProxy.Host)
+	v, err := pc.Authorization.HeaderValue()
+	assert.Nil(t, err)
+	assert.Equal(t, "Basic dXNlcjpwYXNz", v)
+
+	params = transport.HTTPClientParams{ProxyServer: mustParseURL("socks5://u:p@socks:1080")}
+	pc = params.ProxyConfig()
+	assert.Nil(t, pc.HTTPProxy)
+	assert.Equal(t, "socks:1080", pc.SOCKS5Address)
+	assert.Equal(t, "u", pc.SOCKS5Username)
+	assert.Equal(t, "p", pc.SOCKS5Password)
+}
+
+func TestHttpProxyReceivesAuthorization(t *testing.T) {
+	var received *http.Request
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		_, _ = io.WriteString(w, "proxied")
+	}))
+	defer proxy.Close()
+
+	params := transport.HTTPClientParams{
+		Proxy: transport.ProxyConfig{
+			HTTPProxy:     mustParseURL(proxy.URL),
+			Authorization: &transport.ProxyAuthorization{Scheme: transport.ProxyAuthBearer, Token: "proxy-token"},
+		},
+	}
+
+	exec := params.CreateHttpExecutor()
+	defer exec.CloseIdleConnections()
+
+	req, _ := http.NewRequest(http.MethodGet, "http://api.example.com/v3/rest/services", nil)
+	resp, err := exec.Do(req)
+	assert.Nil(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, "proxied", string(body))
+	assert.Equal(t, "Bearer proxy-token", received.Header.Get("Proxy-Authorization"))
+	assert.Equal(t, "api.example.com", received.Host)
+	assert.Empty(t, req.Header.Get("Proxy-Authorization"))
+}
@@ -0,0 +1,232 @@
+package transport
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiter Controls the rate at which calls are sent to Mashery. A single limiter can be shared between
+// several transports (e.g. V3, V2 and wildcard clients) that use the same API key, so that the combined rate
+// stays within the key's allowance.
+type RateLimiter interface {
+	// Wait blocks until the call can be made or the context is done.
+	Wait(ctx context.Context) error
+}
+
+// TokenBucketRateLimiter Rate limiter that refills the bucket continuously, spreading the calls evenly within
+// a second rather than allocating them per wall-clock second. The calls that have to wait are queued in the lane
+// of their CallPriority; the interactive lane is served first. The calls fail immediately where the wait would
+// exceed the MaxThrottleWait of the call options or the deadline of the context.
+type TokenBucketRateLimiter struct {
+	mutex sync.Mutex
+
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	lanes [2][]*limiterWaiter
+	timer *time.Timer
+}
+
+type limiterWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// NewTokenBucketRateLimiter Creates a limiter allowing qps calls per second with the specified burst size.
+// A burst smaller than 1 is treated as 1.
+func NewTokenBucketRateLimiter(qps float64, burst int) *TokenBucketRateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &TokenBucketRateLimiter{
+		rate:   qps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// advance refills the bucket; must be called with the mutex held.
+func (tb *TokenBucketRateLimiter) advance(now time.Time) {
+	if elapsed := now.Sub(tb.last).Seconds(); elapsed > 0 {
+		tb.tokens = math.Min(tb.burst, tb.tokens+elapsed*tb.rate)
+		tb.last = now
+	}
+}
+
+// Reserve Takes a token from the bucket, returning the time the caller needs to wait before making the call.
+// The reservation is made ahead of the calls queued in Wait.
+func (tb *TokenBucketRateLimiter) Reserve() time.Duration {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+
+	if tb.rate <= 0 {
+		return 0
+	}
+
+	tb.advance(time.Now())
+	tb.tokens--
+
+	if tb.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+}
+
+func (tb *TokenBucketRateLimiter) Wait(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	lane := laneOf(CallOptionsFromContext(ctx).Priority)
+
+	tb.mutex.Lock()
+	if tb.rate <= 0 {
+		tb.mutex.Unlock()
+		return nil
+	}
+
+	tb.advance(time.Now())
+	ahead := tb.queuedAheadLocked(lane)
+	if ahead == 0 && tb.tokens >= 1 {
+		tb.tokens--
+		tb.mutex.Unlock()
+		return nil
+	}
+
+	// The call needs the tokens for the calls queued ahead of it, and one for itself.
+	wait := time.Duration((float64(ahead) + 1 - tb.tokens) / tb.rate * float64(time.Second))
+	if err := checkThrottleWait(ctx, wait); err != nil {
+		tb.mutex.Unlock()
+		return err
+	}
+
+	w := &limiterWaiter{ready: make(chan struct{})}
+	tb.lanes[lane] = append(tb.lanes[lane], w)
+	tb.scheduleLocked()
+	tb.mutex.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		tb.mutex.Lock()
+		defer tb.mutex.Unlock()
+
+		if w.granted {
+			// The slot was given at the same time the context was done; it is passed on to the next call.
+			tb.tokens = math.Min(tb.burst, tb.tokens+1)
+			tb.dispatchLocked()
+		} else {
+			tb.removeLocked(lane, w)
+		}
+		return ctx.Err()
+	}
+}
+
+func laneOf(p CallPriority) int {
+	if p <= PriorityInteractive {
+		return 0
+	}
+	return 1
+}
+
+// queuedAheadLocked returns the number of the queued calls that will be served before a call in the lane.
+func (tb *TokenBucketRateLimiter) queuedAheadLocked(lane int) int {
+	rv := 0
+	for i := 0; i <= lane; i++ {
+		rv += len(tb.lanes[i])
+	}
+	return rv
+}
+
+func (tb *TokenBucketRateLimiter) removeLocked(lane int, w *limiterWaiter) {
+	for i, v := range tb.lanes[lane] {
+		if v == w {
+			tb.lanes[lane] = append(tb.lanes[lane][:i], tb.lanes[lane][i+1:]...)
+			return
+		}
+	}
+}
+
+// dispatchLocked gives the available tokens to the queued calls, the interactive lane first.
+func (tb *TokenBucketRateLimiter) dispatchLocked() {
+	tb.advance(time.Now())
+
+	for lane := range tb.lanes {
+		for len(tb.lanes[lane]) > 0 && (tb.rate <= 0 || tb.tokens >= 1) {
+			w := tb.lanes[lane][0]
+			tb.lanes[lane] = tb.lanes[lane][1:]
+
+			if tb.rate > 0 {
+				tb.tokens--
+			}
+			w.granted = true
+			close(w.ready)
+		}
+	}
+
+	tb.scheduleLocked()
+}
+
+// scheduleLocked arranges the dispatch for the time the next token becomes available, if any calls are queued.
+func (tb *TokenBucketRateLimiter) scheduleLocked() {
+	if tb.timer != nil || tb.rate <= 0 || tb.queuedAheadLocked(len(tb.lanes)-1) == 0 {
+		return
+	}
+
+	delay := time.Duration(math.Ceil((1 - tb.tokens) / tb.rate * float64(time.Second)))
+
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		tb.mutex.Lock()
+		defer tb.mutex.Unlock()
+
+		// The timer may have been replaced while this dispatch was waiting for the mutex.
+		if tb.timer == timer {
+			tb.timer = nil
+		}
+		tb.dispatchLocked()
+	})
+	tb.timer = timer
+}
+
+// Available Returns the number of calls that can be made without waiting. The value is negative where
+// the calls are queued.
+func (tb *TokenBucketRateLimiter) Available() float64 {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+
+	tb.advance(time.Now())
+	return tb.tokens - float64(tb.queuedAheadLocked(len(tb.lanes)-1))
+}
+
+// QPS Returns the rate of this limiter
+func (tb *TokenBucketRateLimiter) QPS() float64 {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+
+	return tb.rate
+}
+
+// SetQPS Changes the rate of this limiter. Tokens accumulated so far are retained.
+func (tb *TokenBucketRateLimiter) SetQPS(qps float64) {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+
+	tb.advance(time.Now())
+	tb.rate = qps
+
+	// The pending dispatch was scheduled at the previous rate.
+	if tb.timer != nil {
+		tb.timer.Stop()
+		tb.timer = nil
+	}
+	tb.dispatchLocked()
+}
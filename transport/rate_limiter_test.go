@@ -0,0 +1,146 @@
+package transport_test
+
+import (
+	"context"
+	"errors"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketSpreadsCallsWithinSecond(t *testing.T) {
+	limiter := transport.NewTokenBucketRateLimiter(10, 1)
+
+	assert.Equal(t, time.Duration(0), limiter.Reserve())
+	assert.InDelta(t, float64(100*time.Millisecond), float64(limiter.Reserve()), float64(10*time.Millisecond))
+	assert.InDelta(t, float64(200*time.Millisecond), float64(limiter.Reserve()), float64(10*time.Millisecond))
+}
+
+func TestTokenBucketAllowsBurst(t *testing.T) {
+	limiter := transport.NewTokenBucketRateLimiter(2, 3)
+
+	assert.Equal(t, time.Duration(0), limiter.Reserve())
+	assert.Equal(t, time.Duration(0), limiter.Reserve())
+	assert.Equal(t, time.Duration(0), limiter.Reserve())
+	assert.InDelta(t, float64(500*time.Millisecond), float64(limiter.Reserve()), float64(10*time.Millisecond))
+}
+
+func TestTokenBucketWaitHonoursContext(t *testing.T) {
+	limiter := transport.NewTokenBucketRateLimiter(1, 1)
+	assert.Nil(t, limiter.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	assert.Equal(t, context.DeadlineExceeded, limiter.Wait(ctx))
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestSharedLimiterPacesTransports(t *testing.T) {
+	limiter := transport.NewTokenBucketRateLimiter(20, 1)
+
+	first := transport.HttpTransport{RateLimiter: limiter}
+	second := transport.HttpTransport{RateLimiter: limiter}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, first.WaitForCallSlot(context.Background()))
+		assert.Nil(t, second.WaitForCallSlot(context.Background()))
+	}
+
+	// 6 calls at 20 QPS: the last one is made no sooner than 250ms after the first.
+	assert.GreaterOrEqual(t, time.Since(start), 240*time.Millisecond)
+}
+
+func TestTokenBucketServesInteractiveLaneFirst(t *testing.T) {
+	limiter := transport.NewTokenBucketRateLimiter(20, 1)
+	assert.Nil(t, limiter.Wait(context.Background()))
+
+	order := make(chan string, 4)
+	done := make(chan struct{})
+
+	batchCtx := transport.WithPriority(context.Background(), transport.PriorityBatch)
+	for i := 0; i < 2; i++ {
+		go func() {
+			assert.Nil(t, limiter.Wait(batchCtx))
+			order <- "batch"
+			done <- struct{}{}
+		}()
+	}
+
+	// The interactive calls are queued after the batch calls, but are served before them.
+	time.Sleep(10 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		go func() {
+			assert.Nil(t, limiter.Wait(context.Background()))
+			order <- "interactive"
+			done <- struct{}{}
+		}()
+	}
+
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+	close(order)
+
+	var served []string
+	for s := range order {
+		served = append(served, s)
+	}
+	assert.Equal(t, []string{"interactive", "interactive", "batch", "batch"}, served)
+}
+
+func TestTokenBucketFailsFastBeyondMaxThrottleWait(t *testing.T) {
+	limiter := transport.NewTokenBucketRateLimiter(1, 1)
+	assert.Nil(t, limiter.Wait(context.Background()))
+
+	ctx := transport.WithMaxThrottleWait(context.Background(), 100*time.Millisecond)
+
+	start := time.Now()
+	err := limiter.Wait(ctx)
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+
+	var waitErr *transport.ThrottleWaitExceededError
+	assert.True(t, errors.As(err, &waitErr))
+	assert.Equal(t, 100*time.Millisecond, waitErr.MaxWait)
+	assert.Greater(t, waitErr.Wait, 900*time.Millisecond)
+
+	// The rejected call did not take the slot: the next one is available within the second.
+	ctx = transport.WithMaxThrottleWait(context.Background(), 1100*time.Millisecond)
+	assert.Nil(t, limiter.Wait(ctx))
+}
+
+func TestTokenBucketFailsFastBeyondDeadline(t *testing.T) {
+	limiter := transport.NewTokenBucketRateLimiter(1, 1)
+	assert.Nil(t, limiter.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	assert.Equal(t, context.DeadlineExceeded, limiter.Wait(ctx))
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestTokenBucketSetQPSReschedulesQueuedCalls(t *testing.T) {
+	limiter := transport.NewTokenBucketRateLimiter(0.2, 1)
+	assert.Nil(t, limiter.Wait(context.Background()))
+
+	done := make(chan error)
+	go func() {
+		done <- limiter.Wait(context.Background())
+	}()
+
+	// At 0.2 QPS the queued call would be dispatched in 5 seconds.
+	time.Sleep(20 * time.Millisecond)
+	limiter.SetQPS(100)
+
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		assert.Fail(t, "the queued call was not dispatched at the new rate")
+	}
+}
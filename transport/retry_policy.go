@@ -0,0 +1,220 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryNonIdempotent Context key allowing the retry policy to repeat non-idempotent (POST) calls.
+const RetryNonIdempotent = ".retry.non.idempotent"
+
+// RetryPolicy Configurable retry strategy with exponential back-off. The policy is applied to the pipeline
+// with Middleware, replacing BackOffOnDeveloperOverQPSFunc and RetryOn400Func.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry; each next delay is Multiplier times longer,
+	// up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter is the fraction (0..1) by which each delay is randomly shortened or lengthened.
+	Jitter float64
+	// MaxElapsedTime limits the total time spent on the call, including the delays. Zero means no limit.
+	MaxElapsedTime time.Duration
+
+	// RetryOnStatus lists the HTTP status codes that are retried regardless of the error code.
+	RetryOnStatus map[int]bool
+	// RetryOnErrorCode lists the values of the X-Mashery-Error-Code header that are retried.
+	RetryOnErrorCode map[string]bool
+	// RetryNetworkErrors allows retrying the calls that failed without receiving a response.
+	RetryNetworkErrors bool
+	// RespectRetryAfter uses the delay indicated by the Retry-After header where it is longer than the back-off.
+	RespectRetryAfter bool
+}
+
+// DefaultRetryPolicy Retries developer-over-QPS, gateway and network errors for up to two minutes.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Second * 30,
+		Multiplier:     2,
+		Jitter:         0.2,
+		MaxElapsedTime: time.Minute * 2,
+		RetryOnStatus: map[int]bool{
+			http.StatusBadGateway:         true,
+			http.StatusServiceUnavailable: true,
+			http.StatusGatewayTimeout:     true,
+		},
+		RetryOnErrorCode: map[string]bool{
+			"ERR_403_DEVELOPER_OVER_QPS": true,
+		},
+		RetryNetworkErrors: true,
+		RespectRetryAfter:  true,
+	}
+}
+
+// WithNonIdempotentRetries Returns the context where the retry policy is allowed to repeat POST calls.
+// Use only where repeating the call cannot create duplicate objects.
+func WithNonIdempotentRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, RetryNonIdempotent, true)
+}
+
+// Middleware Returns the middleware function applying this policy.
+func (rp *RetryPolicy) Middleware() ChainedMiddlewareFunc {
+	return func(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
+		return rp.execute(ctx, c, next)
+	}
+}
+
+func (rp *RetryPolicy) execute(ctx context.Context, c *HttpTransport, next MiddlewareFunc) (*WrappedResponse, error) {
+	start := time.Now()
+	ci := CallInfoFromContext(ctx)
+
+	for attempt := 1; ; attempt++ {
+		attemptCtx, span := c.startSpan(ctx, SpanAttempt, attemptAttributes(ci, attempt))
+		wr, err := next(attemptCtx, c)
+		endSpan(span, wr, err)
+
+		if attempt >= rp.MaxAttempts || ctx.Err() != nil || !rp.shouldRetry(ctx, wr, err) {
+			return wr, err
+		}
+
+		delay := rp.Backoff(attempt)
+		if rp.RespectRetryAfter && wr != nil {
+			if ra, ok := parseRetryAfter(wr.Header.Get("Retry-After"), time.Now()); ok && ra > delay {
+				delay = ra
+			}
+		}
+
+		if rp.MaxElapsedTime > 0 && time.Since(start)+delay > rp.MaxElapsedTime {
+			return wr, err
+		}
+
+		// The body of the discarded response is drained, so that the connection can be reused.
+		if wr != nil {
+			_, _ = wr.Body()
+		}
+
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			return wr, sleepErr
+		}
+	}
+}
+
+func (rp *RetryPolicy) shouldRetry(ctx context.Context, wr *WrappedResponse, err error) bool {
+	if !rp.retryableMethod(ctx, wr) {
+		return false
+	}
+
+	if wr == nil || wr.Response == nil {
+		return rp.RetryNetworkErrors && exchangeError(err)
+	} else if err != nil {
+		return false
+	}
+
+	if rp.RetryOnStatus[wr.StatusCode] {
+		return true
+	} else if wr.StatusCode == http.StatusBadRequest && boolKey(ctx, RetryOn400) {
+		return true
+	} else if code := wr.Header.Get("X-Mashery-Error-Code"); len(code) > 0 {
+		return rp.RetryOnErrorCode[code]
+	}
+
+	return false
+}
+
+// exchangeError Whether the call failed while exchanging the data with Mashery, as opposed to being rejected
+// by the pipeline itself (throttle wait, exhausted key pool, open circuit) or abandoned by the caller.
+func exchangeError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var throttleErr *ThrottleWaitExceededError
+	var poolErr *KeyPoolExhaustedError
+	var circuitErr *CircuitOpenError
+
+	return !errors.As(err, &throttleErr) && !errors.As(err, &poolErr) && !errors.As(err, &circuitErr)
+}
+
+func (rp *RetryPolicy) retryableMethod(ctx context.Context, wr *WrappedResponse) bool {
+	method := ""
+	if ci := CallInfoFromContext(ctx); ci != nil {
+		method = ci.Method()
+	} else if wr != nil && wr.Request != nil && wr.Request.Request != nil {
+		method = wr.Request.Request.Method
+	}
+
+	if strings.ToUpper(method) == http.MethodPost {
+		return boolKey(ctx, RetryNonIdempotent)
+	}
+
+	return true
+}
+
+// Backoff Returns the delay before the retry that follows the specified attempt, with the jitter applied.
+func (rp *RetryPolicy) Backoff(attempt int) time.Duration {
+	mult := rp.Multiplier
+	if mult < 1 {
+		mult = 1
+	}
+
+	d := float64(rp.InitialBackoff) * math.Pow(mult, float64(attempt-1))
+	if rp.MaxBackoff > 0 && d > float64(rp.MaxBackoff) {
+		d = float64(rp.MaxBackoff)
+	}
+
+	if rp.Jitter > 0 {
+		d += d * rp.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	return time.Duration(d)
+}
+
+// parseRetryAfter parses the Retry-After header, which can be either a number of seconds or an HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if len(v) == 0 {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// sleepContext sleeps for the specified duration unless the context is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
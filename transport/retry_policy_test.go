@@ -0,0 +1,202 @@
+package transport_test
+
+import (
+	"context"
+	"errors"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3simulator"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func fastRetryPolicy() *transport.RetryPolicy {
+	rv := transport.DefaultRetryPolicy()
+	rv.InitialBackoff = time.Millisecond
+	rv.MaxBackoff = time.Millisecond * 5
+	rv.Jitter = 0
+	return rv
+}
+
+func retryingClient(sim *v3simulator.Simulator, policy *transport.RetryPolicy) v3client.Client {
+	return v3simulator.NewClient(sim, func(p *v3client.Params) {
+		p.RetryPolicy = policy
+	})
+}
+
+func failingResponse(r *http.Request, status int, errorCode string) *http.Response {
+	hdr := http.Header{}
+	if len(errorCode) > 0 {
+		hdr.Set("X-Mashery-Error-Code", errorCode)
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     hdr,
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    r,
+	}
+}
+
+// failFirst makes the simulator fail the first n requests with the supplied function
+func failFirst(sim *v3simulator.Simulator, n int32, f func(r *http.Request) *http.Response) *int32 {
+	var calls int32
+	sim.Interceptor = func(r *http.Request) *http.Response {
+		if atomic.AddInt32(&calls, 1) <= n {
+			return f(r)
+		}
+		return nil
+	}
+	return &calls
+}
+
+func TestRetryPolicyRetriesGatewayErrors(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	svcId, _ := sim.Create("/services", masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	calls := failFirst(sim, 2, func(r *http.Request) *http.Response {
+		return failingResponse(r, 503, "")
+	})
+
+	_, exists, err := retryingClient(sim, fastRetryPolicy()).GetService(context.Background(), masherytypes.ServiceIdentityFrom(svcId))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+}
+
+func TestRetryPolicyRetriesErrorCodes(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	calls := failFirst(sim, 1, func(r *http.Request) *http.Response {
+		return failingResponse(r, 403, "ERR_403_DEVELOPER_OVER_QPS")
+	})
+
+	_, err := retryingClient(sim, fastRetryPolicy()).ListServices(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestRetryPolicyDoesNotRetryPostUnlessAllowed(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	calls := failFirst(sim, 1, func(r *http.Request) *http.Response {
+		return failingResponse(r, 502, "")
+	})
+	cl := retryingClient(sim, fastRetryPolicy())
+
+	_, err := cl.CreateService(context.Background(), masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+
+	calls = failFirst(sim, 1, func(r *http.Request) *http.Response {
+		return failingResponse(r, 502, "")
+	})
+	ctx := transport.WithNonIdempotentRetries(context.Background())
+	_, err = cl.CreateService(ctx, masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+type failingExecutor struct {
+	calls int32
+}
+
+func (fe *failingExecutor) Do(_ *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&fe.calls, 1)
+	return nil, errors.New("connection reset")
+}
+
+func (fe *failingExecutor) CloseIdleConnections() {}
+
+func TestRetryPolicyRetriesNetworkErrorsUpToMaxAttempts(t *testing.T) {
+	exec := &failingExecutor{}
+	policy := fastRetryPolicy()
+	policy.MaxAttempts = 4
+
+	cl := v3client.NewHttpClient(v3client.Params{
+		MashEndpoint: "http://localhost/v3/rest",
+		QPS:          1000,
+		RetryPolicy:  policy,
+		HTTPClientParams: transport.HTTPClientParams{
+			ExplicitHttpExecutor: exec,
+		},
+	})
+
+	_, err := cl.ListServices(context.Background())
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&exec.calls))
+}
+
+func TestRetryPolicyHonoursRetryAfterAndContext(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	failFirst(sim, 10, func(r *http.Request) *http.Response {
+		rv := failingResponse(r, 503, "")
+		rv.Header.Set("Retry-After", "30")
+		return rv
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := retryingClient(sim, fastRetryPolicy()).ListServices(ctx)
+	assert.NotNil(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRetryPolicyStopsAtMaxElapsedTime(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	calls := failFirst(sim, 10, func(r *http.Request) *http.Response {
+		return failingResponse(r, 504, "")
+	})
+
+	policy := fastRetryPolicy()
+	policy.InitialBackoff = time.Millisecond * 40
+	policy.MaxBackoff = time.Millisecond * 40
+	policy.MaxElapsedTime = time.Millisecond * 100
+
+	_, err := retryingClient(sim, policy).ListServices(context.Background())
+	assert.NotNil(t, err)
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+}
+
+func TestRetryPolicyBackoffGrowsExponentially(t *testing.T) {
+	policy := transport.RetryPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Second * 5,
+		Multiplier:     2,
+	}
+
+	assert.Equal(t, time.Second, policy.Backoff(1))
+	assert.Equal(t, time.Second*2, policy.Backoff(2))
+	assert.Equal(t, time.Second*4, policy.Backoff(3))
+	assert.Equal(t, time.Second*5, policy.Backoff(4))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 20; i++ {
+		d := policy.Backoff(2)
+		assert.True(t, d >= time.Second && d <= time.Second*3)
+	}
+}
+
+func TestRetryPolicyDoesNotRetryThrottleWaitExceeded(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	cl := v3simulator.NewClient(sim, func(p *v3client.Params) {
+		p.QPS = 1
+		p.RetryPolicy = transport.DefaultRetryPolicy()
+	})
+
+	ctx := transport.WithMaxThrottleWait(context.Background(), 10*time.Millisecond)
+
+	_, err := cl.ListServices(ctx)
+	assert.Nil(t, err)
+
+	start := time.Now()
+	_, err = cl.ListServices(ctx)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	var waitErr *transport.ThrottleWaitExceededError
+	assert.True(t, errors.As(err, &waitErr))
+}
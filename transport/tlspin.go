@@ -5,17 +5,28 @@ import (
 	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"log/slog"
 	"math/big"
 	"strings"
 	"time"
 )
 
+// TLSCertChainPin A pin that a certificate of the chain has to match. All the specified attributes have to match
+// the same certificate.
 type TLSCertChainPin struct {
 	CommonName   string
 	SerialNumber []byte
-	Fingerprint  []byte
+	// Fingerprint is the SHA-256 digest of the whole certificate
+	Fingerprint []byte
+	// SPKISHA256 is the SHA-256 digest of the subject public key info of the certificate. Unlike the other
+	// attributes, it remains the same when the certificate is renewed with the same key.
+	SPKISHA256 []byte
+	// NotAfter, if set, is the time after which the pin is no longer checked.
+	NotAfter time.Time
 }
 
 func (pin *TLSCertChainPin) SerialNumberFromHex(str string) error {
@@ -26,8 +37,60 @@ func (pin *TLSCertChainPin) FingerprintFrom(str string) error {
 	return decodeUserHex(str, &pin.Fingerprint)
 }
 
+// SPKISHA256From Sets the SPKI pin from the hex or base64 encoding of the SHA-256 digest
+func (pin *TLSCertChainPin) SPKISHA256From(str string) error {
+	var decoded []byte
+	if err := decodeUserHex(str, &decoded); err != nil {
+		if decoded, err = base64.StdEncoding.DecodeString(str); err != nil {
+			return errors.New(fmt.Sprintf("spki pin '%s' is neither hex nor base64 encoded", str))
+		}
+	}
+
+	if len(decoded) != sha256.Size {
+		return errors.New(fmt.Sprintf("spki pin '%s' is not a sha-256 digest", str))
+	}
+
+	pin.SPKISHA256 = decoded
+	return nil
+}
+
 func (pin *TLSCertChainPin) IsEmpty() bool {
-	return len(pin.CommonName) == 0 && len(pin.SerialNumber) == 0 && len(pin.Fingerprint) == 0
+	return len(pin.CommonName) == 0 && len(pin.SerialNumber) == 0 && len(pin.Fingerprint) == 0 && len(pin.SPKISHA256) == 0
+}
+
+// Expired Whether the pin is no longer checked at the specified time
+func (pin *TLSCertChainPin) Expired(at time.Time) bool {
+	return !pin.NotAfter.IsZero() && at.After(pin.NotAfter)
+}
+
+// Matches Whether the certificate matches all attributes of this pin
+func (pin *TLSCertChainPin) Matches(cert *x509.Certificate) bool {
+	// Check the common name pin
+	if len(pin.CommonName) > 0 && pin.CommonName != cert.Subject.CommonName {
+		return false
+	}
+
+	if pinnedSerial := pin.PinnedSerial(); pinnedSerial != nil {
+		if cert.SerialNumber == nil || cert.SerialNumber.Cmp(pinnedSerial) != 0 {
+			return false
+		}
+	}
+
+	if len(pin.Fingerprint) > 0 {
+		sig := sha256.Sum256(cert.Raw)
+		if !bytes.Equal(pin.Fingerprint, sig[:]) {
+			return false
+		}
+	}
+
+	if len(pin.SPKISHA256) > 0 {
+		sig := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		if !bytes.Equal(pin.SPKISHA256, sig[:]) {
+			return false
+		}
+	}
+
+	return true
 }
 
 func decodeUserHex(input string, output *[]byte) error {
@@ -51,66 +114,146 @@ func (pin TLSCertChainPin) PinnedSerial() *big.Int {
 	}
 }
 
+// TLSPinSet The pins that all have to occur in the chain presented by the server
+type TLSPinSet struct {
+	Name string
+	Pins []TLSCertChainPin
+	// NotAfter, if set, is the time after which the set is no longer checked.
+	NotAfter time.Time
+}
+
+// Active Whether the set is checked at the specified time: it has not expired and has pins that have not expired.
+func (ps *TLSPinSet) Active(at time.Time) bool {
+	if !ps.NotAfter.IsZero() && at.After(ps.NotAfter) {
+		return false
+	}
+
+	for _, pin := range ps.Pins {
+		if !pin.Expired(at) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns true if every unexpired pin of the set occurs in the chain.
+func (ps *TLSPinSet) matches(chain []*x509.Certificate, at time.Time) bool {
+pins:
+	for _, pin := range ps.Pins {
+		if pin.Expired(at) {
+			continue
+		}
+
+		for _, cert := range chain {
+			if pin.Matches(cert) {
+				continue pins
+			}
+		}
+
+		// We have a pin that does not occur in this chain.
+		return false
+	}
+
+	return true
+}
+
+// TLSPinner Verifies that the chain presented by the server contains the pinned certificates. The chain is
+// accepted if it matches either the primary pins, or any of the backup pin sets. The expired pins and sets are not
+// checked; where no pins remain active, the chain is rejected unless AllowExpiredPins is set.
 type TLSPinner struct {
+	// TLSCertChainPins are the primary pins
 	TLSCertChainPins []TLSCertChainPin
+	// BackupPinSets are accepted in place of the primary pins, e.g. the pins of the certificates Mashery is
+	// expected to rotate to.
+	BackupPinSets []TLSPinSet
+
+	// ReportOnly makes the pinner log the mismatches rather than fail the connection.
+	ReportOnly bool
+	// AllowExpiredPins accepts the chain on the standard verification alone where all pins and sets have expired.
+	// Each such handshake is logged as a warning.
+	AllowExpiredPins bool
+	// Logger receives the mismatches reported in ReportOnly mode and the handshakes accepted with the expired
+	// pins. Defaults to slog.Default().
+	Logger *slog.Logger
 }
 
+// errPinsExpired Returned where all pins and sets of the pinner have expired
+var errPinsExpired = errors.New("all tls pins have expired")
+
 func (pinner *TLSPinner) Add(pin TLSCertChainPin) *TLSPinner {
 	pinner.TLSCertChainPins = append(pinner.TLSCertChainPins, pin)
 	return pinner
 }
 
-func (tlp *TLSPinner) verifyPeerCertificateImpl(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
-chains:
-	for _, v := range verifiedChains {
+// AddBackup Adds the backup pin set
+func (pinner *TLSPinner) AddBackup(set TLSPinSet) *TLSPinner {
+	pinner.BackupPinSets = append(pinner.BackupPinSets, set)
+	return pinner
+}
 
-	pins:
-		for _, chainPin := range tlp.TLSCertChainPins {
-
-			pinnedSerial := chainPin.PinnedSerial()
-			checkTime := time.Now()
-
-			for _, cert := range v {
-				// We can't accept expired certificates in the chain path
-				if checkTime.Before(cert.NotBefore) || checkTime.After(cert.NotAfter) {
-					continue chains
-				}
-
-				// Check the common name pin
-				if len(chainPin.CommonName) > 0 && chainPin.CommonName != cert.Subject.CommonName {
-					continue
-				}
-
-				if pinnedSerial != nil {
-					if cert.SerialNumber == nil {
-						continue
-					} else if cert.SerialNumber.Cmp(pinnedSerial) != 0 {
-						continue
-					}
-				}
-
-				if len(chainPin.Fingerprint) > 0 {
-					hash := sha256.New()
-					hash.Write(cert.Raw)
-
-					sig := hash.Sum(nil)
-					if bytes.Compare(chainPin.Fingerprint, sig) != 0 {
-						continue
-					}
-				}
-
-				// Found a pinned certificate this chain. Let's look for
-				// next PIN
-				continue pins
-			}
+// activePinSets returns the sets checked at the specified time.
+func (tlp *TLSPinner) activePinSets(at time.Time) []TLSPinSet {
+	var rv []TLSPinSet
 
-			// We have a pin that does not occur in this chain.
-			// Let's continue searching for next chain
-			continue chains
+	primary := TLSPinSet{Name: "primary", Pins: tlp.TLSCertChainPins}
+	if primary.Active(at) {
+		rv = append(rv, primary)
+	}
+	for _, set := range tlp.BackupPinSets {
+		if set.Active(at) {
+			rv = append(rv, set)
 		}
+	}
 
-		// This chain contains all required pins.
+	return rv
+}
+
+// Verify Checks that one of the verified chains matches the pins. In the ReportOnly mode, the mismatch is logged and
+// nil is returned.
+func (tlp *TLSPinner) Verify(serverName string, verifiedChains [][]*x509.Certificate) error {
+	err := tlp.verifyChains(verifiedChains, time.Now())
+	if err == errPinsExpired && tlp.AllowExpiredPins {
+		tlp.logger().Warn("tls pins have expired, accepting the chain on the standard verification", "server", serverName)
 		return nil
+	} else if err != nil && tlp.ReportOnly {
+		tlp.logger().Warn("tls pin mismatch", "server", serverName, "error", err.Error())
+		return nil
+	}
+
+	if err != nil && len(serverName) > 0 {
+		return errors.New(fmt.Sprintf("%s for %s", err.Error(), serverName))
+	}
+	return err
+}
+
+func (tlp *TLSPinner) logger() *slog.Logger {
+	if tlp.Logger != nil {
+		return tlp.Logger
+	}
+	return slog.Default()
+}
+
+func (tlp *TLSPinner) verifyChains(verifiedChains [][]*x509.Certificate, checkTime time.Time) error {
+	sets := tlp.activePinSets(checkTime)
+	if len(sets) == 0 {
+		return errPinsExpired
+	}
+
+chains:
+	for _, v := range verifiedChains {
+		for _, cert := range v {
+			// We can't accept expired certificates in the chain path
+			if checkTime.Before(cert.NotBefore) || checkTime.After(cert.NotAfter) {
+				continue chains
+			}
+		}
+
+		for _, set := range sets {
+			if set.matches(v, checkTime) {
+				// This chain contains all required pins.
+				return nil
+			}
+		}
 	}
 
 	return errors.New("no matching chains")
@@ -118,7 +261,9 @@ chains:
 
 func (tlp *TLSPinner) CreateTLSConfig() *tls.Config {
 	return &tls.Config{
-		VerifyPeerCertificate: tlp.verifyPeerCertificateImpl,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			return tlp.Verify(cs.ServerName, cs.VerifiedChains)
+		},
 	}
 }
 
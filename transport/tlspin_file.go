@@ -0,0 +1,158 @@
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/errwrap"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TLSPinFile The file the TLSPinner is loaded from, allowing to rotate the pins without rebuilding the client. The
+// binary attributes are hex-encoded, optionally colon-separated; the SPKI pins may also be base64-encoded. The
+// dates are RFC 3339 timestamps or yyyy-mm-dd dates.
+type TLSPinFile struct {
+	ReportOnly       bool              `json:"reportOnly,omitempty" yaml:"reportOnly,omitempty"`
+	AllowExpiredPins bool              `json:"allowExpiredPins,omitempty" yaml:"allowExpiredPins,omitempty"`
+	Primary          []TLSPinFileEntry `json:"primary" yaml:"primary"`
+	Backup           []TLSPinFileSet   `json:"backup,omitempty" yaml:"backup,omitempty"`
+}
+
+type TLSPinFileSet struct {
+	Name     string            `json:"name,omitempty" yaml:"name,omitempty"`
+	NotAfter string            `json:"notAfter,omitempty" yaml:"notAfter,omitempty"`
+	Pins     []TLSPinFileEntry `json:"pins" yaml:"pins"`
+}
+
+type TLSPinFileEntry struct {
+	CommonName   string `json:"commonName,omitempty" yaml:"commonName,omitempty"`
+	SerialNumber string `json:"serialNumber,omitempty" yaml:"serialNumber,omitempty"`
+	Fingerprint  string `json:"fingerprint,omitempty" yaml:"fingerprint,omitempty"`
+	SPKISHA256   string `json:"spkiSha256,omitempty" yaml:"spkiSha256,omitempty"`
+	NotAfter     string `json:"notAfter,omitempty" yaml:"notAfter,omitempty"`
+}
+
+// LoadTLSPinner Loads the pinner from the JSON (where the file has the .json extension) or YAML file
+func LoadTLSPinner(path string) (*TLSPinner, error) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &errwrap.WrappedError{
+			Context: "read tls pin file",
+			Cause:   err,
+		}
+	}
+
+	return ParseTLSPinner(dat, strings.EqualFold(filepath.Ext(path), ".json"))
+}
+
+// ParseTLSPinner Parses the pinner from the contents of the TLSPinFile, in JSON or YAML format
+func ParseTLSPinner(dat []byte, isJSON bool) (*TLSPinner, error) {
+	file := TLSPinFile{}
+
+	var err error
+	if isJSON {
+		err = json.Unmarshal(dat, &file)
+	} else {
+		err = yaml.Unmarshal(dat, &file)
+	}
+	if err != nil {
+		return nil, &errwrap.WrappedError{
+			Context: "parse tls pin file",
+			Cause:   err,
+		}
+	}
+
+	return file.Pinner()
+}
+
+// Pinner Converts the file into the TLSPinner
+func (f *TLSPinFile) Pinner() (*TLSPinner, error) {
+	rv := &TLSPinner{ReportOnly: f.ReportOnly, AllowExpiredPins: f.AllowExpiredPins}
+
+	for idx, entry := range f.Primary {
+		if pin, err := entry.pin(); err != nil {
+			return nil, &errwrap.WrappedError{
+				Context: fmt.Sprintf("primary pin %d", idx),
+				Cause:   err,
+			}
+		} else {
+			rv.Add(pin)
+		}
+	}
+
+	for setIdx, fileSet := range f.Backup {
+		set := TLSPinSet{Name: fileSet.Name}
+
+		var err error
+		if set.NotAfter, err = parsePinDate(fileSet.NotAfter); err != nil {
+			return nil, &errwrap.WrappedError{
+				Context: fmt.Sprintf("backup set %d", setIdx),
+				Cause:   err,
+			}
+		}
+
+		for idx, entry := range fileSet.Pins {
+			if pin, err := entry.pin(); err != nil {
+				return nil, &errwrap.WrappedError{
+					Context: fmt.Sprintf("backup set %d pin %d", setIdx, idx),
+					Cause:   err,
+				}
+			} else {
+				set.Pins = append(set.Pins, pin)
+			}
+		}
+
+		rv.AddBackup(set)
+	}
+
+	return rv, nil
+}
+
+func (e *TLSPinFileEntry) pin() (TLSCertChainPin, error) {
+	rv := TLSCertChainPin{CommonName: e.CommonName}
+
+	if len(e.SerialNumber) > 0 {
+		if err := rv.SerialNumberFromHex(e.SerialNumber); err != nil {
+			return rv, err
+		}
+	}
+	if len(e.Fingerprint) > 0 {
+		if err := rv.FingerprintFrom(e.Fingerprint); err != nil {
+			return rv, err
+		}
+	}
+	if len(e.SPKISHA256) > 0 {
+		if err := rv.SPKISHA256From(e.SPKISHA256); err != nil {
+			return rv, err
+		}
+	}
+
+	var err error
+	if rv.NotAfter, err = parsePinDate(e.NotAfter); err != nil {
+		return rv, err
+	}
+
+	if rv.IsEmpty() {
+		return rv, errors.New("pin does not specify any attributes")
+	}
+	return rv, nil
+}
+
+func parsePinDate(str string) (time.Time, error) {
+	if len(str) == 0 {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, str); err == nil {
+		return t, nil
+	} else if t, err = time.Parse(time.DateOnly, str); err == nil {
+		// The pin remains valid until the end of the day
+		return t.Add(24*time.Hour - time.Nanosecond), nil
+	}
+
+	return time.Time{}, errors.New(fmt.Sprintf("unrecognized date '%s'", str))
+}
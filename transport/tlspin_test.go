@@ -1,11 +1,28 @@
 package transport_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
 	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestDefaultPinnerWillAcceptMashery(t *testing.T) {
@@ -85,3 +102,183 @@ func TestPinnerWillSuccessfullyRejectCertOnMismatch(t *testing.T) {
 	assert.NotNil(t, err)
 
 }
+
+type testPKI struct {
+	caCert   *x509.Certificate
+	caKey    *ecdsa.PrivateKey
+	leafCert *x509.Certificate
+	server   *httptest.Server
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDer, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	assert.Nil(t, err)
+	caCert, _ := x509.ParseCertificate(caDer)
+
+	leafKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leafTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "api.example.test"},
+		DNSNames:     []string{"api.example.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	leafDer, err := x509.CreateCertificate(rand.Reader, leafTmpl, caCert, &leafKey.PublicKey, caKey)
+	assert.Nil(t, err)
+	leafCert, _ := x509.ParseCertificate(leafDer)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{leafDer}, PrivateKey: leafKey}},
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return &testPKI{caCert: caCert, caKey: caKey, leafCert: leafCert, server: server}
+}
+
+func (pki *testPKI) call(pinner *transport.TLSPinner) error {
+	cfg := pinner.CreateTLSConfig()
+	cfg.RootCAs = x509.NewCertPool()
+	cfg.RootCAs.AddCert(pki.caCert)
+	cfg.ServerName = "api.example.test"
+
+	exec := (&transport.HTTPClientParams{TLSConfig: cfg, Proxy: transport.ProxyConfig{Direct: true}}).CreateHttpExecutor()
+	defer exec.CloseIdleConnections()
+
+	req, _ := http.NewRequest(http.MethodGet, pki.server.URL, nil)
+	resp, err := exec.Do(req)
+	if err == nil {
+		_ = resp.Body.Close()
+	}
+	return err
+}
+
+func spkiPin(cert *x509.Certificate) transport.TLSCertChainPin {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return transport.TLSCertChainPin{SPKISHA256: sum[:]}
+}
+
+var wrongSPKI = transport.TLSCertChainPin{SPKISHA256: bytes.Repeat([]byte{1}, 32)}
+
+func TestSPKIPinMatchesLeafAndIssuer(t *testing.T) {
+	pki := newTestPKI(t)
+
+	pinner := &transport.TLSPinner{}
+	pinner.Add(spkiPin(pki.leafCert)).Add(spkiPin(pki.caCert))
+	assert.Nil(t, pki.call(pinner))
+
+	assert.NotNil(t, pki.call((&transport.TLSPinner{}).Add(wrongSPKI)))
+}
+
+func TestSPKIPinEncodings(t *testing.T) {
+	sum := bytes.Repeat([]byte{0xab}, 32)
+
+	pin := transport.TLSCertChainPin{}
+	assert.Nil(t, pin.SPKISHA256From(hex.EncodeToString(sum)))
+	assert.Equal(t, sum, pin.SPKISHA256)
+
+	pin = transport.TLSCertChainPin{}
+	assert.Nil(t, pin.SPKISHA256From(base64.StdEncoding.EncodeToString(sum)))
+	assert.Equal(t, sum, pin.SPKISHA256)
+
+	assert.NotNil(t, pin.SPKISHA256From("abcd"))
+}
+
+func TestBackupPinSetIsAccepted(t *testing.T) {
+	pki := newTestPKI(t)
+
+	pinner := (&transport.TLSPinner{}).Add(wrongSPKI)
+	pinner.AddBackup(transport.TLSPinSet{Name: "next", Pins: []transport.TLSCertChainPin{spkiPin(pki.leafCert)}})
+	assert.Nil(t, pki.call(pinner))
+
+	// An expired backup set is not accepted
+	pinner.BackupPinSets[0].NotAfter = time.Now().Add(-time.Minute)
+	assert.NotNil(t, pki.call(pinner))
+}
+
+func TestExpiredPinsAreNotChecked(t *testing.T) {
+	pki := newTestPKI(t)
+
+	expired := wrongSPKI
+	expired.NotAfter = time.Now().Add(-time.Minute)
+
+	pinner := (&transport.TLSPinner{}).Add(expired).Add(spkiPin(pki.caCert))
+	assert.Nil(t, pki.call(pinner))
+
+	// Where all pins have expired, the chain is rejected unless explicitly allowed.
+	assert.NotNil(t, pki.call((&transport.TLSPinner{}).Add(expired)))
+
+	buf := bytes.Buffer{}
+	pinner = (&transport.TLSPinner{
+		AllowExpiredPins: true,
+		Logger:           slog.New(slog.NewTextHandler(&buf, nil)),
+	}).Add(expired)
+	assert.Nil(t, pki.call(pinner))
+	assert.Contains(t, buf.String(), "tls pins have expired")
+}
+
+func TestReportOnlyPinnerLogsMismatch(t *testing.T) {
+	pki := newTestPKI(t)
+
+	buf := bytes.Buffer{}
+	pinner := (&transport.TLSPinner{
+		ReportOnly: true,
+		Logger:     slog.New(slog.NewTextHandler(&buf, nil)),
+	}).Add(wrongSPKI)
+
+	assert.Nil(t, pki.call(pinner))
+	assert.Contains(t, buf.String(), "tls pin mismatch")
+	assert.Contains(t, buf.String(), "api.example.test")
+}
+
+func TestLoadTLSPinnerFromFiles(t *testing.T) {
+	pki := newTestPKI(t)
+	leafSum := sha256.Sum256(pki.leafCert.RawSubjectPublicKeyInfo)
+
+	dir := t.TempDir()
+	yamlFile := filepath.Join(dir, "pins.yaml")
+	assert.Nil(t, os.WriteFile(yamlFile, []byte(`
+reportOnly: false
+primary:
+  - spkiSha256: "`+hex.EncodeToString(bytes.Repeat([]byte{1}, 32))+`"
+    notAfter: 2099-12-31
+backup:
+  - name: rotation
+    notAfter: "2099-12-31T00:00:00Z"
+    pins:
+      - commonName: api.example.test
+        spkiSha256: "`+base64.StdEncoding.EncodeToString(leafSum[:])+`"
+`), 0600))
+
+	pinner, err := transport.LoadTLSPinner(yamlFile)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(pinner.TLSCertChainPins))
+	assert.Equal(t, 1, len(pinner.BackupPinSets))
+	assert.Equal(t, "rotation", pinner.BackupPinSets[0].Name)
+	assert.Nil(t, pki.call(pinner))
+
+	jsonFile := filepath.Join(dir, "pins.json")
+	assert.Nil(t, os.WriteFile(jsonFile, []byte(`{"reportOnly": true, "primary": [{"fingerprint": "00:11"}]}`), 0600))
+	pinner, err = transport.LoadTLSPinner(jsonFile)
+	assert.Nil(t, err)
+	assert.True(t, pinner.ReportOnly)
+	assert.Equal(t, []byte{0, 0x11}, pinner.TLSCertChainPins[0].Fingerprint)
+
+	_, err = transport.ParseTLSPinner([]byte(`{"primary": [{}]}`), true)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,111 @@
+package transport
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// CorrelationIdHeader Request header carrying the correlation ID of the logical call
+const CorrelationIdHeader = "X-Correlation-Id"
+
+const correlationIdKey = ".correlation.id"
+
+// Span names and attributes set by the pipeline
+const (
+	SpanCall     = "mashery.call"
+	SpanLeaf     = "mashery.leaf"
+	SpanAttempt  = "mashery.retry.attempt"
+	SpanThrottle = "mashery.throttle"
+
+	AttrAppContext    = "mashery.app_context"
+	AttrResource      = "mashery.resource"
+	AttrCorrelationId = "mashery.correlation_id"
+	AttrAttempt       = "mashery.attempt"
+	AttrMethod        = "http.method"
+	AttrStatusCode    = "http.status_code"
+	AttrErrorCode     = "mashery.error_code"
+)
+
+// Tracer Creates the spans around the stages of the pipeline. The interface deliberately mirrors the shape of
+// the OpenTelemetry API, so that an adapter delegating to an OpenTelemetry tracer can be written by the calling
+// application without this library depending on it.
+type Tracer interface {
+	// StartSpan starts a span that is a child of the span in the context, if any. The returned context
+	// carries the new span.
+	StartSpan(ctx context.Context, name string, attributes map[string]interface{}) (context.Context, Span)
+}
+
+// Span A unit of work reported to the Tracer
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, interface{}) {}
+func (noopSpan) RecordError(error)                {}
+func (noopSpan) End()                             {}
+
+// startSpan starts the span with the tracer of the transport, or returns a span doing nothing if the transport
+// has no tracer.
+func (c *HttpTransport) startSpan(ctx context.Context, name string, attributes map[string]interface{}) (context.Context, Span) {
+	if c == nil || c.Tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return c.Tracer.StartSpan(ctx, name, attributes)
+}
+
+// endSpan records the outcome of the exchange on the span and ends it.
+func endSpan(span Span, wr *WrappedResponse, err error) {
+	if wr != nil && wr.Response != nil {
+		span.SetAttribute(AttrStatusCode, wr.StatusCode)
+		if code := wr.Header.Get("X-Mashery-Error-Code"); len(code) > 0 {
+			span.SetAttribute(AttrErrorCode, code)
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+func callAttributes(ci *CallInfo) map[string]interface{} {
+	rv := map[string]interface{}{}
+	if ci != nil {
+		rv[AttrAppContext] = ci.AppContext
+		rv[AttrResource] = ci.Resource
+		rv[AttrCorrelationId] = ci.CorrelationId
+	}
+	return rv
+}
+
+func attemptAttributes(ci *CallInfo, attempt int) map[string]interface{} {
+	rv := callAttributes(ci)
+	rv[AttrAttempt] = attempt
+	return rv
+}
+
+// WithCorrelationId Returns the context where the calls will use the supplied correlation ID instead of
+// a generated one.
+func WithCorrelationId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIdKey, id)
+}
+
+func correlationIdFrom(ctx context.Context) string {
+	if v := ctx.Value(correlationIdKey); v != nil {
+		if str, ok := v.(string); ok && len(str) > 0 {
+			return str
+		}
+	}
+
+	return newCorrelationId()
+}
+
+func newCorrelationId() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,134 @@
+package transport_test
+
+import (
+	"context"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3simulator"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+type recordedSpan struct {
+	name       string
+	parent     *recordedSpan
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (rs *recordedSpan) SetAttribute(key string, value interface{}) {
+	rs.attributes[key] = value
+}
+
+func (rs *recordedSpan) RecordError(err error) {
+	rs.err = err
+}
+
+func (rs *recordedSpan) End() {
+	rs.ended = true
+}
+
+type spanKey struct{}
+
+type recordingTracer struct {
+	mutex sync.Mutex
+	spans []*recordedSpan
+}
+
+func (rt *recordingTracer) StartSpan(ctx context.Context, name string, attributes map[string]interface{}) (context.Context, transport.Span) {
+	rv := &recordedSpan{name: name, attributes: attributes}
+	if parent, ok := ctx.Value(spanKey{}).(*recordedSpan); ok {
+		rv.parent = parent
+	}
+
+	rt.mutex.Lock()
+	defer rt.mutex.Unlock()
+	rt.spans = append(rt.spans, rv)
+
+	return context.WithValue(ctx, spanKey{}, rv), rv
+}
+
+func (rt *recordingTracer) named(name string) []*recordedSpan {
+	var rv []*recordedSpan
+	for _, s := range rt.spans {
+		if s.name == name {
+			rv = append(rv, s)
+		}
+	}
+	return rv
+}
+
+func TestTracingSpansAndCorrelationId(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	svcId, _ := sim.Create("/services", masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+
+	var sentIds []string
+	calls := 0
+	sim.Interceptor = func(r *http.Request) *http.Response {
+		sentIds = append(sentIds, r.Header.Get(transport.CorrelationIdHeader))
+		if calls++; calls == 1 {
+			return failingResponse(r, 503, "")
+		}
+		return nil
+	}
+
+	tracer := &recordingTracer{}
+	cl := v3simulator.NewClient(sim, func(p *v3client.Params) {
+		p.RetryPolicy = fastRetryPolicy()
+		p.Tracer = tracer
+	})
+
+	ctx := transport.WithCorrelationId(context.Background(), "corr-1")
+	_, exists, err := cl.GetService(ctx, masherytypes.ServiceIdentityFrom(svcId))
+	assert.Nil(t, err)
+	assert.True(t, exists)
+
+	assert.Equal(t, []string{"corr-1", "corr-1"}, sentIds)
+
+	callSpans := tracer.named(transport.SpanCall)
+	assert.Equal(t, 1, len(callSpans))
+	call := callSpans[0]
+	assert.True(t, call.ended)
+	assert.Equal(t, "service", call.attributes[transport.AttrAppContext])
+	assert.Equal(t, "/services/"+svcId, call.attributes[transport.AttrResource])
+	assert.Equal(t, "corr-1", call.attributes[transport.AttrCorrelationId])
+	assert.Equal(t, 200, call.attributes[transport.AttrStatusCode])
+	assert.Equal(t, "GET", call.attributes[transport.AttrMethod])
+
+	attempts := tracer.named(transport.SpanAttempt)
+	assert.Equal(t, 2, len(attempts))
+	assert.Equal(t, 503, attempts[0].attributes[transport.AttrStatusCode])
+	assert.Equal(t, 2, attempts[1].attributes[transport.AttrAttempt])
+	assert.Equal(t, call, attempts[0].parent)
+
+	leaves := tracer.named(transport.SpanLeaf)
+	assert.Equal(t, 2, len(leaves))
+	assert.Equal(t, attempts[1], leaves[1].parent)
+
+	assert.Equal(t, 2, len(tracer.named(transport.SpanThrottle)))
+}
+
+func TestCorrelationIdIsStoredOnResponse(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	var sent string
+	sim.Interceptor = func(r *http.Request) *http.Response {
+		sent = r.Header.Get(transport.CorrelationIdHeader)
+		return nil
+	}
+
+	var received string
+	cl := v3simulator.NewClient(sim, func(p *v3client.Params) {
+		p.ExchangeListener = func(ctx context.Context, req *transport.WrappedRequest, res *transport.WrappedResponse, err error) {
+			received = res.CorrelationId
+		}
+	})
+
+	_, err := cl.ListServices(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 32, len(sent))
+	assert.Equal(t, sent, received)
+}
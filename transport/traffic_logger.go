@@ -0,0 +1,142 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// TrafficVerbosity Determines how much of the exchange the TrafficLogger writes
+type TrafficVerbosity int
+
+const (
+	// TrafficSummary logs the method, URL, status, error code and timing of the exchange
+	TrafficSummary TrafficVerbosity = iota
+	// TrafficHeaders additionally logs the request and response headers
+	TrafficHeaders
+	// TrafficBodies additionally logs the request and response bodies
+	TrafficBodies
+)
+
+// DefaultMaxLoggedBody Default number of body bytes written by the TrafficLogger
+const DefaultMaxLoggedBody = 4096
+
+// TrafficLogger Writes the exchanges with Mashery as structured log records. Authorization headers, V2
+// signatures, secrets and passwords are redacted as configured by the Scrubber.
+type TrafficLogger struct {
+	Logger    *slog.Logger
+	Level     slog.Level
+	Verbosity TrafficVerbosity
+	// MaxBodySize is the number of bytes of each body written to the log; longer bodies are truncated.
+	MaxBodySize int
+	Scrubber    CassetteScrubber
+}
+
+func NewTrafficLogger(logger *slog.Logger, verbosity TrafficVerbosity) *TrafficLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return &TrafficLogger{
+		Logger:      logger,
+		Level:       slog.LevelDebug,
+		Verbosity:   verbosity,
+		MaxBodySize: DefaultMaxLoggedBody,
+		Scrubber:    DefaultCassetteScrubber(),
+	}
+}
+
+// Listen Logs the exchange. The method has the signature of the ExchangeListener.
+func (tl *TrafficLogger) Listen(ctx context.Context, req *WrappedRequest, res *WrappedResponse, err error) {
+	if req == nil || req.Request == nil {
+		return
+	}
+
+	level := tl.Level
+	if err != nil && level < slog.LevelWarn {
+		level = slog.LevelWarn
+	}
+	if !tl.Logger.Enabled(ctx, level) {
+		return
+	}
+
+	r := req.Request
+	attrs := []slog.Attr{
+		slog.String("method", r.Method),
+		slog.String("url", tl.scrubURL(r.URL)),
+	}
+	if !req.Sent.IsZero() {
+		attrs = append(attrs, slog.Duration("duration", time.Since(req.Sent)))
+	}
+	if id := r.Header.Get(CorrelationIdHeader); len(id) > 0 {
+		attrs = append(attrs, slog.String("correlationId", id))
+	}
+
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	if res != nil && res.Response != nil {
+		attrs = append(attrs, slog.Int("status", res.StatusCode))
+		if code := res.Header.Get("X-Mashery-Error-Code"); len(code) > 0 {
+			attrs = append(attrs, slog.String("errorCode", code))
+		}
+	}
+
+	if tl.Verbosity >= TrafficHeaders {
+		attrs = append(attrs, tl.headerGroup("requestHeaders", r.Header))
+		if res != nil && res.Response != nil {
+			attrs = append(attrs, tl.headerGroup("responseHeaders", res.Header))
+		}
+	}
+
+	if tl.Verbosity >= TrafficBodies {
+		if body := requestBody(req); len(body) > 0 {
+			attrs = append(attrs, slog.String("requestBody", tl.body(r.Header.Get("Content-Type"), body)))
+		}
+		if res != nil && res.Response != nil {
+			if body, readErr := res.Body(); readErr == nil && len(body) > 0 {
+				attrs = append(attrs, slog.String("responseBody", tl.body(res.Header.Get("Content-Type"), body)))
+			}
+		}
+	}
+
+	tl.Logger.LogAttrs(ctx, level, "mashery exchange", attrs...)
+}
+
+func (tl *TrafficLogger) scrubURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	rv := *u
+	if qs := u.Query(); len(qs) > 0 {
+		rv.RawQuery = tl.Scrubber.scrubQuery(qs).Encode()
+	}
+	return rv.String()
+}
+
+func (tl *TrafficLogger) headerGroup(name string, h http.Header) slog.Attr {
+	scrubbed := tl.Scrubber.scrubHeader(h)
+
+	args := make([]any, 0, len(scrubbed))
+	for k, v := range scrubbed {
+		if len(v) == 1 {
+			args = append(args, slog.String(k, v[0]))
+		} else {
+			args = append(args, slog.Any(k, v))
+		}
+	}
+	return slog.Group(name, args...)
+}
+
+func (tl *TrafficLogger) body(contentType string, body []byte) string {
+	rv := tl.Scrubber.scrubBody(contentType, body)
+	if tl.MaxBodySize > 0 && len(rv) > tl.MaxBodySize {
+		return fmt.Sprintf("%s...(%d bytes truncated)", rv[:tl.MaxBodySize], len(rv)-tl.MaxBodySize)
+	}
+	return rv
+}
@@ -0,0 +1,81 @@
+package transport_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3simulator"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func loggingClient(sim *v3simulator.Simulator, tl *transport.TrafficLogger) v3client.Client {
+	return v3simulator.NewClient(sim, func(p *v3client.Params) {
+		p.Authorizer = transport.NewBearerAuthorizer("secret-token")
+		p.ExchangeListener = tl.Listen
+	})
+}
+
+func TestTrafficLoggerRedactsSecrets(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	_, _ = sim.Create("/packageKeys", masherytypes.PackageKey{})
+
+	buf := bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	tl := transport.NewTrafficLogger(logger, transport.TrafficBodies)
+
+	cl := loggingClient(sim, tl)
+	ctx := context.Background()
+
+	passwd := "very-secret-password"
+	_, err := cl.CreateMember(ctx, masherytypes.Member{Username: "user", PasswdNew: &passwd})
+	assert.Nil(t, err)
+
+	keys, err := cl.ListPackageKeys(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(keys))
+
+	out := buf.String()
+	assert.Equal(t, 2, strings.Count(out, "mashery exchange"))
+	assert.Contains(t, out, `"method":"POST"`)
+	assert.Contains(t, out, `"status":200`)
+	assert.Contains(t, out, `"duration"`)
+	assert.Contains(t, out, `"correlationId"`)
+	assert.Contains(t, out, `"requestHeaders"`)
+	assert.NotContains(t, out, "secret-token")
+	assert.NotContains(t, out, "very-secret-password")
+	assert.NotContains(t, out, *keys[0].Secret)
+}
+
+func TestTrafficLoggerTruncatesBodiesAndHonoursVerbosity(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	for i := 0; i < 5; i++ {
+		_, _ = sim.Create("/services", masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "a-service-with-a-long-name"}})
+	}
+
+	buf := bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	tl := transport.NewTrafficLogger(logger, transport.TrafficBodies)
+	tl.MaxBodySize = 50
+
+	_, err := loggingClient(sim, tl).ListServices(context.Background())
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), "bytes truncated")
+
+	buf.Reset()
+	tl.Verbosity = transport.TrafficSummary
+	_, err = loggingClient(sim, tl).ListServices(context.Background())
+	assert.Nil(t, err)
+	assert.NotContains(t, buf.String(), "responseBody")
+	assert.NotContains(t, buf.String(), "requestHeaders")
+
+	buf.Reset()
+	tl.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	_, err = loggingClient(sim, tl).ListServices(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, buf.Len())
+}
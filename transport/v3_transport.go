@@ -3,13 +3,13 @@ package transport
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/errwrap"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 )
 
 type MiddlewareFunc func(ctx context.Context, transport *HttpTransport) (*WrappedResponse, error)
@@ -18,7 +18,7 @@ type MiddlewareFunc func(ctx context.Context, transport *HttpTransport) (*Wrappe
 type ChainedMiddlewareFunc func(ctx context.Context, transport *HttpTransport, middlewareFunc MiddlewareFunc) (*WrappedResponse, error)
 
 func GetObject[T any](ctx context.Context, opCtx ObjectFetchSpec[T], c *HttpTransport) (T, bool, error) {
-	rv, resp, err := performGenericObjectCRUDWithResponse[T](ctx, c, opCtx, opCtx.FetchFunc())
+	rv, resp, err := performGenericObjectCRUDWithResponse[T](ctx, c, http.MethodGet, opCtx, opCtx.FetchFunc())
 	objectExists := false
 	if resp != nil {
 		objectExists = resp.StatusCode == 200
@@ -27,26 +27,26 @@ func GetObject[T any](ctx context.Context, opCtx ObjectFetchSpec[T], c *HttpTran
 }
 
 func CreateObject[T any](ctx context.Context, opCtx ObjectUpsertSpec[T], c *HttpTransport) (T, error) {
-	return performGenericObjectCRUD(ctx, c, opCtx.ObjectFetchSpec, func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
+	return performGenericObjectCRUD(ctx, c, http.MethodPost, opCtx.ObjectFetchSpec, func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
 		return c.Post(ctx, opCtx.DestResource(), opCtx.Upsert)
 	})
 }
 
 func UpdateObject[T any](ctx context.Context, opCtx ObjectUpsertSpec[T], c *HttpTransport) (T, error) {
-	return performGenericObjectCRUD(ctx, c, opCtx.ObjectFetchSpec, func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
+	return performGenericObjectCRUD(ctx, c, http.MethodPut, opCtx.ObjectFetchSpec, func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
 		return c.Put(ctx, opCtx.DestResource(), opCtx.Upsert)
 	})
 }
 
 // ExchangeObject exchange an input object for the output one.
 func ExchangeObject[TIn, TOut any](ctx context.Context, opCtx ObjectExchangeSpec[TIn, TOut], verb string, c *HttpTransport) (TOut, error) {
-	return performGenericObjectCRUD(ctx, c, opCtx.ObjectFetchSpec, func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
+	return performGenericObjectCRUD(ctx, c, strings.ToUpper(verb), opCtx.ObjectFetchSpec, func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
 		return c.Send(ctx, verb, opCtx.DestResource(), opCtx.Body)
 	})
 }
 
 func DeleteObject[T any](ctx context.Context, opCtx ObjectFetchSpec[T], c *HttpTransport) error {
-	_, err := performGenericObjectCRUD(ctx, c, opCtx, func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
+	_, err := performGenericObjectCRUD(ctx, c, http.MethodDelete, opCtx, func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
 		return c.Delete(ctx, opCtx.DestResource())
 	})
 
@@ -62,7 +62,7 @@ func Count[T any](ctx context.Context, opCtx ObjectListFetchSpec[T], c *HttpTran
 	builder := opCtx.ToBuilder()
 	builder.WithMergedQuery(limitQuery)
 
-	if _, wr, err := performGenericObjectCRUDWithResponse(ctx, c, builder.Build().AsObjectFetchSpec(), func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
+	if _, wr, err := performGenericObjectCRUDWithResponse(ctx, c, http.MethodGet, builder.Build().AsObjectFetchSpec(), func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
 		return c.Fetch(ctx, opCtx.DestResource())
 	}); err != nil {
 		return -1, err
@@ -72,7 +72,7 @@ func Count[T any](ctx context.Context, opCtx ObjectListFetchSpec[T], c *HttpTran
 }
 
 func Exists[T any](ctx context.Context, opCtx ObjectFetchSpec[T], c *HttpTransport) (bool, error) {
-	if _, wr, err := performGenericObjectCRUDWithResponse(ctx, c, opCtx, func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
+	if _, wr, err := performGenericObjectCRUDWithResponse(ctx, c, http.MethodGet, opCtx, func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
 		return c.Fetch(ctx, opCtx.DestResource())
 	}); err != nil {
 		return false, err
@@ -83,7 +83,7 @@ func Exists[T any](ctx context.Context, opCtx ObjectFetchSpec[T], c *HttpTranspo
 
 // AsyncFetch Perform a fetch asynchronously, returning the response in the provided channel.
 func AsyncFetch[T any](ctx context.Context, opCtx ObjectFetchSpec[T], c *HttpTransport, comm chan AsyncFetchResult[T]) {
-	rv, err := performGenericObjectCRUD(ctx, c, opCtx, func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
+	rv, err := performGenericObjectCRUD(ctx, c, http.MethodGet, opCtx, func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
 		return c.Fetch(ctx, opCtx.DestResource())
 	})
 
@@ -99,85 +99,20 @@ func FetchAll[T any](ctx context.Context, opCtx ObjectListFetchSpec[T], c *HttpT
 	return rv, err
 }
 
-// FetchAllWithExists Fetch all Mashery objects, including the handling for the pagination
+// FetchAllWithExists Fetch all Mashery objects, including the handling for the pagination. The pages are fetched
+// with the default concurrency of the Paginator; the fetching stops on the first error, in which case the objects
+// fetched so far are returned together with the error.
 func FetchAllWithExists[T any](ctx context.Context, opCtx ObjectListFetchSpec[T], c *HttpTransport) ([]T, bool, error) {
+	p := NewPaginator(opCtx, c, PaginatorOptions{})
+	rv, err := p.Collect(ctx)
 
-	firstPageData, firstPageResponse, firstPageFetchErr := performGenericObjectCRUDWithResponse[[]T](ctx, c, opCtx.AsObjectFetchSpec(), func(ctx context.Context, c *HttpTransport) (*WrappedResponse, error) {
-		return c.Fetch(ctx, opCtx.DestResource())
-	})
-
-	if firstPageFetchErr != nil {
-		return nil, false, firstPageFetchErr
-	} else if firstPageData == nil {
-		return nil, firstPageResponse.StatusCode != 404, nil
-	}
-
-	rv := firstPageData
-	var collErr error
-
-	pageSize := len(rv)
-	// Don't try doing anything else if the response contains no data.
-	if pageSize == 0 {
-		return rv, firstPageResponse.StatusCode != 404, nil
-	}
-
-	totalCountHdr := firstPageResponse.Header.Get("X-Total-Count")
-	//fmt.Println(totalCountHdr)
-
-	if len(totalCountHdr) > 0 {
-		totalCount, _ := strconv.ParseInt(totalCountHdr, 10, 0)
-
-		if totalCount > int64(pageSize) {
-			allFetches := int(totalCount / int64(pageSize))
-
-			commChan := make(chan AsyncFetchResult[[]T])
-			defer close(commChan)
-
-			for p := 1; p <= allFetches; p++ {
-				offset := p
-				if opCtx.Pagination == PerItem {
-					offset *= pageSize
-				}
-
-				offsetParam := url.Values{
-					"offset": {strconv.Itoa(offset)},
-				}
-				//fmt.Println(offsetParam)
-
-				pageFetchSpec := opCtx.ToBuilder()
-				pageFetchSpec.WithMergedQuery(offsetParam)
-
-				go AsyncFetch(ctx, pageFetchSpec.Build().AsObjectFetchSpec(), c, commChan)
-			}
-
-			for p := 1; p <= allFetches; p++ {
-				asyncRead := <-commChan
-				if asyncRead.Err != nil {
-					collErr = asyncRead.Err
-					// TODO: if error occurred, we might need to terminate the rest
-					// of the fetching operations.
-				} else {
-					if asyncRead.Data == nil {
-						collErr = &errwrap.WrappedError{
-							Context: fmt.Sprintf("fetch all %s->read async response", opCtx.AppContext),
-							Cause:   errors.New("nil response received"),
-						}
-						// TODO Terminate making any remaining calls to the server
-					} else {
-						rv = append(rv, asyncRead.Data...)
-					}
-				}
-			}
-		}
-	}
-
-	return rv, true, collErr
+	return rv, p.Exists(), err
 }
 
 type CallFunc func(ctx context.Context) (*WrappedResponse, error)
 
-func performGenericObjectCRUD[T any](ctx context.Context, c *HttpTransport, opCtx ObjectFetchSpec[T], f MiddlewareFunc) (T, error) {
-	rv, _, err := performGenericObjectCRUDWithResponse(ctx, c, opCtx, f)
+func performGenericObjectCRUD[T any](ctx context.Context, c *HttpTransport, method string, opCtx ObjectFetchSpec[T], f MiddlewareFunc) (T, error) {
+	rv, _, err := performGenericObjectCRUDWithResponse(ctx, c, method, opCtx, f)
 	return rv, err
 }
 
@@ -197,19 +132,46 @@ func wrapMiddleware(p1 ChainedMiddlewareFunc, mf MiddlewareFunc) MiddlewareFunc
 	}
 }
 
-func executeCallPipeline(ctx context.Context, c *HttpTransport, execFunc MiddlewareFunc) (*WrappedResponse, error) {
-	cCtx := context.WithValue(ctx, LeafExecutor, execFunc)
+func executeCallPipeline(ctx context.Context, c *HttpTransport, method string, spec CommonFetchSpec, execFunc MiddlewareFunc) (wr *WrappedResponse, err error) {
+	if opts := CallOptionsFromContext(ctx); opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+
+		// The body has to be read before the context is cancelled.
+		defer func() {
+			if wr != nil && wr.Response != nil {
+				_, _ = wr.Body()
+			}
+		}()
+	}
+
+	cCtx := contextWithCallInfo(ctx, method, spec)
+	ci := CallInfoFromContext(cCtx)
+
+	cCtx, span := c.startSpan(cCtx, SpanCall, callAttributes(ci))
+	cCtx = context.WithValue(cCtx, LeafExecutor, execFunc)
+
+	// Mutating calls of a dry run are planned without passing the pipeline, so that these take neither
+	// the throttle slots nor the breaker's or the metrics' account.
+	if c.DryRun != nil && c.DryRun.interceptsMethod(method) {
+		wr, err = execFunc(cCtx, c)
+	} else {
+		wr, err = c.Pipeline(cCtx, c)
+	}
+	span.SetAttribute(AttrMethod, ci.Method())
+	endSpan(span, wr, err)
 
-	return c.Pipeline(cCtx, c)
+	return wr, err
 }
 
-func performGenericObjectCRUDWithResponse[T any](entryCtx context.Context, c *HttpTransport, opCtx ObjectFetchSpec[T], f MiddlewareFunc) (T, *WrappedResponse, error) {
+func performGenericObjectCRUDWithResponse[T any](entryCtx context.Context, c *HttpTransport, method string, opCtx ObjectFetchSpec[T], f MiddlewareFunc) (T, *WrappedResponse, error) {
 	ctx := entryCtx
 	if !opCtx.Return404AsNil {
 		ctx = context.WithValue(ctx, SendErrorOn404, true)
 	}
 
-	if wr, err := executeCallPipeline(ctx, c, f); err != nil {
+	if wr, err := executeCallPipeline(ctx, c, method, opCtx.CommonFetchSpec, f); err != nil {
 		return opCtx.ValueFactory(), wr, err
 	} else {
 		rv := opCtx.ValueFactory()
@@ -3,6 +3,7 @@ package transport
 import (
 	"net/http"
 	"sync"
+	"time"
 )
 
 // WrappedResponse Wraps the response so that calling applications can safely read the body multiple times.
@@ -11,6 +12,8 @@ type WrappedResponse struct {
 	Response   *http.Response
 	StatusCode int
 	Header     http.Header
+	// CorrelationId of the logical call this response belongs to
+	CorrelationId string
 
 	once sync.Once
 
@@ -21,6 +24,8 @@ type WrappedResponse struct {
 type WrappedRequest struct {
 	Request *http.Request
 	Body    interface{}
+	// Sent is the time the request was passed to the HttpExecutor
+	Sent time.Time
 }
 
 func (wr *WrappedResponse) Body() ([]byte, error) {
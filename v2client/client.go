@@ -85,7 +85,9 @@ func (ci *ClientImpl) Close(ctx context.Context) {
 
 func (ci *ClientImpl) GetRawResponse(ctx context.Context, req V2Request) (*transport.WrappedResponse, error) {
 	// Implement rate-controls
-	time.Sleep(ci.transport.DelayBeforeCall())
+	if err := ci.transport.WaitForCallSlot(ctx); err != nil {
+		return nil, err
+	}
 
 	m, _ := ci.transport.Authorizer.QueryStringAuthorization(ctx)
 	qs := url.Values{}
@@ -109,6 +111,8 @@ type Params struct {
 	Authorizer     transport.Authorizer
 	QPS            int64
 	TravelTimeComp time.Duration
+	// RateLimiter paces the calls made by the client. If not set, a token bucket limiter observing QPS is created.
+	RateLimiter transport.RateLimiter
 
 	MasheryEndpoint string
 }
@@ -130,6 +134,9 @@ func (h *Params) FillDefaults() error {
 	if h.QPS <= 0 {
 		h.QPS = 2
 	}
+	if h.RateLimiter == nil {
+		h.RateLimiter = transport.NewTokenBucketRateLimiter(float64(h.QPS), 1)
+	}
 
 	if h.Timeout == 0 {
 		h.Timeout = time.Second * 60
@@ -154,5 +161,8 @@ func NewHTTPClient(params Params) Client {
 			HttpExecutor: params.CreateHttpExecutor(),
 			Mutex:        &sync.Mutex{},
 			MaxQPS:       params.QPS,
+			RateLimiter:  params.RateLimiter,
+
+			ExchangeListener: params.ExchangeListener,
 		}}
 }
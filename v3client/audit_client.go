@@ -0,0 +1,539 @@
+package v3client
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"log/slog"
+	"time"
+)
+
+// AuditOperation Kind of the change recorded by the AuditingClient
+type AuditOperation string
+
+const (
+	AuditCreate = AuditOperation("create")
+	AuditUpdate = AuditOperation("update")
+	AuditDelete = AuditOperation("delete")
+)
+
+// AuditOutcome Outcome of the audited change
+type AuditOutcome string
+
+const (
+	AuditSuccess = AuditOutcome("success")
+	AuditFailure = AuditOutcome("failure")
+)
+
+// AuditKind Kind of the Mashery resource changed
+type AuditKind string
+
+const (
+	AuditService                      = AuditKind("service")
+	AuditErrorSet                     = AuditKind("error set")
+	AuditErrorSetMessage              = AuditKind("error set message")
+	AuditServiceRoles                 = AuditKind("service roles")
+	AuditServiceCache                 = AuditKind("service cache")
+	AuditServiceOAuth                 = AuditKind("service oauth security profile")
+	AuditEndpoint                     = AuditKind("endpoint")
+	AuditEndpointMethod               = AuditKind("endpoint method")
+	AuditEndpointMethodFilter         = AuditKind("endpoint method filter")
+	AuditPackage                      = AuditKind("package")
+	AuditPlan                         = AuditKind("plan")
+	AuditPlanService                  = AuditKind("plan service")
+	AuditPlanEndpoint                 = AuditKind("plan endpoint")
+	AuditPlanMethod                   = AuditKind("plan method")
+	AuditPlanMethodFilter             = AuditKind("plan method filter")
+	AuditMember                       = AuditKind("member")
+	AuditApplication                  = AuditKind("application")
+	AuditApplicationExtendedAttribute = AuditKind("application extended attributes")
+	AuditApplicationPackageKey        = AuditKind("application package key")
+	AuditPackageKey                   = AuditKind("package key")
+)
+
+// AuditPrincipal Identifies who is making the changes
+type AuditPrincipal struct {
+	ApiKey   string `json:"apiKey,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// AuditPrincipalOf Returns the principal of the credentials. The secret and the password are not included.
+func AuditPrincipalOf(creds MasheryV3Credentials) AuditPrincipal {
+	return AuditPrincipal{
+		ApiKey:   creds.ApiKey,
+		Username: creds.Username,
+	}
+}
+
+// AuditRecord Record of a single change made through the AuditingClient
+type AuditRecord struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Principal AuditPrincipal `json:"principal"`
+	Operation AuditOperation `json:"operation"`
+	Kind      AuditKind      `json:"kind"`
+	// Identifier is the typed identifier of the changed object. It is not set for the failed creates.
+	Identifier interface{} `json:"identifier,omitempty"`
+	// Parent is the identifier of the object the new object was created in, if any.
+	Parent interface{} `json:"parent,omitempty"`
+	// Before is the state of the object read before an update or a delete; it is not set if the object did not exist.
+	Before json.RawMessage `json:"before,omitempty"`
+	// BeforeError is the error that prevented reading the state before the change.
+	BeforeError string `json:"beforeError,omitempty"`
+	// After is the state of the object Mashery returned in response to a create or an update.
+	After   json.RawMessage `json:"after,omitempty"`
+	Outcome AuditOutcome    `json:"outcome"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// AuditSink Receives the audit records
+type AuditSink interface {
+	Write(ctx context.Context, rec AuditRecord) error
+}
+
+// AuditSinkFunc Adapter allowing the use of ordinary functions as AuditSink
+type AuditSinkFunc func(ctx context.Context, rec AuditRecord) error
+
+func (f AuditSinkFunc) Write(ctx context.Context, rec AuditRecord) error {
+	return f(ctx, rec)
+}
+
+// stateFetcher reads the current state of the object being changed
+type stateFetcher func() (interface{}, bool, error)
+
+func stateOf[TIdent, T any](ctx context.Context, get func(context.Context, TIdent) (T, bool, error), id TIdent) stateFetcher {
+	return func() (interface{}, bool, error) {
+		return get(ctx, id)
+	}
+}
+
+// AuditingClient Client decorator recording each create, update and delete into the Sink. The state of the object
+// before updates and deletes is read from Mashery before the change is made. The states are recorded with the
+// secrets redacted as configured by the Scrubber.
+//
+// The change is made whether the record could be written or not; the errors of the sink are passed to
+// OnSinkError, or logged where it is not set. Methods that don't change Mashery objects are passed to the wrapped
+// client unchanged.
+type AuditingClient struct {
+	Client
+	Principal AuditPrincipal
+	Sink      AuditSink
+	Scrubber  transport.CassetteScrubber
+	// OnSinkError, if set, is notified of the records the Sink failed to write.
+	OnSinkError func(rec AuditRecord, err error)
+	// Logger receives the errors of the Sink where OnSinkError is not set. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+func NewAuditingClient(delegate Client, principal AuditPrincipal, sink AuditSink) *AuditingClient {
+	return &AuditingClient{
+		Client:    delegate,
+		Principal: principal,
+		Sink:      sink,
+		Scrubber:  transport.DefaultCassetteScrubber(),
+	}
+}
+
+func (ac *AuditingClient) snapshot(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+
+	if dat, err := json.Marshal(v); err != nil || string(dat) == "null" {
+		return nil
+	} else {
+		return ac.Scrubber.ScrubJSON(dat)
+	}
+}
+
+// begin starts the record of the change, reading the state of the object before the change where before is supplied.
+func (ac *AuditingClient) begin(op AuditOperation, kind AuditKind, ident interface{}, before stateFetcher) *AuditRecord {
+	rv := &AuditRecord{
+		Principal:  ac.Principal,
+		Operation:  op,
+		Kind:       kind,
+		Identifier: ident,
+	}
+
+	if before != nil {
+		if state, exists, err := before(); err != nil {
+			rv.BeforeError = err.Error()
+		} else if exists {
+			rv.Before = ac.snapshot(state)
+		}
+	}
+
+	return rv
+}
+
+func (ac *AuditingClient) beginCreate(kind AuditKind, parent interface{}) *AuditRecord {
+	rv := ac.begin(AuditCreate, kind, nil, nil)
+	rv.Parent = parent
+	return rv
+}
+
+// end completes the record with the outcome of the change and passes it to the sink. The error of the change is
+// returned unchanged.
+func (ac *AuditingClient) end(ctx context.Context, rec *AuditRecord, after interface{}, err error) error {
+	rec.Timestamp = time.Now()
+	if err != nil {
+		rec.Outcome = AuditFailure
+		rec.Error = err.Error()
+	} else {
+		rec.Outcome = AuditSuccess
+		rec.After = ac.snapshot(after)
+	}
+
+	if ac.Sink != nil {
+		if sinkErr := ac.Sink.Write(ctx, *rec); sinkErr != nil {
+			ac.sinkError(*rec, sinkErr)
+		}
+	}
+
+	return err
+}
+
+func (ac *AuditingClient) sinkError(rec AuditRecord, err error) {
+	if ac.OnSinkError != nil {
+		ac.OnSinkError(rec, err)
+		return
+	}
+
+	logger := ac.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.Error("audit record could not be written",
+		"operation", rec.Operation, "kind", rec.Kind, "outcome", rec.Outcome, "error", err.Error())
+}
+
+func (ac *AuditingClient) endCreate(ctx context.Context, rec *AuditRecord, ident interface{}, after interface{}, err error) error {
+	if err == nil {
+		rec.Identifier = ident
+	}
+	return ac.end(ctx, rec, after, err)
+}
+
+// -----------------------------------------------------------------------------------------------------------------
+// Services
+
+func (ac *AuditingClient) CreateService(ctx context.Context, service masherytypes.Service) (masherytypes.Service, error) {
+	rec := ac.beginCreate(AuditService, nil)
+	rv, err := ac.Client.CreateService(ctx, service)
+	return rv, ac.endCreate(ctx, rec, rv.Identifier(), rv, err)
+}
+
+func (ac *AuditingClient) UpdateService(ctx context.Context, service masherytypes.Service) (masherytypes.Service, error) {
+	rec := ac.begin(AuditUpdate, AuditService, service.Identifier(), stateOf(ctx, ac.Client.GetService, service.Identifier()))
+	rv, err := ac.Client.UpdateService(ctx, service)
+	return rv, ac.end(ctx, rec, rv, err)
+}
+
+func (ac *AuditingClient) DeleteService(ctx context.Context, serviceId masherytypes.ServiceIdentifier) error {
+	rec := ac.begin(AuditDelete, AuditService, serviceId, stateOf(ctx, ac.Client.GetService, serviceId))
+	return ac.end(ctx, rec, nil, ac.Client.DeleteService(ctx, serviceId))
+}
+
+func (ac *AuditingClient) CreateErrorSet(ctx context.Context, serviceId masherytypes.ServiceIdentifier, set masherytypes.ErrorSet) (masherytypes.ErrorSet, error) {
+	rec := ac.beginCreate(AuditErrorSet, serviceId)
+	rv, err := ac.Client.CreateErrorSet(ctx, serviceId, set)
+	return rv, ac.endCreate(ctx, rec, rv.Identifier(), rv, err)
+}
+
+func (ac *AuditingClient) UpdateErrorSet(ctx context.Context, setData masherytypes.ErrorSet) (masherytypes.ErrorSet, error) {
+	rec := ac.begin(AuditUpdate, AuditErrorSet, setData.Identifier(), stateOf(ctx, ac.Client.GetErrorSet, setData.Identifier()))
+	rv, err := ac.Client.UpdateErrorSet(ctx, setData)
+	return rv, ac.end(ctx, rec, rv, err)
+}
+
+func (ac *AuditingClient) DeleteErrorSet(ctx context.Context, ident masherytypes.ErrorSetIdentifier) error {
+	rec := ac.begin(AuditDelete, AuditErrorSet, ident, stateOf(ctx, ac.Client.GetErrorSet, ident))
+	return ac.end(ctx, rec, nil, ac.Client.DeleteErrorSet(ctx, ident))
+}
+
+// UpdateErrorSetMessage The message is identified by its error set; the states before and after the change are
+// those of the message.
+func (ac *AuditingClient) UpdateErrorSetMessage(ctx context.Context, msg masherytypes.MasheryErrorMessage) (masherytypes.MasheryErrorMessage, error) {
+	rec := ac.begin(AuditUpdate, AuditErrorSetMessage, msg.ParentErrorSet, func() (interface{}, bool, error) {
+		set, exists, err := ac.Client.GetErrorSet(ctx, msg.ParentErrorSet)
+		if err != nil || !exists || set.ErrorMessages == nil {
+			return nil, false, err
+		}
+
+		for _, m := range *set.ErrorMessages {
+			if m.Id == msg.Id {
+				return m, true, nil
+			}
+		}
+		return nil, false, nil
+	})
+	rv, err := ac.Client.UpdateErrorSetMessage(ctx, msg)
+	return rv, ac.end(ctx, rec, rv, err)
+}
+
+func (ac *AuditingClient) SetServiceRoles(ctx context.Context, id masherytypes.ServiceIdentifier, roles []masherytypes.RolePermission) error {
+	rec := ac.begin(AuditUpdate, AuditServiceRoles, id, stateOf(ctx, ac.Client.GetServiceRoles, id))
+	return ac.end(ctx, rec, roles, ac.Client.SetServiceRoles(ctx, id, roles))
+}
+
+func (ac *AuditingClient) DeleteServiceRoles(ctx context.Context, id masherytypes.ServiceIdentifier) error {
+	rec := ac.begin(AuditDelete, AuditServiceRoles, id, stateOf(ctx, ac.Client.GetServiceRoles, id))
+	return ac.end(ctx, rec, nil, ac.Client.DeleteServiceRoles(ctx, id))
+}
+
+func (ac *AuditingClient) CreateServiceCache(ctx context.Context, id masherytypes.ServiceIdentifier, service masherytypes.ServiceCache) (masherytypes.ServiceCache, error) {
+	rec := ac.beginCreate(AuditServiceCache, id)
+	rv, err := ac.Client.CreateServiceCache(ctx, id, service)
+	return rv, ac.endCreate(ctx, rec, id, rv, err)
+}
+
+func (ac *AuditingClient) UpdateServiceCache(ctx context.Context, service masherytypes.ServiceCache) (masherytypes.ServiceCache, error) {
+	rec := ac.begin(AuditUpdate, AuditServiceCache, service.ParentServiceId, stateOf(ctx, ac.Client.GetServiceCache, service.ParentServiceId))
+	rv, err := ac.Client.UpdateServiceCache(ctx, service)
+	return rv, ac.end(ctx, rec, rv, err)
+}
+
+func (ac *AuditingClient) DeleteServiceCache(ctx context.Context, id masherytypes.ServiceIdentifier) error {
+	rec := ac.begin(AuditDelete, AuditServiceCache, id, stateOf(ctx, ac.Client.GetServiceCache, id))
+	return ac.end(ctx, rec, nil, ac.Client.DeleteServiceCache(ctx, id))
+}
+
+func (ac *AuditingClient) CreateServiceOAuthSecurityProfile(ctx context.Context, id masherytypes.ServiceIdentifier, service masherytypes.MasheryOAuth) (masherytypes.MasheryOAuth, error) {
+	rec := ac.beginCreate(AuditServiceOAuth, id)
+	rv, err := ac.Client.CreateServiceOAuthSecurityProfile(ctx, id, service)
+	return rv, ac.endCreate(ctx, rec, id, rv, err)
+}
+
+func (ac *AuditingClient) UpdateServiceOAuthSecurityProfile(ctx context.Context, service masherytypes.MasheryOAuth) (masherytypes.MasheryOAuth, error) {
+	rec := ac.begin(AuditUpdate, AuditServiceOAuth, service.ParentService, stateOf(ctx, ac.Client.GetServiceOAuthSecurityProfile, service.ParentService))
+	rv, err := ac.Client.UpdateServiceOAuthSecurityProfile(ctx, service)
+	return rv, ac.end(ctx, rec, rv, err)
+}
+
+func (ac *AuditingClient) DeleteServiceOAuthSecurityProfile(ctx context.Context, id masherytypes.ServiceIdentifier) error {
+	rec := ac.begin(AuditDelete, AuditServiceOAuth, id, stateOf(ctx, ac.Client.GetServiceOAuthSecurityProfile, id))
+	return ac.end(ctx, rec, nil, ac.Client.DeleteServiceOAuthSecurityProfile(ctx, id))
+}
+
+// -----------------------------------------------------------------------------------------------------------------
+// Endpoints, methods and response filters
+
+func (ac *AuditingClient) CreateEndpoint(ctx context.Context, serviceId masherytypes.ServiceIdentifier, endp masherytypes.Endpoint) (masherytypes.Endpoint, error) {
+	rec := ac.beginCreate(AuditEndpoint, serviceId)
+	rv, err := ac.Client.CreateEndpoint(ctx, serviceId, endp)
+	return rv, ac.endCreate(ctx, rec, rv.Identifier(), rv, err)
+}
+
+func (ac *AuditingClient) UpdateEndpoint(ctx context.Context, endp masherytypes.Endpoint) (masherytypes.Endpoint, error) {
+	rec := ac.begin(AuditUpdate, AuditEndpoint, endp.Identifier(), stateOf(ctx, ac.Client.GetEndpoint, endp.Identifier()))
+	rv, err := ac.Client.UpdateEndpoint(ctx, endp)
+	return rv, ac.end(ctx, rec, rv, err)
+}
+
+func (ac *AuditingClient) DeleteEndpoint(ctx context.Context, ident masherytypes.ServiceEndpointIdentifier) error {
+	rec := ac.begin(AuditDelete, AuditEndpoint, ident, stateOf(ctx, ac.Client.GetEndpoint, ident))
+	return ac.end(ctx, rec, nil, ac.Client.DeleteEndpoint(ctx, ident))
+}
+
+func (ac *AuditingClient) CreateEndpointMethod(ctx context.Context, ident masherytypes.ServiceEndpointIdentifier, methodUpsert masherytypes.ServiceEndpointMethod) (masherytypes.ServiceEndpointMethod, error) {
+	rec := ac.beginCreate(AuditEndpointMethod, ident)
+	rv, err := ac.Client.CreateEndpointMethod(ctx, ident, methodUpsert)
+	return rv, ac.endCreate(ctx, rec, rv.Identifier(), rv, err)
+}
+
+func (ac *AuditingClient) UpdateEndpointMethod(ctx context.Context, methUpsert masherytypes.ServiceEndpointMethod) (masherytypes.ServiceEndpointMethod, error) {
+	rec := ac.begin(AuditUpdate, AuditEndpointMethod, methUpsert.Identifier(), stateOf(ctx, ac.Client.GetEndpointMethod, methUpsert.Identifier()))
+	rv, err := ac.Client.UpdateEndpointMethod(ctx, methUpsert)
+	return rv, ac.end(ctx, rec, rv, err)
+}
+
+func (ac *AuditingClient) DeleteEndpointMethod(ctx context.Context, ident masherytypes.ServiceEndpointMethodIdentifier) error {
+	rec := ac.begin(AuditDelete, AuditEndpointMethod, ident, stateOf(ctx, ac.Client.GetEndpointMethod, ident))
+	return ac.end(ctx, rec, nil, ac.Client.DeleteEndpointMethod(ctx, ident))
+}
+
+func (ac *AuditingClient) CreateEndpointMethodFilter(ctx context.Context, ident masherytypes.ServiceEndpointMethodIdentifier, filterUpsert masherytypes.ServiceEndpointMethodFilter) (masherytypes.ServiceEndpointMethodFilter, error) {
+	rec := ac.beginCreate(AuditEndpointMethodFilter, ident)
+	rv, err := ac.Client.CreateEndpointMethodFilter(ctx, ident, filterUpsert)
+	return rv, ac.endCreate(ctx, rec, rv.Identifier(), rv, err)
+}
+
+func (ac *AuditingClient) UpdateEndpointMethodFilter(ctx context.Context, methUpsert masherytypes.ServiceEndpointMethodFilter) (masherytypes.ServiceEndpointMethodFilter, error) {
+	rec := ac.begin(AuditUpdate, AuditEndpointMethodFilter, methUpsert.Identifier(), stateOf(ctx, ac.Client.GetEndpointMethodFilter, methUpsert.Identifier()))
+	rv, err := ac.Client.UpdateEndpointMethodFilter(ctx, methUpsert)
+	return rv, ac.end(ctx, rec, rv, err)
+}
+
+func (ac *AuditingClient) DeleteEndpointMethodFilter(ctx context.Context, ident masherytypes.ServiceEndpointMethodFilterIdentifier) error {
+	rec := ac.begin(AuditDelete, AuditEndpointMethodFilter, ident, stateOf(ctx, ac.Client.GetEndpointMethodFilter, ident))
+	return ac.end(ctx, rec, nil, ac.Client.DeleteEndpointMethodFilter(ctx, ident))
+}
+
+// -----------------------------------------------------------------------------------------------------------------
+// Packages and plans
+
+func (ac *AuditingClient) CreatePackage(ctx context.Context, pack masherytypes.Package) (masherytypes.Package, error) {
+	rec := ac.beginCreate(AuditPackage, nil)
+	rv, err := ac.Client.CreatePackage(ctx, pack)
+	return rv, ac.endCreate(ctx, rec, rv.Identifier(), rv, err)
+}
+
+func (ac *AuditingClient) UpdatePackage(ctx context.Context, pack masherytypes.Package) (masherytypes.Package, error) {
+	rec := ac.begin(AuditUpdate, AuditPackage, pack.Identifier(), stateOf(ctx, ac.Client.GetPackage, pack.Identifier()))
+	rv, err := ac.Client.UpdatePackage(ctx, pack)
+	return rv, ac.end(ctx, rec, rv, err)
+}
+
+func (ac *AuditingClient) ResetPackageOwnership(ctx context.Context, pack masherytypes.PackageIdentifier) (masherytypes.Package, error) {
+	rec := ac.begin(AuditUpdate, AuditPackage, pack, stateOf(ctx, ac.Client.GetPackage, pack))
+	rv, err := ac.Client.ResetPackageOwnership(ctx, pack)
+	return rv, ac.end(ctx, rec, rv, err)
+}
+
+func (ac *AuditingClient) DeletePackage(ctx context.Context, packId masherytypes.PackageIdentifier) error {
+	rec := ac.begin(AuditDelete, AuditPackage, packId, stateOf(ctx, ac.Client.GetPackage, packId))
+	return ac.end(ctx, rec, nil, ac.Client.DeletePackage(ctx, packId))
+}
+
+func (ac *AuditingClient) CreatePlan(ctx context.Context, packageId masherytypes.PackageIdentifier, plan masherytypes.Plan) (masherytypes.Plan, error) {
+	rec := ac.beginCreate(AuditPlan, packageId)
+	rv, err := ac.Client.CreatePlan(ctx, packageId, plan)
+	return rv, ac.endCreate(ctx, rec, rv.Identifier(), rv, err)
+}
+
+func (ac *AuditingClient) UpdatePlan(ctx context.Context, plan masherytypes.Plan) (masherytypes.Plan, error) {
+	rec := ac.begin(AuditUpdate, AuditPlan, plan.Identifier(), stateOf(ctx, ac.Client.GetPlan, plan.Identifier()))
+	rv, err := ac.Client.UpdatePlan(ctx, plan)
+	return rv, ac.end(ctx, rec, rv, err)
+}
+
+func (ac *AuditingClient) DeletePlan(ctx context.Context, ident masherytypes.PackagePlanIdentifier) error {
+	rec := ac.begin(AuditDelete, AuditPlan, ident, stateOf(ctx, ac.Client.GetPlan, ident))
+	return ac.end(ctx, rec, nil, ac.Client.DeletePlan(ctx, ident))
+}
+
+// CreatePlanService The plan service is a reference to the service; the identifier of the created object is the
+// supplied one.
+func (ac *AuditingClient) CreatePlanService(ctx context.Context, planService masherytypes.PackagePlanServiceIdentifier) (masherytypes.AddressableV3Object, error) {
+	rec := ac.beginCreate(AuditPlanService, planService.PackagePlanIdentifier)
+	rv, err := ac.Client.CreatePlanService(ctx, planService)
+	return rv, ac.endCreate(ctx, rec, planService, rv, err)
+}
+
+func (ac *AuditingClient) DeletePlanService(ctx context.Context, planService masherytypes.PackagePlanServiceIdentifier) error {
+	rec := ac.begin(AuditDelete, AuditPlanService, planService, nil)
+	return ac.end(ctx, rec, nil, ac.Client.DeletePlanService(ctx, planService))
+}
+
+func (ac *AuditingClient) CreatePlanEndpoint(ctx context.Context, planEndp masherytypes.PackagePlanServiceEndpointIdentifier) (masherytypes.AddressableV3Object, error) {
+	rec := ac.beginCreate(AuditPlanEndpoint, planEndp.PackagePlanIdentifier)
+	rv, err := ac.Client.CreatePlanEndpoint(ctx, planEndp)
+	return rv, ac.endCreate(ctx, rec, planEndp, rv, err)
+}
+
+func (ac *AuditingClient) DeletePlanEndpoint(ctx context.Context, planEndp masherytypes.PackagePlanServiceEndpointIdentifier) error {
+	rec := ac.begin(AuditDelete, AuditPlanEndpoint, planEndp, nil)
+	return ac.end(ctx, rec, nil, ac.Client.DeletePlanEndpoint(ctx, planEndp))
+}
+
+func (ac *AuditingClient) CreatePackagePlanMethod(ctx context.Context, id masherytypes.PackagePlanServiceEndpointMethodIdentifier) (masherytypes.PackagePlanServiceEndpointMethod, error) {
+	rec := ac.beginCreate(AuditPlanMethod, id.GetPackagePlanServiceEndpointIdentifier())
+	rv, err := ac.Client.CreatePackagePlanMethod(ctx, id)
+	return rv, ac.endCreate(ctx, rec, id, rv, err)
+}
+
+func (ac *AuditingClient) DeletePackagePlanMethod(ctx context.Context, id masherytypes.PackagePlanServiceEndpointMethodIdentifier) error {
+	rec := ac.begin(AuditDelete, AuditPlanMethod, id, stateOf(ctx, ac.Client.GetPackagePlanMethod, id))
+	return ac.end(ctx, rec, nil, ac.Client.DeletePackagePlanMethod(ctx, id))
+}
+
+func (ac *AuditingClient) CreatePackagePlanMethodFilter(ctx context.Context, id masherytypes.PackagePlanServiceEndpointMethodFilterIdentifier) (masherytypes.PackagePlanServiceEndpointMethodFilter, error) {
+	rec := ac.beginCreate(AuditPlanMethodFilter, id.AsPackagePlanServiceEndpointMethodIdentifier())
+	rv, err := ac.Client.CreatePackagePlanMethodFilter(ctx, id)
+	return rv, ac.endCreate(ctx, rec, id, rv, err)
+}
+
+func (ac *AuditingClient) DeletePackagePlanMethodFilter(ctx context.Context, id masherytypes.PackagePlanServiceEndpointMethodIdentifier) error {
+	rec := ac.begin(AuditDelete, AuditPlanMethodFilter, id, stateOf(ctx, ac.Client.GetPackagePlanMethodFilter, id))
+	return ac.end(ctx, rec, nil, ac.Client.DeletePackagePlanMethodFilter(ctx, id))
+}
+
+// -----------------------------------------------------------------------------------------------------------------
+// Members, applications and package keys
+
+func (ac *AuditingClient) CreateMember(ctx context.Context, member masherytypes.Member) (masherytypes.Member, error) {
+	rec := ac.beginCreate(AuditMember, nil)
+	rv, err := ac.Client.CreateMember(ctx, member)
+	return rv, ac.endCreate(ctx, rec, rv.Identifier(), rv, err)
+}
+
+func (ac *AuditingClient) UpdateMember(ctx context.Context, member masherytypes.Member) (masherytypes.Member, error) {
+	rec := ac.begin(AuditUpdate, AuditMember, member.Identifier(), stateOf(ctx, ac.Client.GetMember, member.Identifier()))
+	rv, err := ac.Client.UpdateMember(ctx, member)
+	return rv, ac.end(ctx, rec, rv, err)
+}
+
+func (ac *AuditingClient) DeleteMember(ctx context.Context, memberId masherytypes.MemberIdentifier) error {
+	rec := ac.begin(AuditDelete, AuditMember, memberId, stateOf(ctx, ac.Client.GetMember, memberId))
+	return ac.end(ctx, rec, nil, ac.Client.DeleteMember(ctx, memberId))
+}
+
+func (ac *AuditingClient) CreateApplication(ctx context.Context, memberId masherytypes.MemberIdentifier, app masherytypes.Application) (masherytypes.Application, error) {
+	rec := ac.beginCreate(AuditApplication, memberId)
+	rv, err := ac.Client.CreateApplication(ctx, memberId, app)
+	return rv, ac.endCreate(ctx, rec, rv.Identifier(), rv, err)
+}
+
+func (ac *AuditingClient) UpdateApplication(ctx context.Context, app masherytypes.Application) (masherytypes.Application, error) {
+	rec := ac.begin(AuditUpdate, AuditApplication, app.Identifier(), stateOf(ctx, ac.Client.GetApplication, app.Identifier()))
+	rv, err := ac.Client.UpdateApplication(ctx, app)
+	return rv, ac.end(ctx, rec, rv, err)
+}
+
+func (ac *AuditingClient) DeleteApplication(ctx context.Context, appId masherytypes.ApplicationIdentifier) error {
+	rec := ac.begin(AuditDelete, AuditApplication, appId, stateOf(ctx, ac.Client.GetApplication, appId))
+	return ac.end(ctx, rec, nil, ac.Client.DeleteApplication(ctx, appId))
+}
+
+func (ac *AuditingClient) UpdateApplicationExtendedAttributes(ctx context.Context, appId masherytypes.ApplicationIdentifier, params map[string]string) (map[string]string, error) {
+	rec := ac.begin(AuditUpdate, AuditApplicationExtendedAttribute, appId, func() (interface{}, bool, error) {
+		rv, err := ac.Client.GetApplicationExtendedAttributes(ctx, appId)
+		return rv, err == nil, err
+	})
+	rv, err := ac.Client.UpdateApplicationExtendedAttributes(ctx, appId, params)
+	return rv, ac.end(ctx, rec, rv, err)
+}
+
+func (ac *AuditingClient) CreateApplicationPackageKey(ctx context.Context, appId masherytypes.ApplicationIdentifier, packageKey masherytypes.ApplicationPackageKey) (masherytypes.ApplicationPackageKey, error) {
+	rec := ac.beginCreate(AuditApplicationPackageKey, appId)
+	rv, err := ac.Client.CreateApplicationPackageKey(ctx, appId, packageKey)
+	return rv, ac.endCreate(ctx, rec, rv.Identifier(), rv, err)
+}
+
+func (ac *AuditingClient) UpdateApplicationPackageKey(ctx context.Context, packageKey masherytypes.ApplicationPackageKey) (masherytypes.ApplicationPackageKey, error) {
+	rec := ac.begin(AuditUpdate, AuditApplicationPackageKey, packageKey.Identifier(), stateOf(ctx, ac.Client.GetApplicationPackageKey, packageKey.Identifier()))
+	rv, err := ac.Client.UpdateApplicationPackageKey(ctx, packageKey)
+	return rv, ac.end(ctx, rec, rv, err)
+}
+
+func (ac *AuditingClient) DeleteApplicationPackageKey(ctx context.Context, keyId masherytypes.ApplicationPackageKeyIdentifier) error {
+	rec := ac.begin(AuditDelete, AuditApplicationPackageKey, keyId, stateOf(ctx, ac.Client.GetApplicationPackageKey, keyId))
+	return ac.end(ctx, rec, nil, ac.Client.DeleteApplicationPackageKey(ctx, keyId))
+}
+
+func (ac *AuditingClient) CreatePackageKey(ctx context.Context, packageKey masherytypes.PackageKey) (masherytypes.PackageKey, error) {
+	rec := ac.beginCreate(AuditPackageKey, nil)
+	rv, err := ac.Client.CreatePackageKey(ctx, packageKey)
+	return rv, ac.endCreate(ctx, rec, rv.Identifier(), rv, err)
+}
+
+func (ac *AuditingClient) UpdatePackageKey(ctx context.Context, packageKey masherytypes.PackageKey) (masherytypes.PackageKey, error) {
+	rec := ac.begin(AuditUpdate, AuditPackageKey, packageKey.Identifier(), stateOf(ctx, ac.Client.GetPackageKey, packageKey.Identifier()))
+	rv, err := ac.Client.UpdatePackageKey(ctx, packageKey)
+	return rv, ac.end(ctx, rec, rv, err)
+}
+
+func (ac *AuditingClient) DeletePackageKey(ctx context.Context, keyId masherytypes.PackageKeyIdentifier) error {
+	rec := ac.begin(AuditDelete, AuditPackageKey, keyId, stateOf(ctx, ac.Client.GetPackageKey, keyId))
+	return ac.end(ctx, rec, nil, ac.Client.DeletePackageKey(ctx, keyId))
+}
@@ -0,0 +1,219 @@
+package v3client_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3simulator"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func auditingClientOf(sim *v3simulator.Simulator) (*v3client.AuditingClient, *[]v3client.AuditRecord) {
+	cl := v3simulator.NewClient(sim)
+
+	var records []v3client.AuditRecord
+	sink := v3client.AuditSinkFunc(func(_ context.Context, rec v3client.AuditRecord) error {
+		records = append(records, rec)
+		return nil
+	})
+
+	principal := v3client.AuditPrincipalOf(v3client.MasheryV3Credentials{ApiKey: "key", Secret: "secret", Username: "operator", Password: "pwd"})
+	return v3client.NewAuditingClient(cl, principal, sink), &records
+}
+
+func TestAuditingClientRecordsChangesWithStates(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	ac, records := auditingClientOf(sim)
+	ctx := context.Background()
+
+	svc, err := ac.CreateService(ctx, masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	assert.Nil(t, err)
+
+	svc.Name = "renamed"
+	_, err = ac.UpdateService(ctx, svc)
+	assert.Nil(t, err)
+
+	assert.Nil(t, ac.DeleteService(ctx, svc.Identifier()))
+
+	assert.Equal(t, 3, len(*records))
+	created, updated, deleted := (*records)[0], (*records)[1], (*records)[2]
+
+	assert.Equal(t, v3client.AuditPrincipal{ApiKey: "key", Username: "operator"}, created.Principal)
+	assert.Equal(t, v3client.AuditCreate, created.Operation)
+	assert.Equal(t, v3client.AuditService, created.Kind)
+	assert.Equal(t, svc.Identifier(), created.Identifier)
+	assert.Nil(t, created.Before)
+	assert.Contains(t, string(created.After), `"svc"`)
+	assert.Equal(t, v3client.AuditSuccess, created.Outcome)
+	assert.False(t, created.Timestamp.IsZero())
+
+	assert.Equal(t, v3client.AuditUpdate, updated.Operation)
+	assert.Contains(t, string(updated.Before), `"svc"`)
+	assert.Contains(t, string(updated.After), `"renamed"`)
+
+	assert.Equal(t, v3client.AuditDelete, deleted.Operation)
+	assert.Equal(t, svc.Identifier(), deleted.Identifier)
+	assert.Contains(t, string(deleted.Before), `"renamed"`)
+	assert.Nil(t, deleted.After)
+}
+
+func TestAuditingClientRecordsFailuresAndRedactsSecrets(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	ac, records := auditingClientOf(sim)
+	ctx := context.Background()
+
+	member, err := ac.CreateMember(ctx, masherytypes.Member{Username: "user"})
+	assert.Nil(t, err)
+	app, err := ac.CreateApplication(ctx, member.Identifier(), masherytypes.Application{AddressableV3Object: masherytypes.AddressableV3Object{Name: "app"}})
+	assert.Nil(t, err)
+	key, err := ac.CreateApplicationPackageKey(ctx, app.Identifier(), masherytypes.ApplicationPackageKey{})
+	assert.Nil(t, err)
+	assert.NotNil(t, key.Secret)
+
+	last := (*records)[len(*records)-1]
+	assert.Equal(t, app.Identifier(), last.Parent)
+	assert.NotContains(t, string(last.After), *key.Secret)
+
+	sim.Interceptor = func(r *http.Request) *http.Response {
+		if r.Method == http.MethodPut {
+			return &http.Response{
+				StatusCode: http.StatusBadRequest,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(`{"errorCode":400,"errorMessage":"rejected"}`)),
+				Request:    r,
+			}
+		}
+		return nil
+	}
+
+	app.Name = "rejected"
+	_, err = ac.UpdateApplication(ctx, app)
+	assert.NotNil(t, err)
+
+	failed := (*records)[len(*records)-1]
+	assert.Equal(t, v3client.AuditFailure, failed.Outcome)
+	assert.NotEmpty(t, failed.Error)
+	assert.Contains(t, string(failed.Before), `"app"`)
+	assert.Nil(t, failed.After)
+}
+
+func TestAuditingClientReportsSinkErrors(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	ac, _ := auditingClientOf(sim)
+
+	ac.Sink = v3client.AuditSinkFunc(func(_ context.Context, _ v3client.AuditRecord) error {
+		return errors.New("sink unavailable")
+	})
+	var reported []error
+	ac.OnSinkError = func(_ v3client.AuditRecord, err error) {
+		reported = append(reported, err)
+	}
+
+	_, err := ac.CreateService(context.Background(), masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(reported))
+
+	// Without OnSinkError, the errors are logged
+	buf := bytes.Buffer{}
+	ac.OnSinkError = nil
+	ac.Logger = slog.New(slog.NewTextHandler(&buf, nil))
+
+	_, err = ac.CreateService(context.Background(), masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc-2"}})
+	assert.Nil(t, err)
+	assert.Contains(t, buf.String(), "audit record could not be written")
+	assert.Contains(t, buf.String(), "sink unavailable")
+}
+
+func TestRotatingFileAuditSinkRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink := v3client.NewRotatingFileAuditSink(path, 300, 2)
+	defer sink.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, sink.Write(ctx, v3client.AuditRecord{
+			Principal:  v3client.AuditPrincipal{ApiKey: "key"},
+			Operation:  v3client.AuditUpdate,
+			Kind:       v3client.AuditService,
+			Identifier: masherytypes.ServiceIdentityFrom("svc"),
+			Outcome:    v3client.AuditSuccess,
+		}))
+	}
+
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		stat, err := os.Stat(p)
+		assert.Nil(t, err)
+		assert.LessOrEqual(t, stat.Size(), int64(300))
+
+		f, err := os.Open(p)
+		assert.Nil(t, err)
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			rec := v3client.AuditRecord{}
+			assert.Nil(t, json.Unmarshal(scanner.Bytes(), &rec))
+			assert.Equal(t, v3client.AuditService, rec.Kind)
+		}
+		_ = f.Close()
+	}
+
+	_, err := os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+
+	dat, _ := os.ReadFile(path)
+	assert.True(t, strings.HasSuffix(string(dat), "\n"))
+}
+
+func TestRotatingFileAuditSinkKeepsAllBackupsByDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink := v3client.NewRotatingFileAuditSink(path, 300, 0)
+	defer sink.Close()
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, sink.Write(ctx, v3client.AuditRecord{
+			Principal:  v3client.AuditPrincipal{ApiKey: "key"},
+			Operation:  v3client.AuditUpdate,
+			Kind:       v3client.AuditService,
+			Identifier: masherytypes.ServiceIdentityFrom(fmt.Sprintf("svc-%d", i)),
+			Outcome:    v3client.AuditSuccess,
+		}))
+	}
+
+	// Reading from the oldest backup to the current file yields every record in the order written
+	files := []string{path}
+	for i := 1; ; i++ {
+		if _, err := os.Stat(fmt.Sprintf("%s.%d", path, i)); err != nil {
+			break
+		}
+		files = append([]string{fmt.Sprintf("%s.%d", path, i)}, files...)
+	}
+	assert.Less(t, 2, len(files))
+
+	var ids []string
+	for _, p := range files {
+		dat, err := os.ReadFile(p)
+		assert.Nil(t, err)
+		for _, line := range strings.Split(strings.TrimSpace(string(dat)), "\n") {
+			rec := v3client.AuditRecord{}
+			assert.Nil(t, json.Unmarshal([]byte(line), &rec))
+			ids = append(ids, fmt.Sprintf("%v", rec.Identifier))
+		}
+	}
+
+	assert.Equal(t, 10, len(ids))
+	for i, id := range ids {
+		assert.Contains(t, id, fmt.Sprintf("svc-%d", i))
+	}
+}
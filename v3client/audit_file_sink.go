@@ -0,0 +1,146 @@
+package v3client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// DefaultAuditFileMaxBytes Size the audit file is rotated at where the sink doesn't specify it
+const DefaultAuditFileMaxBytes = 10 * 1024 * 1024
+
+// RotatingFileAuditSink AuditSink appending the records to a file as JSON lines. Where the next record would
+// make the file exceed MaxBytes, the file is renamed to Path.1, the previous Path.1 to Path.2, and so on; where
+// MaxBackups is set, the files beyond it are removed. The records are never modified once written.
+type RotatingFileAuditSink struct {
+	Path string
+	// MaxBytes is the size the file is rotated at. Zero selects DefaultAuditFileMaxBytes.
+	MaxBytes int64
+	// MaxBackups is the number of rotated files to keep. Zero keeps all rotated files.
+	MaxBackups int
+
+	mutex sync.Mutex
+	file  *os.File
+	size  int64
+}
+
+func NewRotatingFileAuditSink(path string, maxBytes int64, maxBackups int) *RotatingFileAuditSink {
+	return &RotatingFileAuditSink{
+		Path:       path,
+		MaxBytes:   maxBytes,
+		MaxBackups: maxBackups,
+	}
+}
+
+func (s *RotatingFileAuditSink) Write(_ context.Context, rec AuditRecord) error {
+	dat, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	dat = append(dat, '\n')
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err = s.openLocked(); err != nil {
+		return err
+	}
+
+	if s.size > 0 && s.size+int64(len(dat)) > s.maxBytes() {
+		if err = s.rotateLocked(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(dat)
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+
+	// Audit records are expected to survive an abrupt exit of the process.
+	return s.file.Sync()
+}
+
+// Close Closes the current file. The sink re-opens the file on the next write.
+func (s *RotatingFileAuditSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.closeLocked()
+}
+
+func (s *RotatingFileAuditSink) maxBytes() int64 {
+	if s.MaxBytes > 0 {
+		return s.MaxBytes
+	}
+	return DefaultAuditFileMaxBytes
+}
+
+func (s *RotatingFileAuditSink) openLocked() error {
+	if s.file != nil {
+		return nil
+	}
+
+	f, err := os.OpenFile(s.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	if stat, statErr := f.Stat(); statErr != nil {
+		_ = f.Close()
+		return statErr
+	} else {
+		s.file = f
+		s.size = stat.Size()
+		return nil
+	}
+}
+
+func (s *RotatingFileAuditSink) closeLocked() error {
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.file.Close()
+	s.file = nil
+	s.size = 0
+	return err
+}
+
+func (s *RotatingFileAuditSink) rotateLocked() error {
+	if err := s.closeLocked(); err != nil {
+		return err
+	}
+
+	last := s.MaxBackups - 1
+	if s.MaxBackups > 0 {
+		_ = os.Remove(s.backupPath(s.MaxBackups))
+	} else {
+		// All rotated files are kept, and are shifted by one.
+		last = 0
+		for {
+			if _, err := os.Stat(s.backupPath(last + 1)); err != nil {
+				break
+			}
+			last++
+		}
+	}
+
+	for i := last; i >= 1; i-- {
+		if err := os.Rename(s.backupPath(i), s.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(s.Path, s.backupPath(1)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return s.openLocked()
+}
+
+func (s *RotatingFileAuditSink) backupPath(idx int) string {
+	return fmt.Sprintf("%s.%d", s.Path, idx)
+}
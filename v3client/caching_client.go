@@ -0,0 +1,844 @@
+package v3client
+
+import (
+	"context"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheKind Kind of the Mashery resource cached by the CachingClient
+type CacheKind string
+
+const (
+	CacheService              = CacheKind("service")
+	CacheEndpoint             = CacheKind("endpoint")
+	CacheEndpointMethod       = CacheKind("endpoint method")
+	CacheEndpointMethodFilter = CacheKind("endpoint method filter")
+	CachePackage              = CacheKind("package")
+	CachePlan                 = CacheKind("plan")
+	CachePlanService          = CacheKind("plan service")
+	CachePlanEndpoint         = CacheKind("plan endpoint")
+	CacheMember               = CacheKind("member")
+	CacheApplication          = CacheKind("application")
+	CachePackageKey           = CacheKind("package key")
+)
+
+// DefaultCacheTTL Time the objects are cached for where the CachingClientSettings don't specify it
+const DefaultCacheTTL = time.Minute
+
+const cacheBypassContextKey = contextKeyType("cache.bypass.v3.client.mashery")
+
+// WithCacheBypass Returns the context that makes the CachingClient read the objects from Mashery. The objects read
+// replace those in the cache.
+func WithCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassContextKey, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	v, ok := ctx.Value(cacheBypassContextKey).(bool)
+	return ok && v
+}
+
+// CachingClientSettings Settings of the CachingClient
+type CachingClientSettings struct {
+	// DefaultTTL applies to the kinds not listed in TTL. Zero selects DefaultCacheTTL.
+	DefaultTTL time.Duration
+	// TTL per resource kind. A zero or negative value disables caching of the kind.
+	TTL map[CacheKind]time.Duration
+}
+
+func (s CachingClientSettings) ttlOf(kind CacheKind) time.Duration {
+	if v, ok := s.TTL[kind]; ok {
+		return v
+	} else if s.DefaultTTL > 0 {
+		return s.DefaultTTL
+	}
+	return DefaultCacheTTL
+}
+
+// CacheKindStats Hits and misses of a single resource kind
+type CacheKindStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// CacheStats Statistics of the CachingClient
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Bypasses  uint64
+	Evictions uint64
+	Entries   int
+	Kinds     map[CacheKind]CacheKindStats
+}
+
+type cacheKey struct {
+	kind   CacheKind
+	op     string
+	ident  interface{}
+	params string
+}
+
+type cacheEntry struct {
+	value   interface{}
+	exists  bool
+	expires time.Time
+	// paths of the resources this entry was built from
+	paths []string
+}
+
+// CachingClient Client decorator caching the objects and lists read from Mashery. The entries are keyed on the
+// identifiers of the objects. The writes made through this client evict the entries along the identifier
+// hierarchy: e.g. updating an endpoint evicts the endpoint, its service, the endpoint listings and the plan service
+// listings that may include it.
+//
+// The cached objects are shared between the callers and must not be modified. Methods not related to the cached
+// kinds are passed to the wrapped client unchanged. Writes made with other clients are observed only after the
+// entries expire.
+type CachingClient struct {
+	Client
+	Settings CachingClientSettings
+
+	mutex      sync.Mutex
+	entries    map[cacheKey]*cacheEntry
+	generation uint64
+	stats      CacheStats
+}
+
+func NewCachingClient(delegate Client, settings CachingClientSettings) *CachingClient {
+	return &CachingClient{
+		Client:   delegate,
+		Settings: settings,
+		entries:  map[cacheKey]*cacheEntry{},
+		stats:    CacheStats{Kinds: map[CacheKind]CacheKindStats{}},
+	}
+}
+
+// Stats Returns the snapshot of the cache statistics
+func (cc *CachingClient) Stats() CacheStats {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	rv := cc.stats
+	rv.Entries = len(cc.entries)
+	rv.Kinds = make(map[CacheKind]CacheKindStats, len(cc.stats.Kinds))
+	for k, v := range cc.stats.Kinds {
+		rv.Kinds[k] = v
+	}
+	return rv
+}
+
+// Purge Evicts all entries
+func (cc *CachingClient) Purge() {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	cc.stats.Evictions += uint64(len(cc.entries))
+	cc.entries = map[cacheKey]*cacheEntry{}
+	cc.generation++
+}
+
+// lookup returns the unexpired entry for the key, and the generation the value fetched on a miss must be stored with.
+func (cc *CachingClient) lookup(ctx context.Context, key cacheKey) (*cacheEntry, uint64) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	if cacheBypassed(ctx) {
+		cc.stats.Bypasses++
+		return nil, cc.generation
+	}
+
+	kindStats := cc.stats.Kinds[key.kind]
+	defer func() {
+		cc.stats.Kinds[key.kind] = kindStats
+	}()
+
+	if e, ok := cc.entries[key]; ok {
+		if time.Now().Before(e.expires) {
+			cc.stats.Hits++
+			kindStats.Hits++
+			return e, cc.generation
+		}
+		delete(cc.entries, key)
+		cc.stats.Evictions++
+	}
+
+	cc.stats.Misses++
+	kindStats.Misses++
+	return nil, cc.generation
+}
+
+// store saves the entry unless a write was made since the value was looked up, as the value may then be stale.
+func (cc *CachingClient) store(key cacheKey, generation uint64, e *cacheEntry) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	if cc.generation == generation {
+		cc.entries[key] = e
+	}
+}
+
+// invalidate evicts the entries built from the resources that are ancestors or descendants of the written paths.
+func (cc *CachingClient) invalidate(written ...string) {
+	cc.mutex.Lock()
+	defer cc.mutex.Unlock()
+
+	cc.generation++
+	for k, e := range cc.entries {
+		if pathsRelated(e.paths, written) {
+			delete(cc.entries, k)
+			cc.stats.Evictions++
+		}
+	}
+}
+
+func pathsRelated(entryPaths, written []string) bool {
+	for _, p := range entryPaths {
+		for _, w := range written {
+			if p == w || strings.HasPrefix(w, p+"/") || strings.HasPrefix(p, w+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cacheParams serializes the query parameters and the fields requested via context that make up the key.
+func cacheParams(ctx context.Context, params map[string]string) string {
+	var parts []string
+	for k, v := range params {
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+
+	if fields, ok := ctx.Value(fieldsContextKey).([]string); ok {
+		parts = append(parts, "fields="+strings.Join(fields, ","))
+	}
+	return strings.Join(parts, "&")
+}
+
+func cachedObject[T any](ctx context.Context, cc *CachingClient, key cacheKey, paths []string, f func() (T, bool, error)) (T, bool, error) {
+	ttl := cc.Settings.ttlOf(key.kind)
+	if ttl <= 0 {
+		return f()
+	}
+
+	key.params = cacheParams(ctx, nil)
+	if e, generation := cc.lookup(ctx, key); e != nil {
+		return e.value.(T), e.exists, nil
+	} else {
+		rv, exists, err := f()
+		if err == nil {
+			cc.store(key, generation, &cacheEntry{value: rv, exists: exists, expires: time.Now().Add(ttl), paths: paths})
+		}
+		return rv, exists, err
+	}
+}
+
+func cachedList[T any](ctx context.Context, cc *CachingClient, key cacheKey, params map[string]string, paths []string, f func() ([]T, error)) ([]T, error) {
+	ttl := cc.Settings.ttlOf(key.kind)
+	if ttl <= 0 {
+		return f()
+	}
+
+	key.params = cacheParams(ctx, params)
+	if e, generation := cc.lookup(ctx, key); e != nil {
+		return copyOf(e.value.([]T)), nil
+	} else {
+		rv, err := f()
+		if err == nil {
+			cc.store(key, generation, &cacheEntry{value: copyOf(rv), exists: true, expires: time.Now().Add(ttl), paths: paths})
+		}
+		return rv, err
+	}
+}
+
+func copyOf[T any](src []T) []T {
+	if src == nil {
+		return nil
+	}
+	rv := make([]T, len(src))
+	copy(rv, src)
+	return rv
+}
+
+// invalidateAfter evicts the entries related to the paths after the write completes, whether it succeeded or not.
+func invalidateAfter[T any](cc *CachingClient, rv T, err error, paths ...string) (T, error) {
+	cc.invalidate(paths...)
+	return rv, err
+}
+
+// -----------------------------------------------------------------------------------------------------------------
+// Resource paths identifying the cached objects in the invalidation hierarchy
+
+const (
+	servicesPath     = "/services"
+	packagesPath     = "/packages"
+	membersPath      = "/members"
+	applicationsPath = "/applications"
+	packageKeysPath  = "/packageKeys"
+)
+
+func servicePath(id masherytypes.ServiceIdentifier) string {
+	return servicesPath + "/" + id.ServiceId
+}
+
+func endpointPath(id masherytypes.ServiceEndpointIdentifier) string {
+	return servicePath(id.ServiceIdentifier) + "/endpoints/" + id.EndpointId
+}
+
+func endpointMethodPath(id masherytypes.ServiceEndpointMethodIdentifier) string {
+	return endpointPath(id.ServiceEndpointIdentifier) + "/methods/" + id.MethodId
+}
+
+func endpointMethodFilterPath(id masherytypes.ServiceEndpointMethodFilterIdentifier) string {
+	return endpointMethodPath(id.ServiceEndpointMethodIdentifier) + "/responseFilters/" + id.FilterId
+}
+
+func errorSetPath(id masherytypes.ErrorSetIdentifier) string {
+	return servicePath(id.ServiceIdentifier) + "/errorSets/" + id.ErrorSetId
+}
+
+func packagePath(id masherytypes.PackageIdentifier) string {
+	return packagesPath + "/" + id.PackageId
+}
+
+func planPath(id masherytypes.PackagePlanIdentifier) string {
+	return packagePath(id.PackageIdentifier) + "/plans/" + id.PlanId
+}
+
+func planServicePath(id masherytypes.PackagePlanServiceIdentifier) string {
+	return planPath(id.PackagePlanIdentifier) + "/services/" + id.ServiceId
+}
+
+func planEndpointPath(id masherytypes.PackagePlanServiceEndpointIdentifier) string {
+	return planPath(id.PackagePlanIdentifier) + "/services/" + id.ServiceId + "/endpoints/" + id.EndpointId
+}
+
+func planMethodPath(id masherytypes.PackagePlanServiceEndpointMethodIdentifier) string {
+	return planEndpointPath(id.GetPackagePlanServiceEndpointIdentifier()) + "/methods/" + id.MethodId
+}
+
+func memberPath(id masherytypes.MemberIdentifier) string {
+	return membersPath + "/" + id.MemberId
+}
+
+func applicationPath(id masherytypes.ApplicationIdentifier) string {
+	return applicationsPath + "/" + id.ApplicationId
+}
+
+func packageKeyPath(id masherytypes.PackageKeyIdentifier) string {
+	return packageKeysPath + "/" + id.PackageKeyId
+}
+
+func applicationPackageKeyPath(id masherytypes.ApplicationPackageKeyIdentifier) string {
+	return applicationPath(id.ApplicationIdentifier) + "/packageKeys/" + id.PackageKeyId
+}
+
+// createdPath returns the path of the created object, or the path of its parent collection where the create failed.
+func createdPath(parent string, id string, err error) string {
+	if err != nil || len(id) == 0 {
+		return parent
+	}
+	return parent + "/" + id
+}
+
+// -----------------------------------------------------------------------------------------------------------------
+// Services
+
+func (cc *CachingClient) GetService(ctx context.Context, id masherytypes.ServiceIdentifier) (masherytypes.Service, bool, error) {
+	return cachedObject(ctx, cc, cacheKey{kind: CacheService, op: "get", ident: id}, []string{servicePath(id)}, func() (masherytypes.Service, bool, error) {
+		return cc.Client.GetService(ctx, id)
+	})
+}
+
+func (cc *CachingClient) ListServices(ctx context.Context) ([]masherytypes.Service, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CacheService, op: "list"}, nil, []string{servicesPath}, func() ([]masherytypes.Service, error) {
+		return cc.Client.ListServices(ctx)
+	})
+}
+
+func (cc *CachingClient) ListServicesFiltered(ctx context.Context, params map[string]string) ([]masherytypes.Service, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CacheService, op: "list filtered"}, params, []string{servicesPath}, func() ([]masherytypes.Service, error) {
+		return cc.Client.ListServicesFiltered(ctx, params)
+	})
+}
+
+func (cc *CachingClient) CreateService(ctx context.Context, service masherytypes.Service) (masherytypes.Service, error) {
+	rv, err := cc.Client.CreateService(ctx, service)
+	return invalidateAfter(cc, rv, err, createdPath(servicesPath, rv.Id, err))
+}
+
+func (cc *CachingClient) UpdateService(ctx context.Context, service masherytypes.Service) (masherytypes.Service, error) {
+	rv, err := cc.Client.UpdateService(ctx, service)
+	return invalidateAfter(cc, rv, err, servicePath(service.Identifier()))
+}
+
+func (cc *CachingClient) DeleteService(ctx context.Context, serviceId masherytypes.ServiceIdentifier) error {
+	err := cc.Client.DeleteService(ctx, serviceId)
+	cc.invalidate(servicePath(serviceId))
+	return err
+}
+
+func (cc *CachingClient) CreateErrorSet(ctx context.Context, serviceId masherytypes.ServiceIdentifier, set masherytypes.ErrorSet) (masherytypes.ErrorSet, error) {
+	rv, err := cc.Client.CreateErrorSet(ctx, serviceId, set)
+	return invalidateAfter(cc, rv, err, servicePath(serviceId)+"/errorSets")
+}
+
+func (cc *CachingClient) UpdateErrorSet(ctx context.Context, setData masherytypes.ErrorSet) (masherytypes.ErrorSet, error) {
+	rv, err := cc.Client.UpdateErrorSet(ctx, setData)
+	return invalidateAfter(cc, rv, err, errorSetPath(setData.Identifier()))
+}
+
+func (cc *CachingClient) DeleteErrorSet(ctx context.Context, ident masherytypes.ErrorSetIdentifier) error {
+	err := cc.Client.DeleteErrorSet(ctx, ident)
+	cc.invalidate(errorSetPath(ident))
+	return err
+}
+
+func (cc *CachingClient) UpdateErrorSetMessage(ctx context.Context, msg masherytypes.MasheryErrorMessage) (masherytypes.MasheryErrorMessage, error) {
+	rv, err := cc.Client.UpdateErrorSetMessage(ctx, msg)
+	return invalidateAfter(cc, rv, err, errorSetPath(msg.ParentErrorSet))
+}
+
+func (cc *CachingClient) SetServiceRoles(ctx context.Context, id masherytypes.ServiceIdentifier, roles []masherytypes.RolePermission) error {
+	err := cc.Client.SetServiceRoles(ctx, id, roles)
+	cc.invalidate(servicePath(id) + "/roles")
+	return err
+}
+
+func (cc *CachingClient) DeleteServiceRoles(ctx context.Context, id masherytypes.ServiceIdentifier) error {
+	err := cc.Client.DeleteServiceRoles(ctx, id)
+	cc.invalidate(servicePath(id) + "/roles")
+	return err
+}
+
+func (cc *CachingClient) CreateServiceCache(ctx context.Context, id masherytypes.ServiceIdentifier, service masherytypes.ServiceCache) (masherytypes.ServiceCache, error) {
+	rv, err := cc.Client.CreateServiceCache(ctx, id, service)
+	return invalidateAfter(cc, rv, err, servicePath(id)+"/cache")
+}
+
+func (cc *CachingClient) UpdateServiceCache(ctx context.Context, service masherytypes.ServiceCache) (masherytypes.ServiceCache, error) {
+	rv, err := cc.Client.UpdateServiceCache(ctx, service)
+	return invalidateAfter(cc, rv, err, servicePath(service.ParentServiceId)+"/cache")
+}
+
+func (cc *CachingClient) DeleteServiceCache(ctx context.Context, id masherytypes.ServiceIdentifier) error {
+	err := cc.Client.DeleteServiceCache(ctx, id)
+	cc.invalidate(servicePath(id) + "/cache")
+	return err
+}
+
+func (cc *CachingClient) CreateServiceOAuthSecurityProfile(ctx context.Context, id masherytypes.ServiceIdentifier, service masherytypes.MasheryOAuth) (masherytypes.MasheryOAuth, error) {
+	rv, err := cc.Client.CreateServiceOAuthSecurityProfile(ctx, id, service)
+	return invalidateAfter(cc, rv, err, servicePath(id)+"/securityProfile")
+}
+
+func (cc *CachingClient) UpdateServiceOAuthSecurityProfile(ctx context.Context, service masherytypes.MasheryOAuth) (masherytypes.MasheryOAuth, error) {
+	rv, err := cc.Client.UpdateServiceOAuthSecurityProfile(ctx, service)
+	return invalidateAfter(cc, rv, err, servicePath(service.ParentService)+"/securityProfile")
+}
+
+func (cc *CachingClient) DeleteServiceOAuthSecurityProfile(ctx context.Context, id masherytypes.ServiceIdentifier) error {
+	err := cc.Client.DeleteServiceOAuthSecurityProfile(ctx, id)
+	cc.invalidate(servicePath(id) + "/securityProfile")
+	return err
+}
+
+// -----------------------------------------------------------------------------------------------------------------
+// Endpoints, methods and response filters
+
+func (cc *CachingClient) GetEndpoint(ctx context.Context, ident masherytypes.ServiceEndpointIdentifier) (masherytypes.Endpoint, bool, error) {
+	return cachedObject(ctx, cc, cacheKey{kind: CacheEndpoint, op: "get", ident: ident}, []string{endpointPath(ident)}, func() (masherytypes.Endpoint, bool, error) {
+		return cc.Client.GetEndpoint(ctx, ident)
+	})
+}
+
+func (cc *CachingClient) ListEndpoints(ctx context.Context, serviceId masherytypes.ServiceIdentifier) ([]masherytypes.AddressableV3Object, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CacheEndpoint, op: "list", ident: serviceId}, nil, []string{servicePath(serviceId) + "/endpoints"}, func() ([]masherytypes.AddressableV3Object, error) {
+		return cc.Client.ListEndpoints(ctx, serviceId)
+	})
+}
+
+func (cc *CachingClient) ListEndpointsWithFullInfo(ctx context.Context, serviceId masherytypes.ServiceIdentifier) ([]masherytypes.Endpoint, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CacheEndpoint, op: "list full", ident: serviceId}, nil, []string{servicePath(serviceId) + "/endpoints"}, func() ([]masherytypes.Endpoint, error) {
+		return cc.Client.ListEndpointsWithFullInfo(ctx, serviceId)
+	})
+}
+
+func (cc *CachingClient) CreateEndpoint(ctx context.Context, serviceId masherytypes.ServiceIdentifier, endp masherytypes.Endpoint) (masherytypes.Endpoint, error) {
+	rv, err := cc.Client.CreateEndpoint(ctx, serviceId, endp)
+	return invalidateAfter(cc, rv, err, createdPath(servicePath(serviceId)+"/endpoints", rv.Id, err))
+}
+
+func (cc *CachingClient) UpdateEndpoint(ctx context.Context, endp masherytypes.Endpoint) (masherytypes.Endpoint, error) {
+	rv, err := cc.Client.UpdateEndpoint(ctx, endp)
+	return invalidateAfter(cc, rv, err, endpointPath(endp.Identifier()))
+}
+
+func (cc *CachingClient) DeleteEndpoint(ctx context.Context, ident masherytypes.ServiceEndpointIdentifier) error {
+	err := cc.Client.DeleteEndpoint(ctx, ident)
+	cc.invalidate(endpointPath(ident))
+	return err
+}
+
+func (cc *CachingClient) GetEndpointMethod(ctx context.Context, ident masherytypes.ServiceEndpointMethodIdentifier) (masherytypes.ServiceEndpointMethod, bool, error) {
+	return cachedObject(ctx, cc, cacheKey{kind: CacheEndpointMethod, op: "get", ident: ident}, []string{endpointMethodPath(ident)}, func() (masherytypes.ServiceEndpointMethod, bool, error) {
+		return cc.Client.GetEndpointMethod(ctx, ident)
+	})
+}
+
+func (cc *CachingClient) ListEndpointMethods(ctx context.Context, ident masherytypes.ServiceEndpointIdentifier) ([]masherytypes.AddressableV3Object, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CacheEndpointMethod, op: "list", ident: ident}, nil, []string{endpointPath(ident) + "/methods"}, func() ([]masherytypes.AddressableV3Object, error) {
+		return cc.Client.ListEndpointMethods(ctx, ident)
+	})
+}
+
+func (cc *CachingClient) ListEndpointMethodsWithFullInfo(ctx context.Context, ident masherytypes.ServiceEndpointIdentifier) ([]masherytypes.ServiceEndpointMethod, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CacheEndpointMethod, op: "list full", ident: ident}, nil, []string{endpointPath(ident) + "/methods"}, func() ([]masherytypes.ServiceEndpointMethod, error) {
+		return cc.Client.ListEndpointMethodsWithFullInfo(ctx, ident)
+	})
+}
+
+func (cc *CachingClient) CreateEndpointMethod(ctx context.Context, ident masherytypes.ServiceEndpointIdentifier, methodUpsert masherytypes.ServiceEndpointMethod) (masherytypes.ServiceEndpointMethod, error) {
+	rv, err := cc.Client.CreateEndpointMethod(ctx, ident, methodUpsert)
+	return invalidateAfter(cc, rv, err, createdPath(endpointPath(ident)+"/methods", rv.Id, err))
+}
+
+func (cc *CachingClient) UpdateEndpointMethod(ctx context.Context, methUpsert masherytypes.ServiceEndpointMethod) (masherytypes.ServiceEndpointMethod, error) {
+	rv, err := cc.Client.UpdateEndpointMethod(ctx, methUpsert)
+	return invalidateAfter(cc, rv, err, endpointMethodPath(methUpsert.Identifier()))
+}
+
+func (cc *CachingClient) DeleteEndpointMethod(ctx context.Context, ident masherytypes.ServiceEndpointMethodIdentifier) error {
+	err := cc.Client.DeleteEndpointMethod(ctx, ident)
+	cc.invalidate(endpointMethodPath(ident))
+	return err
+}
+
+func (cc *CachingClient) GetEndpointMethodFilter(ctx context.Context, ident masherytypes.ServiceEndpointMethodFilterIdentifier) (masherytypes.ServiceEndpointMethodFilter, bool, error) {
+	return cachedObject(ctx, cc, cacheKey{kind: CacheEndpointMethodFilter, op: "get", ident: ident}, []string{endpointMethodFilterPath(ident)}, func() (masherytypes.ServiceEndpointMethodFilter, bool, error) {
+		return cc.Client.GetEndpointMethodFilter(ctx, ident)
+	})
+}
+
+func (cc *CachingClient) ListEndpointMethodFilters(ctx context.Context, ident masherytypes.ServiceEndpointMethodIdentifier) ([]masherytypes.AddressableV3Object, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CacheEndpointMethodFilter, op: "list", ident: ident}, nil, []string{endpointMethodPath(ident) + "/responseFilters"}, func() ([]masherytypes.AddressableV3Object, error) {
+		return cc.Client.ListEndpointMethodFilters(ctx, ident)
+	})
+}
+
+func (cc *CachingClient) ListEndpointMethodFiltersWithFullInfo(ctx context.Context, ident masherytypes.ServiceEndpointMethodIdentifier) ([]masherytypes.ServiceEndpointMethodFilter, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CacheEndpointMethodFilter, op: "list full", ident: ident}, nil, []string{endpointMethodPath(ident) + "/responseFilters"}, func() ([]masherytypes.ServiceEndpointMethodFilter, error) {
+		return cc.Client.ListEndpointMethodFiltersWithFullInfo(ctx, ident)
+	})
+}
+
+func (cc *CachingClient) CreateEndpointMethodFilter(ctx context.Context, ident masherytypes.ServiceEndpointMethodIdentifier, filterUpsert masherytypes.ServiceEndpointMethodFilter) (masherytypes.ServiceEndpointMethodFilter, error) {
+	rv, err := cc.Client.CreateEndpointMethodFilter(ctx, ident, filterUpsert)
+	return invalidateAfter(cc, rv, err, createdPath(endpointMethodPath(ident)+"/responseFilters", rv.Id, err))
+}
+
+func (cc *CachingClient) UpdateEndpointMethodFilter(ctx context.Context, methUpsert masherytypes.ServiceEndpointMethodFilter) (masherytypes.ServiceEndpointMethodFilter, error) {
+	rv, err := cc.Client.UpdateEndpointMethodFilter(ctx, methUpsert)
+	return invalidateAfter(cc, rv, err, endpointMethodFilterPath(methUpsert.Identifier()))
+}
+
+func (cc *CachingClient) DeleteEndpointMethodFilter(ctx context.Context, ident masherytypes.ServiceEndpointMethodFilterIdentifier) error {
+	err := cc.Client.DeleteEndpointMethodFilter(ctx, ident)
+	cc.invalidate(endpointMethodFilterPath(ident))
+	return err
+}
+
+// -----------------------------------------------------------------------------------------------------------------
+// Packages, plans, plan services and plan endpoints. Plans may include the services, hence the plan entries
+// depend on the services, too.
+
+func (cc *CachingClient) GetPackage(ctx context.Context, id masherytypes.PackageIdentifier) (masherytypes.Package, bool, error) {
+	return cachedObject(ctx, cc, cacheKey{kind: CachePackage, op: "get", ident: id}, []string{packagePath(id), servicesPath}, func() (masherytypes.Package, bool, error) {
+		return cc.Client.GetPackage(ctx, id)
+	})
+}
+
+func (cc *CachingClient) ListPackages(ctx context.Context) ([]masherytypes.Package, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CachePackage, op: "list"}, nil, []string{packagesPath, servicesPath}, func() ([]masherytypes.Package, error) {
+		return cc.Client.ListPackages(ctx)
+	})
+}
+
+func (cc *CachingClient) ListPackagesFiltered(ctx context.Context, params map[string]string) ([]masherytypes.Package, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CachePackage, op: "list filtered"}, params, []string{packagesPath, servicesPath}, func() ([]masherytypes.Package, error) {
+		return cc.Client.ListPackagesFiltered(ctx, params)
+	})
+}
+
+func (cc *CachingClient) CreatePackage(ctx context.Context, pack masherytypes.Package) (masherytypes.Package, error) {
+	rv, err := cc.Client.CreatePackage(ctx, pack)
+	return invalidateAfter(cc, rv, err, createdPath(packagesPath, rv.Id, err))
+}
+
+func (cc *CachingClient) UpdatePackage(ctx context.Context, pack masherytypes.Package) (masherytypes.Package, error) {
+	rv, err := cc.Client.UpdatePackage(ctx, pack)
+	return invalidateAfter(cc, rv, err, packagePath(pack.Identifier()))
+}
+
+func (cc *CachingClient) ResetPackageOwnership(ctx context.Context, pack masherytypes.PackageIdentifier) (masherytypes.Package, error) {
+	rv, err := cc.Client.ResetPackageOwnership(ctx, pack)
+	return invalidateAfter(cc, rv, err, packagePath(pack))
+}
+
+// DeletePackage Deletes the package. The package keys provisioned for the package are deleted with it.
+func (cc *CachingClient) DeletePackage(ctx context.Context, packId masherytypes.PackageIdentifier) error {
+	err := cc.Client.DeletePackage(ctx, packId)
+	cc.invalidate(packagePath(packId), packageKeysPath, applicationsPath)
+	return err
+}
+
+func (cc *CachingClient) GetPlan(ctx context.Context, ident masherytypes.PackagePlanIdentifier) (masherytypes.Plan, bool, error) {
+	return cachedObject(ctx, cc, cacheKey{kind: CachePlan, op: "get", ident: ident}, []string{planPath(ident), servicesPath}, func() (masherytypes.Plan, bool, error) {
+		return cc.Client.GetPlan(ctx, ident)
+	})
+}
+
+func (cc *CachingClient) ListPlans(ctx context.Context, packageId masherytypes.PackageIdentifier) ([]masherytypes.Plan, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CachePlan, op: "list", ident: packageId}, nil, []string{packagePath(packageId) + "/plans", servicesPath}, func() ([]masherytypes.Plan, error) {
+		return cc.Client.ListPlans(ctx, packageId)
+	})
+}
+
+func (cc *CachingClient) ListPlansFiltered(ctx context.Context, packageId masherytypes.PackageIdentifier, params map[string]string) ([]masherytypes.Plan, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CachePlan, op: "list filtered", ident: packageId}, params, []string{packagePath(packageId) + "/plans", servicesPath}, func() ([]masherytypes.Plan, error) {
+		return cc.Client.ListPlansFiltered(ctx, packageId, params)
+	})
+}
+
+func (cc *CachingClient) CreatePlan(ctx context.Context, packageId masherytypes.PackageIdentifier, plan masherytypes.Plan) (masherytypes.Plan, error) {
+	rv, err := cc.Client.CreatePlan(ctx, packageId, plan)
+	return invalidateAfter(cc, rv, err, createdPath(packagePath(packageId)+"/plans", rv.Id, err))
+}
+
+func (cc *CachingClient) UpdatePlan(ctx context.Context, plan masherytypes.Plan) (masherytypes.Plan, error) {
+	rv, err := cc.Client.UpdatePlan(ctx, plan)
+	return invalidateAfter(cc, rv, err, planPath(plan.Identifier()))
+}
+
+// DeletePlan Deletes the plan. The package keys provisioned for the plan are deleted with it.
+func (cc *CachingClient) DeletePlan(ctx context.Context, ident masherytypes.PackagePlanIdentifier) error {
+	err := cc.Client.DeletePlan(ctx, ident)
+	cc.invalidate(planPath(ident), packageKeysPath, applicationsPath)
+	return err
+}
+
+func (cc *CachingClient) ListPlanServices(ctx context.Context, ident masherytypes.PackagePlanIdentifier) ([]masherytypes.Service, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CachePlanService, op: "list", ident: ident}, nil, []string{planPath(ident) + "/services", servicesPath}, func() ([]masherytypes.Service, error) {
+		return cc.Client.ListPlanServices(ctx, ident)
+	})
+}
+
+func (cc *CachingClient) CreatePlanService(ctx context.Context, planService masherytypes.PackagePlanServiceIdentifier) (masherytypes.AddressableV3Object, error) {
+	rv, err := cc.Client.CreatePlanService(ctx, planService)
+	return invalidateAfter(cc, rv, err, planServicePath(planService))
+}
+
+func (cc *CachingClient) DeletePlanService(ctx context.Context, planService masherytypes.PackagePlanServiceIdentifier) error {
+	err := cc.Client.DeletePlanService(ctx, planService)
+	cc.invalidate(planServicePath(planService))
+	return err
+}
+
+func (cc *CachingClient) ListPlanEndpoints(ctx context.Context, planService masherytypes.PackagePlanServiceIdentifier) ([]masherytypes.AddressableV3Object, error) {
+	paths := []string{planServicePath(planService) + "/endpoints", servicePath(planService.ServiceIdentifier) + "/endpoints"}
+	return cachedList(ctx, cc, cacheKey{kind: CachePlanEndpoint, op: "list", ident: planService}, nil, paths, func() ([]masherytypes.AddressableV3Object, error) {
+		return cc.Client.ListPlanEndpoints(ctx, planService)
+	})
+}
+
+func (cc *CachingClient) CreatePlanEndpoint(ctx context.Context, planEndp masherytypes.PackagePlanServiceEndpointIdentifier) (masherytypes.AddressableV3Object, error) {
+	rv, err := cc.Client.CreatePlanEndpoint(ctx, planEndp)
+	return invalidateAfter(cc, rv, err, planEndpointPath(planEndp))
+}
+
+func (cc *CachingClient) DeletePlanEndpoint(ctx context.Context, planEndp masherytypes.PackagePlanServiceEndpointIdentifier) error {
+	err := cc.Client.DeletePlanEndpoint(ctx, planEndp)
+	cc.invalidate(planEndpointPath(planEndp))
+	return err
+}
+
+func (cc *CachingClient) CreatePackagePlanMethod(ctx context.Context, id masherytypes.PackagePlanServiceEndpointMethodIdentifier) (masherytypes.PackagePlanServiceEndpointMethod, error) {
+	rv, err := cc.Client.CreatePackagePlanMethod(ctx, id)
+	return invalidateAfter(cc, rv, err, planMethodPath(id))
+}
+
+func (cc *CachingClient) DeletePackagePlanMethod(ctx context.Context, id masherytypes.PackagePlanServiceEndpointMethodIdentifier) error {
+	err := cc.Client.DeletePackagePlanMethod(ctx, id)
+	cc.invalidate(planMethodPath(id))
+	return err
+}
+
+func (cc *CachingClient) CreatePackagePlanMethodFilter(ctx context.Context, id masherytypes.PackagePlanServiceEndpointMethodFilterIdentifier) (masherytypes.PackagePlanServiceEndpointMethodFilter, error) {
+	rv, err := cc.Client.CreatePackagePlanMethodFilter(ctx, id)
+	return invalidateAfter(cc, rv, err, planMethodPath(id.AsPackagePlanServiceEndpointMethodIdentifier()))
+}
+
+func (cc *CachingClient) DeletePackagePlanMethodFilter(ctx context.Context, id masherytypes.PackagePlanServiceEndpointMethodIdentifier) error {
+	err := cc.Client.DeletePackagePlanMethodFilter(ctx, id)
+	cc.invalidate(planMethodPath(id))
+	return err
+}
+
+// -----------------------------------------------------------------------------------------------------------------
+// Members, applications and package keys
+
+func (cc *CachingClient) GetMember(ctx context.Context, id masherytypes.MemberIdentifier) (masherytypes.Member, bool, error) {
+	return cachedObject(ctx, cc, cacheKey{kind: CacheMember, op: "get", ident: id}, []string{memberPath(id)}, func() (masherytypes.Member, bool, error) {
+		return cc.Client.GetMember(ctx, id)
+	})
+}
+
+func (cc *CachingClient) GetFullMember(ctx context.Context, id masherytypes.MemberIdentifier) (masherytypes.Member, bool, error) {
+	return cachedObject(ctx, cc, cacheKey{kind: CacheMember, op: "get full", ident: id}, []string{memberPath(id), applicationsPath}, func() (masherytypes.Member, bool, error) {
+		return cc.Client.GetFullMember(ctx, id)
+	})
+}
+
+func (cc *CachingClient) ListMembers(ctx context.Context) ([]masherytypes.Member, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CacheMember, op: "list"}, nil, []string{membersPath}, func() ([]masherytypes.Member, error) {
+		return cc.Client.ListMembers(ctx)
+	})
+}
+
+func (cc *CachingClient) ListMembersFiltered(ctx context.Context, params map[string]string) ([]masherytypes.Member, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CacheMember, op: "list filtered"}, params, []string{membersPath}, func() ([]masherytypes.Member, error) {
+		return cc.Client.ListMembersFiltered(ctx, params)
+	})
+}
+
+func (cc *CachingClient) CreateMember(ctx context.Context, member masherytypes.Member) (masherytypes.Member, error) {
+	rv, err := cc.Client.CreateMember(ctx, member)
+	return invalidateAfter(cc, rv, err, createdPath(membersPath, rv.Id, err))
+}
+
+func (cc *CachingClient) UpdateMember(ctx context.Context, member masherytypes.Member) (masherytypes.Member, error) {
+	rv, err := cc.Client.UpdateMember(ctx, member)
+	return invalidateAfter(cc, rv, err, memberPath(member.Identifier()))
+}
+
+// DeleteMember Deletes the member. The applications and the package keys of the member are deleted with it.
+func (cc *CachingClient) DeleteMember(ctx context.Context, memberId masherytypes.MemberIdentifier) error {
+	err := cc.Client.DeleteMember(ctx, memberId)
+	cc.invalidate(memberPath(memberId), applicationsPath, packageKeysPath)
+	return err
+}
+
+func (cc *CachingClient) GetApplication(ctx context.Context, appId masherytypes.ApplicationIdentifier) (masherytypes.Application, bool, error) {
+	return cachedObject(ctx, cc, cacheKey{kind: CacheApplication, op: "get", ident: appId}, []string{applicationPath(appId)}, func() (masherytypes.Application, bool, error) {
+		return cc.Client.GetApplication(ctx, appId)
+	})
+}
+
+func (cc *CachingClient) GetFullApplication(ctx context.Context, id masherytypes.ApplicationIdentifier) (masherytypes.Application, bool, error) {
+	return cachedObject(ctx, cc, cacheKey{kind: CacheApplication, op: "get full", ident: id}, []string{applicationPath(id), packageKeysPath}, func() (masherytypes.Application, bool, error) {
+		return cc.Client.GetFullApplication(ctx, id)
+	})
+}
+
+func (cc *CachingClient) ListApplications(ctx context.Context) ([]masherytypes.Application, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CacheApplication, op: "list"}, nil, []string{applicationsPath}, func() ([]masherytypes.Application, error) {
+		return cc.Client.ListApplications(ctx)
+	})
+}
+
+func (cc *CachingClient) ListApplicationsFiltered(ctx context.Context, p map[string]string) ([]masherytypes.Application, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CacheApplication, op: "list filtered"}, p, []string{applicationsPath}, func() ([]masherytypes.Application, error) {
+		return cc.Client.ListApplicationsFiltered(ctx, p)
+	})
+}
+
+func (cc *CachingClient) CreateApplication(ctx context.Context, memberId masherytypes.MemberIdentifier, app masherytypes.Application) (masherytypes.Application, error) {
+	rv, err := cc.Client.CreateApplication(ctx, memberId, app)
+	return invalidateAfter(cc, rv, err, memberPath(memberId), createdPath(applicationsPath, rv.Id, err))
+}
+
+func (cc *CachingClient) UpdateApplication(ctx context.Context, app masherytypes.Application) (masherytypes.Application, error) {
+	rv, err := cc.Client.UpdateApplication(ctx, app)
+	return invalidateAfter(cc, rv, err, applicationPath(app.Identifier()))
+}
+
+func (cc *CachingClient) UpdateApplicationExtendedAttributes(ctx context.Context, appId masherytypes.ApplicationIdentifier, params map[string]string) (map[string]string, error) {
+	rv, err := cc.Client.UpdateApplicationExtendedAttributes(ctx, appId, params)
+	return invalidateAfter(cc, rv, err, applicationPath(appId))
+}
+
+// DeleteApplication Deletes the application. The package keys of the application are deleted with it.
+func (cc *CachingClient) DeleteApplication(ctx context.Context, appId masherytypes.ApplicationIdentifier) error {
+	err := cc.Client.DeleteApplication(ctx, appId)
+	cc.invalidate(applicationPath(appId), membersPath, packageKeysPath)
+	return err
+}
+
+func (cc *CachingClient) GetApplicationPackageKeys(ctx context.Context, appId masherytypes.ApplicationIdentifier) ([]masherytypes.ApplicationPackageKey, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CachePackageKey, op: "list of application", ident: appId}, nil, []string{applicationPath(appId) + "/packageKeys", packageKeysPath}, func() ([]masherytypes.ApplicationPackageKey, error) {
+		return cc.Client.GetApplicationPackageKeys(ctx, appId)
+	})
+}
+
+func (cc *CachingClient) GetApplicationPackageKey(ctx context.Context, id masherytypes.ApplicationPackageKeyIdentifier) (masherytypes.ApplicationPackageKey, bool, error) {
+	paths := []string{applicationPackageKeyPath(id), packageKeyPath(id.PackageKeyIdentifier)}
+	return cachedObject(ctx, cc, cacheKey{kind: CachePackageKey, op: "get of application", ident: id}, paths, func() (masherytypes.ApplicationPackageKey, bool, error) {
+		return cc.Client.GetApplicationPackageKey(ctx, id)
+	})
+}
+
+func (cc *CachingClient) CreateApplicationPackageKey(ctx context.Context, appId masherytypes.ApplicationIdentifier, packageKey masherytypes.ApplicationPackageKey) (masherytypes.ApplicationPackageKey, error) {
+	rv, err := cc.Client.CreateApplicationPackageKey(ctx, appId, packageKey)
+	return invalidateAfter(cc, rv, err, createdPath(applicationPath(appId)+"/packageKeys", rv.Id, err), createdPath(packageKeysPath, rv.Id, err))
+}
+
+func (cc *CachingClient) UpdateApplicationPackageKey(ctx context.Context, packageKey masherytypes.ApplicationPackageKey) (masherytypes.ApplicationPackageKey, error) {
+	rv, err := cc.Client.UpdateApplicationPackageKey(ctx, packageKey)
+	id := packageKey.Identifier()
+	return invalidateAfter(cc, rv, err, applicationPackageKeyPath(id), packageKeyPath(id.PackageKeyIdentifier))
+}
+
+func (cc *CachingClient) DeleteApplicationPackageKey(ctx context.Context, keyId masherytypes.ApplicationPackageKeyIdentifier) error {
+	err := cc.Client.DeleteApplicationPackageKey(ctx, keyId)
+	cc.invalidate(applicationPackageKeyPath(keyId), packageKeyPath(keyId.PackageKeyIdentifier))
+	return err
+}
+
+func (cc *CachingClient) GetPackageKey(ctx context.Context, id masherytypes.PackageKeyIdentifier) (masherytypes.PackageKey, bool, error) {
+	return cachedObject(ctx, cc, cacheKey{kind: CachePackageKey, op: "get", ident: id}, []string{packageKeyPath(id)}, func() (masherytypes.PackageKey, bool, error) {
+		return cc.Client.GetPackageKey(ctx, id)
+	})
+}
+
+func (cc *CachingClient) ListPackageKeys(ctx context.Context) ([]masherytypes.PackageKey, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CachePackageKey, op: "list"}, nil, []string{packageKeysPath}, func() ([]masherytypes.PackageKey, error) {
+		return cc.Client.ListPackageKeys(ctx)
+	})
+}
+
+func (cc *CachingClient) ListPackageKeysFiltered(ctx context.Context, params map[string]string) ([]masherytypes.PackageKey, error) {
+	return cachedList(ctx, cc, cacheKey{kind: CachePackageKey, op: "list filtered"}, params, []string{packageKeysPath}, func() ([]masherytypes.PackageKey, error) {
+		return cc.Client.ListPackageKeysFiltered(ctx, params)
+	})
+}
+
+func (cc *CachingClient) CreatePackageKey(ctx context.Context, packageKey masherytypes.PackageKey) (masherytypes.PackageKey, error) {
+	rv, err := cc.Client.CreatePackageKey(ctx, packageKey)
+	return invalidateAfter(cc, rv, err, createdPath(packageKeysPath, rv.Id, err))
+}
+
+func (cc *CachingClient) UpdatePackageKey(ctx context.Context, packageKey masherytypes.PackageKey) (masherytypes.PackageKey, error) {
+	rv, err := cc.Client.UpdatePackageKey(ctx, packageKey)
+	return invalidateAfter(cc, rv, err, packageKeyPath(packageKey.Identifier()))
+}
+
+func (cc *CachingClient) DeletePackageKey(ctx context.Context, keyId masherytypes.PackageKeyIdentifier) error {
+	err := cc.Client.DeletePackageKey(ctx, keyId)
+	cc.invalidate(packageKeyPath(keyId))
+	return err
+}
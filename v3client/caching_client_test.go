@@ -0,0 +1,133 @@
+package v3client_test
+
+import (
+	"context"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3simulator"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func cachingClientOf(sim *v3simulator.Simulator, settings v3client.CachingClientSettings) (*v3client.CachingClient, *int32) {
+	var gets int32
+	sim.Interceptor = func(r *http.Request) *http.Response {
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(&gets, 1)
+		}
+		return nil
+	}
+
+	cl := v3simulator.NewClient(sim)
+
+	return v3client.NewCachingClient(cl, settings), &gets
+}
+
+func TestCachingClientServesRepeatedReads(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	svcId, _ := sim.Create("/services", masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	cc, gets := cachingClientOf(sim, v3client.CachingClientSettings{})
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		svc, exists, err := cc.GetService(ctx, masherytypes.ServiceIdentityFrom(svcId))
+		assert.Nil(t, err)
+		assert.True(t, exists)
+		assert.Equal(t, "svc", svc.Name)
+	}
+
+	_, exists, err := cc.GetService(ctx, masherytypes.ServiceIdentityFrom("missing"))
+	assert.Nil(t, err)
+	assert.False(t, exists)
+	_, exists, _ = cc.GetService(ctx, masherytypes.ServiceIdentityFrom("missing"))
+	assert.False(t, exists)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(gets))
+
+	stats := cc.Stats()
+	assert.Equal(t, uint64(3), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, v3client.CacheKindStats{Hits: 3, Misses: 2}, stats.Kinds[v3client.CacheService])
+
+	_, _, _ = cc.GetService(v3client.WithCacheBypass(ctx), masherytypes.ServiceIdentityFrom(svcId))
+	assert.Equal(t, int32(3), atomic.LoadInt32(gets))
+	assert.Equal(t, uint64(1), cc.Stats().Bypasses)
+}
+
+func TestCachingClientKeysOnParametersAndFields(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	_, _ = sim.Create("/services", masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	cc, gets := cachingClientOf(sim, v3client.CachingClientSettings{})
+
+	ctx := context.Background()
+	_, _ = cc.ListServicesFiltered(ctx, map[string]string{"name": "svc"})
+	_, _ = cc.ListServicesFiltered(ctx, map[string]string{"name": "other"})
+	_, _ = cc.ListServicesFiltered(v3client.ReturnFields(ctx, []string{"id"}), map[string]string{"name": "svc"})
+	_, _ = cc.ListServicesFiltered(ctx, map[string]string{"name": "svc"})
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(gets))
+}
+
+func TestCachingClientEndpointUpdateInvalidatesHierarchy(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	svcId, _ := sim.Create("/services", masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	endpId, _ := sim.Create("/services/"+svcId+"/endpoints", masherytypes.Endpoint{AddressableV3Object: masherytypes.AddressableV3Object{Name: "endp"}})
+	otherSvcId, _ := sim.Create("/services", masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "other"}})
+	packId, _ := sim.Create("/packages", masherytypes.Package{AddressableV3Object: masherytypes.AddressableV3Object{Name: "pack"}})
+	planId, _ := sim.Create("/packages/"+packId+"/plans", masherytypes.Plan{AddressableV3Object: masherytypes.AddressableV3Object{Name: "plan"}})
+
+	cc, _ := cachingClientOf(sim, v3client.CachingClientSettings{})
+	ctx := context.Background()
+
+	svcIdent := masherytypes.ServiceIdentityFrom(svcId)
+	endpIdent := masherytypes.ServiceEndpointIdentifier{ServiceIdentifier: svcIdent, EndpointId: endpId}
+	planIdent := masherytypes.PackagePlanIdentifier{PackageIdentifier: masherytypes.PackageIdentifier{PackageId: packId}, PlanId: planId}
+
+	_, _, _ = cc.GetService(ctx, svcIdent)
+	_, _, _ = cc.GetService(ctx, masherytypes.ServiceIdentityFrom(otherSvcId))
+	endp, _, _ := cc.GetEndpoint(ctx, endpIdent)
+	_, _ = cc.ListEndpoints(ctx, svcIdent)
+	_, _ = cc.ListPlanServices(ctx, planIdent)
+	_, _ = cc.ListMembers(ctx)
+	assert.Equal(t, 6, cc.Stats().Entries)
+
+	endp.ParentServiceId = svcIdent
+	endp.Name = "updated"
+	_, err := cc.UpdateEndpoint(ctx, endp)
+	assert.Nil(t, err)
+
+	// The other service and the members remain cached.
+	assert.Equal(t, 2, cc.Stats().Entries)
+
+	reread, _, err := cc.GetEndpoint(ctx, endpIdent)
+	assert.Nil(t, err)
+	assert.Equal(t, "updated", reread.Name)
+}
+
+func TestCachingClientDisabledKindAndExpiry(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	_, _ = sim.Create("/members", masherytypes.Member{Username: "user"})
+	cc, gets := cachingClientOf(sim, v3client.CachingClientSettings{
+		DefaultTTL: 50 * time.Millisecond,
+		TTL: map[v3client.CacheKind]time.Duration{
+			v3client.CacheService: 0,
+		},
+	})
+
+	ctx := context.Background()
+	_, _ = cc.ListServices(ctx)
+	_, _ = cc.ListServices(ctx)
+	assert.Equal(t, int32(2), atomic.LoadInt32(gets))
+
+	_, _ = cc.ListMembers(ctx)
+	_, _ = cc.ListMembers(ctx)
+	assert.Equal(t, int32(3), atomic.LoadInt32(gets))
+
+	time.Sleep(60 * time.Millisecond)
+	_, _ = cc.ListMembers(ctx)
+	assert.Equal(t, int32(4), atomic.LoadInt32(gets))
+}
@@ -1,9 +1,17 @@
 package v3client_test
 
 import (
+	"context"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/stretchr/testify/assert"
 	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 )
 
 //func TestClientCredentialsProviderIsImplementedCorrectly(t *testing.T) {
@@ -94,3 +102,245 @@ func TestMasheryV3Credentials_Inherit(t *testing.T) {
 	}
 
 }
+
+// tokenEndpoint Simulates the Mashery token endpoint. The password grant is rejected the number of times set by
+// failPassword, and the refresh grant is rejected where rejectRefresh is set.
+type tokenEndpoint struct {
+	mutex         sync.Mutex
+	passwordCalls int
+	refreshCalls  int
+	failPassword  int
+	rejectRefresh bool
+	expiresIn     int
+}
+
+func (te *tokenEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+
+	te.mutex.Lock()
+	defer te.mutex.Unlock()
+
+	switch r.PostForm.Get("grant_type") {
+	case "password":
+		te.passwordCalls++
+		if te.failPassword > 0 {
+			te.failPassword--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+	case "refresh_token":
+		te.refreshCalls++
+		if te.rejectRefresh {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+	}
+
+	// Simulate the latency of the token endpoint
+	time.Sleep(20 * time.Millisecond)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = fmt.Fprintf(w, `{"token_type":"bearer","access_token":"tkn-%d-%d","refresh_token":"rt-%d","expires_in":%d}`,
+		te.passwordCalls, te.refreshCalls, te.refreshCalls, te.expiresIn)
+}
+
+func (te *tokenEndpoint) calls() (int, int) {
+	te.mutex.Lock()
+	defer te.mutex.Unlock()
+
+	return te.passwordCalls, te.refreshCalls
+}
+
+type refreshEvents struct {
+	mutex  sync.Mutex
+	events []string
+}
+
+func (re *refreshEvents) listen(source string, err error) {
+	re.mutex.Lock()
+	defer re.mutex.Unlock()
+
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	re.events = append(re.events, source+":"+outcome)
+}
+
+func (re *refreshEvents) get() []string {
+	re.mutex.Lock()
+	defer re.mutex.Unlock()
+
+	return append([]string{}, re.events...)
+}
+
+func credentialsProviderFor(te *tokenEndpoint) (*v3client.ClientCredentialsProvider, *httptest.Server) {
+	srv := httptest.NewServer(te)
+	lcp := v3client.NewLiveCredentialsProviderWithParams(v3client.MasheryV3Credentials{
+		AreaId:   "area",
+		ApiKey:   "key",
+		Secret:   "secret",
+		Username: "user",
+		Password: "password",
+	}, v3client.OAuthHelperParams{MasheryTokenEndpoint: srv.URL})
+	lcp.RetryPolicy = &transport.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 10 * time.Millisecond,
+		Multiplier:     1,
+	}
+
+	return lcp, srv
+}
+
+func TestClientCredentialsProviderSharesConcurrentRetrieval(t *testing.T) {
+	te := &tokenEndpoint{expiresIn: 3600}
+	lcp, srv := credentialsProviderFor(te)
+	defer srv.Close()
+	defer lcp.Close()
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			hdr, err := lcp.HeaderAuthorization(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, "Bearer tkn-1-0", hdr["Authorization"])
+		}()
+	}
+	wg.Wait()
+
+	password, refresh := te.calls()
+	assert.Equal(t, 1, password)
+	assert.Equal(t, 0, refresh)
+}
+
+func TestClientCredentialsProviderRetriesWithBackoff(t *testing.T) {
+	te := &tokenEndpoint{expiresIn: 3600, failPassword: 2}
+	lcp, srv := credentialsProviderFor(te)
+	defer srv.Close()
+	defer lcp.Close()
+
+	events := refreshEvents{}
+	lcp.OnTokenRefresh(events.listen)
+
+	tkn, err := lcp.AccessToken(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "tkn-3-0", tkn)
+	assert.Equal(t, []string{"password:failure", "password:failure", "password:success"}, events.get())
+
+	// Where the attempts are exhausted, the error is returned
+	te.mutex.Lock()
+	te.failPassword = 5
+	te.rejectRefresh = true
+	te.mutex.Unlock()
+	assert.NotNil(t, lcp.Refresh())
+}
+
+func TestClientCredentialsProviderRefreshesAheadOfExpiry(t *testing.T) {
+	te := &tokenEndpoint{expiresIn: 2}
+	lcp, srv := credentialsProviderFor(te)
+	defer srv.Close()
+
+	events := refreshEvents{}
+	lcp.OnTokenRefresh(events.listen)
+
+	refreshed := make(chan struct{}, 10)
+	lcp.OnPostRefresh(func() { refreshed <- struct{}{} })
+
+	_, err := lcp.TokenData()
+	assert.Nil(t, err)
+	lcp.EnsureRefresh()
+
+	// The token living 2 seconds is refreshed at half of its lifetime with the refresh token
+	select {
+	case <-refreshed:
+	case <-time.After(3 * time.Second):
+		assert.Fail(t, "token was not refreshed")
+	}
+
+	tkn, err := lcp.AccessToken(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "tkn-1-1", tkn)
+
+	// Where the refresh token is rejected, the password grant is used
+	te.mutex.Lock()
+	te.rejectRefresh = true
+	te.mutex.Unlock()
+
+	select {
+	case <-refreshed:
+	case <-time.After(3 * time.Second):
+		assert.Fail(t, "token was not refreshed")
+	}
+
+	tkn, err = lcp.AccessToken(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "tkn-2-2", tkn)
+	assert.Equal(t, []string{"password:success", "refresh_token:success", "refresh_token:failure", "password:success"}, events.get())
+
+	// Close stops the refresh goroutine
+	closed := make(chan struct{})
+	go func() {
+		lcp.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		assert.Fail(t, "refresh did not stop")
+	}
+}
+
+func TestClientCredentialsProviderUsesSeededToken(t *testing.T) {
+	te := &tokenEndpoint{expiresIn: 3600}
+	lcp, srv := credentialsProviderFor(te)
+	defer srv.Close()
+	defer lcp.Close()
+
+	assert.Nil(t, lcp.Response())
+
+	seeded := &masherytypes.TimedAccessTokenResponse{
+		Obtained: time.Now(),
+		AccessTokenResponse: masherytypes.AccessTokenResponse{
+			TokenType:   "bearer",
+			AccessToken: "seeded",
+			ExpiresIn:   3600,
+		},
+	}
+	lcp.SetTokenData(seeded)
+
+	tkn, err := lcp.AccessToken(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "seeded", tkn)
+	assert.Equal(t, seeded, lcp.Response())
+
+	password, refresh := te.calls()
+	assert.Equal(t, 0, password)
+	assert.Equal(t, 0, refresh)
+}
+
+func TestTokenRefreshListenerOfParams(t *testing.T) {
+	te := &tokenEndpoint{expiresIn: 3600}
+	srv := httptest.NewServer(te)
+	defer srv.Close()
+
+	events := refreshEvents{}
+	params := v3client.OAuthHelperParams{MasheryTokenEndpoint: srv.URL, OnTokenRefresh: events.listen}
+	creds := v3client.MasheryV3Credentials{AreaId: "area", ApiKey: "key", Secret: "secret", Username: "user", Password: "password"}
+
+	source := v3client.NewCredentialsTokenSource(creds, params)
+	current, err := source(context.Background(), nil)
+	assert.Nil(t, err)
+	_, err = source(context.Background(), current)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"password:success", "refresh_token:success"}, events.get())
+
+	pool := v3client.NewCredentialsKeyPool(params, creds)
+	defer pool.Close()
+	_, err = pool.HeaderAuthorization(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"password:success", "refresh_token:success", "password:success"}, events.get())
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -29,16 +32,63 @@ func (m *MasheryV3Credentials) FullySpecified() bool {
 //------------------------------------------------------------------------
 // Abstract credentials provider
 
+// DefaultTokenRefreshAhead Time before the expiry of the access token at which the provider refreshes it
+const DefaultTokenRefreshAhead = time.Minute * 5
+
+// ClientCredentialsProvider Provider retrieving the access tokens from Mashery with the password grant, and keeping
+// them fresh for long-running processes. The provider is safe for concurrent use:
+// - concurrent callers needing a token share a single retrieval;
+// - once EnsureRefresh is called, the token is refreshed in the background RefreshAhead before it expires, using
+// the refresh token;
+// - where Mashery rejects the refresh token, the token is retrieved with the password grant instead;
+// - failed retrievals are retried with the back-off of the RetryPolicy, and the background refresh continues
+// after the failures;
+// - the outcome of each attempt is reported to the TokenRefreshListener.
+//
+// Close stops the background refresh.
 type ClientCredentialsProvider struct {
 	V3OAuthHelper
 
-	Response            *masherytypes.TimedAccessTokenResponse
-	tokenFile           string
-	credentials         MasheryV3Credentials
-	comm                chan int
-	asyncRefreshRunning bool
+	// RefreshAhead is the time before the expiry at which the token is refreshed. Zero selects
+	// DefaultTokenRefreshAhead. Where the token lives shorter than twice RefreshAhead, it is refreshed at half
+	// of its lifetime.
+	RefreshAhead time.Duration
+	// RetryPolicy sets the number of attempts and the back-off of the token retrieval. Only MaxAttempts,
+	// MaxElapsedTime and the back-off fields are used. Nil selects DefaultTokenRetryPolicy.
+	RetryPolicy *transport.RetryPolicy
+
+	credentials MasheryV3Credentials
+
+	mutex    sync.Mutex
+	response *masherytypes.TimedAccessTokenResponse
+	flight   *tokenFlight
+
+	ctx           context.Context
+	cancel        context.CancelFunc
+	refreshDone   chan struct{}
+	refreshActive bool
 
 	postRefreshAction func()
+	refreshListener   transport.TokenRefreshListener
+}
+
+// tokenFlight is the token retrieval shared by the concurrent callers.
+type tokenFlight struct {
+	done     chan struct{}
+	response *masherytypes.TimedAccessTokenResponse
+	err      error
+}
+
+// DefaultTokenRetryPolicy Retries the token retrieval up to 5 times within a minute.
+func DefaultTokenRetryPolicy() *transport.RetryPolicy {
+	return &transport.RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Second * 15,
+		Multiplier:     2,
+		Jitter:         0.2,
+		MaxElapsedTime: time.Minute,
+	}
 }
 
 func NewClientCredentialsProvider(credentials MasheryV3Credentials, tlsCfg *tls.Config) *ClientCredentialsProvider {
@@ -50,19 +100,26 @@ func NewLiveCredentialsProviderUsing(credentials MasheryV3Credentials, endpoint
 		panic("nil tls configuration is not allowed")
 	}
 
-	retVal := ClientCredentialsProvider{
-		V3OAuthHelper: V3OAuthHelper{
-			TokenEndpoint: endpoint,
-			client: &http.Client{
-				Transport: &http.Transport{
-					TLSClientConfig: tlsCfg,
-				},
-				Timeout: time.Second * 30,
-			},
+	return NewLiveCredentialsProviderWithParams(credentials, OAuthHelperParams{
+		HTTPClientParams: transport.HTTPClientParams{
+			TLSConfig: tlsCfg,
 		},
+		MasheryTokenEndpoint: endpoint,
+	})
+}
+
+// NewLiveCredentialsProviderWithParams Creates the provider that retrieves the access tokens with the V3OAuthHelper
+// configured by the params, e.g. to connect via a proxy.
+func NewLiveCredentialsProviderWithParams(credentials MasheryV3Credentials, params OAuthHelperParams) *ClientCredentialsProvider {
+	ctx, cancel := context.WithCancel(context.Background())
 
-		credentials: credentials,
-		comm:        make(chan int),
+	retVal := ClientCredentialsProvider{
+		V3OAuthHelper: *NewOAuthHelper(params),
+
+		credentials:     credentials,
+		ctx:             ctx,
+		cancel:          cancel,
+		refreshListener: params.OnTokenRefresh,
 	}
 
 	return &retVal
@@ -94,47 +151,125 @@ func (m *MasheryV3Credentials) Inherit(other *MasheryV3Credentials) {
 	}
 }
 
+// OnPostRefresh Sets the function called after each successful background refresh
 func (lcp *ClientCredentialsProvider) OnPostRefresh(f func()) {
+	lcp.mutex.Lock()
+	defer lcp.mutex.Unlock()
+
 	lcp.postRefreshAction = f
 }
 
+// OnTokenRefresh Sets the listener notified of each attempt to retrieve or refresh the access token, successful
+// or not, e.g. Metrics.RecordTokenRefresh. Replaces the OnTokenRefresh listener of the params.
+func (lcp *ClientCredentialsProvider) OnTokenRefresh(l transport.TokenRefreshListener) {
+	lcp.mutex.Lock()
+	defer lcp.mutex.Unlock()
+
+	lcp.refreshListener = l
+}
+
+func (lcp *ClientCredentialsProvider) notifyRefresh(source string, err error) {
+	lcp.mutex.Lock()
+	l := lcp.refreshListener
+	lcp.mutex.Unlock()
+
+	if l != nil {
+		l(source, err)
+	}
+}
+
+// EnsureRefresh Starts refreshing the token in the background ahead of its expiry, unless already started.
 func (lcp *ClientCredentialsProvider) EnsureRefresh() {
-	if !lcp.asyncRefreshRunning {
-		go lcp.doEnsureRefresh()
-		lcp.asyncRefreshRunning = true
+	lcp.mutex.Lock()
+	defer lcp.mutex.Unlock()
+
+	if lcp.refreshActive || lcp.ctx.Err() != nil {
+		return
 	}
+
+	lcp.refreshActive = true
+	lcp.refreshDone = make(chan struct{})
+	go lcp.doEnsureRefresh(lcp.refreshDone)
 }
 
+// Close Stops the background refresh and waits for it to exit. The tokens can still be retrieved on demand.
 func (lcp *ClientCredentialsProvider) Close() {
-	// Send the command to the refresh thread if it is running
-	if lcp.asyncRefreshRunning {
-		lcp.comm <- 1
+	lcp.mutex.Lock()
+	lcp.cancel()
+	done := lcp.refreshDone
+	lcp.mutex.Unlock()
+
+	if done != nil {
+		<-done
 	}
 }
 
-func (lcp *ClientCredentialsProvider) doEnsureRefresh() {
-	for lcp.Response != nil {
-		waitDur := lcp.Response.ExpiryTime().Sub(time.Now())
-		waitDur -= time.Minute * 5
+func (lcp *ClientCredentialsProvider) doEnsureRefresh(done chan struct{}) {
+	defer close(done)
+
+	failures := 0
+	for {
+		wait := lcp.nextRefreshIn()
+		if failures > 0 {
+			// The retries of the failed refresh are exhausted; the refresh is re-attempted after the
+			// longest back-off.
+			wait = lcp.retryPolicy().Backoff(lcp.retryPolicy().MaxAttempts)
+		}
 
-		select {
-		case <-lcp.comm:
-			lcp.asyncRefreshRunning = false
+		if sleepUntilDone(lcp.ctx, wait) != nil {
 			return
-		case <-time.After(waitDur):
-			err := lcp.Refresh()
-			if err != nil {
-				lcp.asyncRefreshRunning = false
+		}
+
+		if _, err := lcp.obtain(lcp.ctx, true); err != nil {
+			if lcp.ctx.Err() != nil {
 				return
 			}
+			failures++
+			continue
 		}
 
-		if lcp.postRefreshAction != nil {
-			lcp.postRefreshAction()
+		failures = 0
+
+		lcp.mutex.Lock()
+		f := lcp.postRefreshAction
+		lcp.mutex.Unlock()
+
+		if f != nil {
+			f()
 		}
 	}
 }
 
+// nextRefreshIn returns the time until the current token has to be refreshed.
+func (lcp *ClientCredentialsProvider) nextRefreshIn() time.Duration {
+	lcp.mutex.Lock()
+	resp := lcp.response
+	lcp.mutex.Unlock()
+
+	if resp == nil {
+		return 0
+	}
+
+	ahead := lcp.RefreshAhead
+	if ahead <= 0 {
+		ahead = DefaultTokenRefreshAhead
+	}
+
+	lifetime := time.Duration(resp.ExpiresIn) * time.Second
+	if lifetime < 2*ahead {
+		ahead = lifetime / 2
+	}
+
+	return time.Until(resp.Obtained.Add(lifetime - ahead))
+}
+
+func (lcp *ClientCredentialsProvider) retryPolicy() *transport.RetryPolicy {
+	if lcp.RetryPolicy != nil {
+		return lcp.RetryPolicy
+	}
+	return DefaultTokenRetryPolicy()
+}
+
 func ResponseDate(resp *http.Response) time.Time {
 	if val := resp.Header.Get("Date"); len(val) > 0 {
 		if t, err := time.Parse(time.RFC1123, val); err == nil {
@@ -145,32 +280,125 @@ func ResponseDate(resp *http.Response) time.Time {
 	return time.Unix(0, 0)
 }
 
+// Refresh Refreshes the access token immediately, using the refresh token where available.
 func (lcp *ClientCredentialsProvider) Refresh() error {
-	if lcp.Response == nil {
-		return errors.New("cannot refresh without having a previous response")
-	} else if lcp.Response.Expired() {
-		return errors.New("refresh token has already expired")
-	}
-
-	resp, err := lcp.ExchangeRefreshToken(&lcp.credentials, lcp.Response.RefreshToken)
-	lcp.Response = resp
-
+	_, err := lcp.obtain(context.Background(), true)
 	return err
 }
 
+// TokenData Returns the current access token, retrieving it where the provider has none or it has expired.
 func (lcp *ClientCredentialsProvider) TokenData() (*masherytypes.TimedAccessTokenResponse, error) {
-	if lcp.Response != nil && !lcp.Response.Expired() {
-		return lcp.Response, nil
+	return lcp.obtain(context.Background(), false)
+}
+
+// SetTokenData Seeds the provider with the access token, e.g. one saved by an earlier run. The token is used until
+// it expires or is refreshed.
+func (lcp *ClientCredentialsProvider) SetTokenData(resp *masherytypes.TimedAccessTokenResponse) {
+	lcp.mutex.Lock()
+	defer lcp.mutex.Unlock()
+
+	lcp.response = resp
+}
+
+// Response Returns the current access token without retrieving it, or nil where the provider has none.
+//
+// Deprecated: the exported Response field is replaced by the accessors; use TokenData to read the token and
+// SetTokenData to seed it.
+func (lcp *ClientCredentialsProvider) Response() *masherytypes.TimedAccessTokenResponse {
+	lcp.mutex.Lock()
+	defer lcp.mutex.Unlock()
+
+	return lcp.response
+}
+
+// obtain returns the current token unless it has expired or force is set; otherwise it joins the retrieval in
+// progress, or starts one.
+func (lcp *ClientCredentialsProvider) obtain(ctx context.Context, force bool) (*masherytypes.TimedAccessTokenResponse, error) {
+	lcp.mutex.Lock()
+	if !force && lcp.response != nil && !lcp.response.Expired() {
+		rv := lcp.response
+		lcp.mutex.Unlock()
+		return rv, nil
+	}
+
+	flight := lcp.flight
+	if flight == nil {
+		flight = &tokenFlight{done: make(chan struct{})}
+		lcp.flight = flight
+		current := lcp.response
+		lcp.mutex.Unlock()
+
+		go lcp.fly(flight, current)
 	} else {
-		resp, err := lcp.RetrieveAccessTokenFor(&lcp.credentials)
-		lcp.Response = resp
+		lcp.mutex.Unlock()
+	}
+
+	select {
+	case <-flight.done:
+		return flight.response, flight.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fly performs the retrieval and publishes its outcome to the waiting callers.
+func (lcp *ClientCredentialsProvider) fly(flight *tokenFlight, current *masherytypes.TimedAccessTokenResponse) {
+	resp, err := lcp.retrieveWithRetries(current)
+
+	lcp.mutex.Lock()
+	if err == nil {
+		lcp.response = resp
+	}
+	lcp.flight = nil
+	lcp.mutex.Unlock()
+
+	flight.response, flight.err = resp, err
+	close(flight.done)
+}
+
+func (lcp *ClientCredentialsProvider) retrieveWithRetries(current *masherytypes.TimedAccessTokenResponse) (*masherytypes.TimedAccessTokenResponse, error) {
+	policy := lcp.retryPolicy()
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		resp, err := lcp.retrieve(current)
+		if err == nil {
+			return resp, nil
+		}
+
+		if attempt >= policy.MaxAttempts {
+			return nil, err
+		}
+
+		delay := policy.Backoff(attempt)
+		if policy.MaxElapsedTime > 0 && time.Since(start)+delay > policy.MaxElapsedTime {
+			return nil, err
+		}
+		if sleepUntilDone(lcp.ctx, delay) != nil {
+			return nil, err
+		}
+	}
+}
 
-		return resp, err
+// retrieve exchanges the refresh token of the current token, where it is still valid; where the exchange fails,
+// e.g. because Mashery rejected the refresh token, the token is retrieved with the password grant.
+func (lcp *ClientCredentialsProvider) retrieve(current *masherytypes.TimedAccessTokenResponse) (*masherytypes.TimedAccessTokenResponse, error) {
+	if current != nil && len(current.RefreshToken) > 0 && !current.Expired() {
+		resp, err := lcp.ExchangeRefreshToken(&lcp.credentials, current.RefreshToken)
+		lcp.notifyRefresh("refresh_token", err)
+		if err == nil {
+			return resp, nil
+		}
 	}
+
+	resp, err := lcp.RetrieveAccessTokenFor(&lcp.credentials)
+	lcp.notifyRefresh("password", err)
+
+	return resp, err
 }
 
-func (lcp *ClientCredentialsProvider) AccessToken(_ context.Context) (string, error) {
-	if dat, err := lcp.TokenData(); err != nil {
+func (lcp *ClientCredentialsProvider) AccessToken(ctx context.Context) (string, error) {
+	if dat, err := lcp.obtain(ctx, false); err != nil {
 		return "", err
 	} else if dat == nil {
 		return "", errors.New("empty token data returned while trying to provide access token")
@@ -180,15 +408,13 @@ func (lcp *ClientCredentialsProvider) AccessToken(_ context.Context) (string, er
 }
 
 func (lcp *ClientCredentialsProvider) HeaderAuthorization(ctx context.Context) (map[string]string, error) {
-	var token string
-	var err error
-	var rv map[string]string
-
-	if token, err = lcp.AccessToken(ctx); err == nil {
-		rv["Authorization"] = token
+	if token, err := lcp.AccessToken(ctx); err != nil {
+		return nil, err
+	} else {
+		return map[string]string{
+			"Authorization": fmt.Sprintf("Bearer %s", token),
+		}, nil
 	}
-
-	return rv, err
 }
 
 func (lcp *ClientCredentialsProvider) QueryStringAuthorization(_ context.Context) (map[string]string, error) {
@@ -196,3 +422,37 @@ func (lcp *ClientCredentialsProvider) QueryStringAuthorization(_ context.Context
 
 	return emptyMap, nil
 }
+
+// sleepUntilDone sleeps for the specified duration unless the context is done first.
+func sleepUntilDone(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// NewCredentialsKeyPool Creates the key pool dispatching the calls across the keys of the credentials. Each key
+// obtains its access tokens with its own ClientCredentialsProvider, configured by the params, and is paced to its
+// MaxQPS, or to 2 QPS if MaxQPS is not set. The attempts of all keys are reported to the OnTokenRefresh listener
+// of the params.
+func NewCredentialsKeyPool(params OAuthHelperParams, creds ...MasheryV3Credentials) *transport.KeyPool {
+	rv := transport.NewKeyPool()
+	for _, c := range creds {
+		qps := c.MaxQPS
+		if qps <= 0 {
+			qps = 2
+		}
+		rv.AddKey(c.ApiKey, float64(qps), NewLiveCredentialsProviderWithParams(c, params))
+	}
+
+	return rv
+}
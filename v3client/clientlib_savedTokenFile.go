@@ -1,6 +1,7 @@
 package v3client
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
@@ -23,16 +24,47 @@ func DefaultSavedAccessTokenFilePath() string {
 	}
 }
 
+// PersistV3TokenResponse Saves the token response into the file. The file is written while holding the lock shared
+// with the other processes using the file as the token cache, and is replaced atomically, so that the readers never
+// observe a partially written file. Like the credentials file, it is readable by the owner only.
 func PersistV3TokenResponse(dat *masherytypes.TimedAccessTokenResponse, path string) error {
+	lck, err := lockTokenFile(context.Background(), path)
+	if err != nil {
+		return err
+	}
+	defer lck.Unlock()
+
+	return writeV3TokenResponse(dat, path)
+}
+
+// writeV3TokenResponse writes the file; the caller has to hold the lock of the file.
+func writeV3TokenResponse(dat *masherytypes.TimedAccessTokenResponse, path string) error {
 	if stat, err := os.Stat(path); (err == nil || os.IsExist(err)) && stat.IsDir() {
 		return errors.New("cannot persis a file into existing directory")
 	}
 
-	if m, err := json.Marshal(dat); err == nil {
-		return ioutil.WriteFile(path, m, 0644)
-	} else {
+	m, err := json.Marshal(dat)
+	if err != nil {
 		return err
 	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(m); err != nil {
+		_ = tmp.Close()
+		return err
+	} else if err = tmp.Chmod(CredentialsFilePermissions); err != nil {
+		_ = tmp.Close()
+		return err
+	} else if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
 }
 
 func LoadV3TokenResponse(path string) (*masherytypes.TimedAccessTokenResponse, error) {
@@ -21,6 +21,10 @@ type V3OAuthHelper struct {
 type OAuthHelperParams struct {
 	transport.HTTPClientParams
 	MasheryTokenEndpoint string
+	// OnTokenRefresh, if set, is notified of each attempt to retrieve or refresh the access token made by the
+	// ClientCredentialsProvider, the key pool and the token source created with these params, e.g.
+	// Metrics.RecordTokenRefresh.
+	OnTokenRefresh transport.TokenRefreshListener
 }
 
 func (ohp *OAuthHelperParams) FillDefaults() {
@@ -0,0 +1,168 @@
+package v3client
+
+import (
+	"errors"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/errwrap"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"gopkg.in/yaml.v2"
+	"os"
+	"sort"
+	"strings"
+)
+
+// CredentialsProfileTLS TLS options of the connections made with the profile
+type CredentialsProfileTLS struct {
+	CABundleFile   string `yaml:"caBundle,omitempty"`
+	ClientCertFile string `yaml:"clientCert,omitempty"`
+	ClientKeyFile  string `yaml:"clientKey,omitempty"`
+	// PinFile is the JSON or YAML file with the TLS pins the Mashery certificates are verified against
+	PinFile string `yaml:"pinFile,omitempty"`
+}
+
+// CredentialsProfile Identity and connection settings of a single Mashery area
+type CredentialsProfile struct {
+	MasheryV3Credentials `yaml:",inline"`
+
+	// Endpoint is the V3 API endpoint; empty selects the default
+	Endpoint string `yaml:"endpoint,omitempty"`
+	// TokenEndpoint is the endpoint the access tokens are obtained from; empty selects the default
+	TokenEndpoint string                `yaml:"tokenEndpoint,omitempty"`
+	TLS           CredentialsProfileTLS `yaml:"tls,omitempty"`
+}
+
+// CredentialsProfiles Content of the credentials file holding the named profiles. The identity at the top level of
+// the file, which is the only one the files written before the profiles contain, is used where no profile is
+// selected.
+type CredentialsProfiles struct {
+	CredentialsProfile `yaml:",inline"`
+
+	// DefaultProfile is the profile selected where neither the name nor the MASHERY_PROFILE variable is supplied
+	DefaultProfile string                         `yaml:"defaultProfile,omitempty"`
+	Profiles       map[string]*CredentialsProfile `yaml:"profiles,omitempty"`
+}
+
+// ProfileNames Returns the sorted names of the defined profiles
+func (cp *CredentialsProfiles) ProfileNames() []string {
+	rv := make([]string, 0, len(cp.Profiles))
+	for k := range cp.Profiles {
+		rv = append(rv, k)
+	}
+	sort.Strings(rv)
+	return rv
+}
+
+// Profile Returns the profile with the name. Empty name selects the profile named by the MASHERY_PROFILE
+// variable, then the DefaultProfile, and finally the top-level identity.
+func (cp *CredentialsProfiles) Profile(name string) (*CredentialsProfile, error) {
+	if len(name) == 0 {
+		name = os.Getenv(ProfileEnv)
+	}
+	if len(name) == 0 {
+		name = cp.DefaultProfile
+	}
+	if len(name) == 0 {
+		return &cp.CredentialsProfile, nil
+	}
+
+	if p, ok := cp.Profiles[name]; ok && p != nil {
+		return p, nil
+	}
+
+	return nil, fmt.Errorf("profile %s is not defined; defined profiles are: %s", name, strings.Join(cp.ProfileNames(), ", "))
+}
+
+// Params Returns the client parameters connecting to the area of the profile: the endpoint, the QPS, the TLS
+// options, and the ClientCredentialsProvider retrieving the access tokens with the credentials of the profile.
+func (p *CredentialsProfile) Params() (Params, error) {
+	if !p.FullySpecified() {
+		return Params{}, errors.New("profile credentials are incomplete: area id, API key, secret, username and password are required")
+	}
+
+	helperParams, err := p.OAuthHelperParams()
+	if err != nil {
+		return Params{}, err
+	}
+
+	rv := Params{
+		HTTPClientParams: helperParams.HTTPClientParams,
+		MashEndpoint:     p.Endpoint,
+		QPS:              int64(p.MaxQPS),
+		Authorizer:       NewLiveCredentialsProviderWithParams(p.MasheryV3Credentials, helperParams),
+	}
+
+	return rv, nil
+}
+
+// OAuthHelperParams Returns the parameters retrieving the access tokens from the token endpoint of the profile,
+// connecting with the TLS options of the profile.
+func (p *CredentialsProfile) OAuthHelperParams() (OAuthHelperParams, error) {
+	rv := OAuthHelperParams{
+		MasheryTokenEndpoint: p.TokenEndpoint,
+	}
+
+	rv.TLS.CABundleFile = p.TLS.CABundleFile
+	if len(p.TLS.ClientCertFile) > 0 || len(p.TLS.ClientKeyFile) > 0 {
+		rv.TLS.ClientCertificate = &transport.ClientCertificate{
+			CertFile: p.TLS.ClientCertFile,
+			KeyFile:  p.TLS.ClientKeyFile,
+		}
+	}
+
+	if len(p.TLS.PinFile) > 0 {
+		if pinner, err := transport.LoadTLSPinner(p.TLS.PinFile); err != nil {
+			return OAuthHelperParams{}, &errwrap.WrappedError{Context: "loading TLS pins", Cause: err}
+		} else {
+			rv.TLSConfig = pinner.CreateTLSConfig()
+		}
+	}
+
+	if err := rv.HTTPClientParams.Validate(); err != nil {
+		return OAuthHelperParams{}, err
+	}
+
+	return rv, nil
+}
+
+// ParseCredentialsProfiles Parses the decrypted content of the credentials file
+func ParseCredentialsProfiles(dat []byte) (*CredentialsProfiles, error) {
+	rv := CredentialsProfiles{}
+	if err := yaml.Unmarshal(dat, &rv); err != nil {
+		return nil, &errwrap.WrappedError{Context: "parsing credentials", Cause: err}
+	}
+
+	return &rv, nil
+}
+
+// LoadCredentialsProfiles Reads the profiles from the encrypted credentials file
+func LoadCredentialsProfiles(path string, passphrase string) (*CredentialsProfiles, error) {
+	if dat, err := ReadCiphertext(path, passphrase); err != nil {
+		return nil, err
+	} else {
+		return ParseCredentialsProfiles(dat)
+	}
+}
+
+// SaveCredentialsProfiles Writes the profiles to the credentials file in the versioned format
+func SaveCredentialsProfiles(path string, profiles *CredentialsProfiles, passphrase string) error {
+	if dat, err := yaml.Marshal(profiles); err != nil {
+		return &errwrap.WrappedError{Context: "serializing credentials", Cause: err}
+	} else {
+		return WriteCredentialsFile(path, dat, passphrase)
+	}
+}
+
+// ProfileParams Returns the client parameters of the named profile of the encrypted credentials file. Empty name
+// selects the profile as described by CredentialsProfiles.Profile.
+func ProfileParams(path string, passphrase string, name string) (Params, error) {
+	profiles, err := LoadCredentialsProfiles(path, passphrase)
+	if err != nil {
+		return Params{}, err
+	}
+
+	if p, err := profiles.Profile(name); err != nil {
+		return Params{}, err
+	} else {
+		return p.Params()
+	}
+}
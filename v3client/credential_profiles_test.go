@@ -0,0 +1,166 @@
+package v3client_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const profilesYaml = `
+areaId: top
+apiKey: topKey
+defaultProfile: dev
+profiles:
+  dev:
+    areaId: devArea
+    apiKey: devKey
+    secret: devSecret
+    username: devUser
+    password: devPassword
+    maxQPS: 5
+  prod:
+    areaId: prodArea
+    apiKey: prodKey
+    secret: prodSecret
+    username: prodUser
+    password: prodPassword
+    maxQPS: 20
+    endpoint: https://api.example.com/v3/rest
+    tokenEndpoint: https://api.example.com/v3/token
+    tls:
+      caBundle: /etc/ca.pem
+      clientCert: /etc/client.pem
+      clientKey: /etc/client.key
+`
+
+func TestCredentialsProfileSelection(t *testing.T) {
+	t.Setenv(v3client.ProfileEnv, "")
+
+	profiles, err := v3client.ParseCredentialsProfiles([]byte(profilesYaml))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"dev", "prod"}, profiles.ProfileNames())
+
+	p, err := profiles.Profile("")
+	assert.Nil(t, err)
+	assert.Equal(t, "devArea", p.AreaId)
+
+	t.Setenv(v3client.ProfileEnv, "prod")
+	p, err = profiles.Profile("")
+	assert.Nil(t, err)
+	assert.Equal(t, "prodArea", p.AreaId)
+
+	// Explicit name overrides the variable
+	p, err = profiles.Profile("dev")
+	assert.Nil(t, err)
+	assert.Equal(t, "devArea", p.AreaId)
+
+	_, err = profiles.Profile("partner")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "dev, prod")
+
+	// Without profiles, the top-level identity is used
+	t.Setenv(v3client.ProfileEnv, "")
+	profiles.DefaultProfile = ""
+	p, err = profiles.Profile("")
+	assert.Nil(t, err)
+	assert.Equal(t, "top", p.AreaId)
+}
+
+// writeTestCertificate writes the self-signed certificate and its key into the directory
+func writeTestCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client.key")
+	assert.Nil(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+
+	return certFile, keyFile
+}
+
+func TestProfileParams(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCertificate(t, dir)
+	yaml := strings.NewReplacer("/etc/ca.pem", certFile, "/etc/client.pem", certFile, "/etc/client.key", keyFile).Replace(profilesYaml)
+
+	path := filepath.Join(dir, "creds")
+	assert.Nil(t, v3client.WriteCredentialsFile(path, []byte(yaml), "passphrase"))
+
+	params, err := v3client.ProfileParams(path, "passphrase", "prod")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://api.example.com/v3/rest", params.MashEndpoint)
+	assert.Equal(t, int64(20), params.QPS)
+	assert.Equal(t, certFile, params.TLS.CABundleFile)
+	assert.Equal(t, certFile, params.TLS.ClientCertificate.CertFile)
+	assert.Equal(t, keyFile, params.TLS.ClientCertificate.KeyFile)
+
+	if provider, ok := params.Authorizer.(*v3client.ClientCredentialsProvider); assert.True(t, ok) {
+		assert.Equal(t, "https://api.example.com/v3/token", provider.TokenEndpoint)
+	}
+
+	// The default profile is used where no name is supplied
+	_, err = v3client.ProfileParams(path, "passphrase", "")
+	assert.Nil(t, err)
+
+	// The top-level identity is incomplete
+	profiles, _ := v3client.LoadCredentialsProfiles(path, "passphrase")
+	profiles.DefaultProfile = ""
+	assert.Nil(t, v3client.SaveCredentialsProfiles(path, profiles, "passphrase"))
+	_, err = v3client.ProfileParams(path, "passphrase", "")
+	assert.NotNil(t, err)
+
+	// The TLS files that cannot be read are reported when the params are created
+	assert.Nil(t, os.Remove(keyFile))
+	_, err = v3client.ProfileParams(path, "passphrase", "prod")
+	assert.NotNil(t, err)
+}
+
+func TestDeriveAccessCredentialsUsesProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "creds")
+	assert.Nil(t, v3client.WriteCredentialsFile(path, []byte(profilesYaml), "passphrase"))
+
+	t.Setenv(v3client.AreaIdEnv, "")
+	t.Setenv(v3client.ProfileEnv, "prod")
+	creds := v3client.DeriveAccessCredentials(path, "passphrase", nil)
+	assert.Equal(t, "prodArea", creds.AreaId)
+	assert.Equal(t, 20, creds.MaxQPS)
+
+	p, err := v3client.DeriveAccessProfile(path, "passphrase", &v3client.MasheryV3Credentials{Username: "cliUser"})
+	assert.Nil(t, err)
+	assert.Equal(t, "prodArea", p.AreaId)
+	assert.Equal(t, "cliUser", p.Username)
+	assert.Equal(t, "https://api.example.com/v3/token", p.TokenEndpoint)
+	assert.Equal(t, "/etc/ca.pem", p.TLS.CABundleFile)
+
+	// The file that cannot be read is reported to the caller, and skipped by DeriveAccessCredentials
+	_, err = v3client.DeriveAccessProfile(path, "wrong passphrase", nil)
+	assert.NotNil(t, err)
+	assert.Equal(t, "", v3client.DeriveAccessCredentials(path, "wrong passphrase", nil).AreaId)
+
+	t.Setenv(v3client.ProfileEnv, "missing")
+	_, err = v3client.DeriveAccessProfile(path, "passphrase", nil)
+	assert.NotNil(t, err)
+}
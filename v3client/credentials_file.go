@@ -0,0 +1,230 @@
+package v3client
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/errwrap"
+	"golang.org/x/crypto/pbkdf2"
+	"gopkg.in/yaml.v2"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// credentialsFileMagic Header identifying the versioned credentials file. Files without the header are legacy
+// files, encrypted with the 32-character password used directly as the AES key.
+var credentialsFileMagic = []byte("MASHCRED")
+
+const (
+	credentialsFileVersion1 byte = 1
+	kdfPBKDF2SHA256         byte = 1
+
+	credentialsSaltSize = 16
+	credentialsKeySize  = 32
+	// maxCredentialsKDFIterations Upper bound of the iterations accepted from the file header
+	maxCredentialsKDFIterations = 10_000_000
+)
+
+// DefaultCredentialsKDFIterations Number of PBKDF2-HMAC-SHA256 iterations the key of the credentials file is
+// derived with
+const DefaultCredentialsKDFIterations = 600_000
+
+// CredentialsFilePermissions Permissions the credentials file is written with
+const CredentialsFilePermissions os.FileMode = 0600
+
+// EncryptCredentialsData Encrypts the data into the versioned credentials file format: the magic header, the
+// format version, the KDF parameters and the salt, followed by the nonce and the AES-GCM ciphertext. The key is
+// derived from the passphrase, which can be of any length, with PBKDF2-HMAC-SHA256. The header is authenticated
+// with the ciphertext.
+func EncryptCredentialsData(plain []byte, passphrase string) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase is required")
+	}
+
+	salt := make([]byte, credentialsSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, &errwrap.WrappedError{Context: "generating salt", Cause: err}
+	}
+
+	header := bytes.Buffer{}
+	header.Write(credentialsFileMagic)
+	header.WriteByte(credentialsFileVersion1)
+	header.WriteByte(kdfPBKDF2SHA256)
+	_ = binary.Write(&header, binary.BigEndian, uint32(DefaultCredentialsKDFIterations))
+	header.WriteByte(byte(len(salt)))
+	header.Write(salt)
+
+	gcm, err := credentialsCipher(pbkdf2SHA256([]byte(passphrase), salt, DefaultCredentialsKDFIterations, credentialsKeySize))
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, &errwrap.WrappedError{Context: "cannot initialize nonce", Cause: err}
+	}
+
+	rv := append(header.Bytes(), nonce...)
+	return gcm.Seal(rv, nonce, plain, header.Bytes()), nil
+}
+
+// DecryptCredentialsData Decrypts the data of either the versioned or the legacy credentials file.
+func DecryptCredentialsData(data []byte, passphrase string) ([]byte, error) {
+	if !IsVersionedCredentialsData(data) {
+		return decryptLegacyCredentialsData(data, passphrase)
+	}
+
+	r := bytes.NewReader(data[len(credentialsFileMagic):])
+	var version, kdf, saltLen byte
+	var iterations uint32
+
+	if err := binary.Read(r, binary.BigEndian, &version); err != nil {
+		return nil, errors.New("credentials file header is truncated")
+	} else if version != credentialsFileVersion1 {
+		return nil, fmt.Errorf("unsupported credentials file version %d", version)
+	}
+
+	if err := binary.Read(r, binary.BigEndian, &kdf); err != nil {
+		return nil, errors.New("credentials file header is truncated")
+	} else if kdf != kdfPBKDF2SHA256 {
+		return nil, fmt.Errorf("unsupported key derivation function %d", kdf)
+	}
+
+	if err := binary.Read(r, binary.BigEndian, &iterations); err != nil {
+		return nil, errors.New("credentials file header is truncated")
+	} else if iterations == 0 || iterations > maxCredentialsKDFIterations {
+		return nil, fmt.Errorf("invalid number of key derivation iterations %d", iterations)
+	}
+
+	if err := binary.Read(r, binary.BigEndian, &saltLen); err != nil {
+		return nil, errors.New("credentials file header is truncated")
+	}
+	salt := make([]byte, saltLen)
+	if _, err := io.ReadFull(r, salt); err != nil {
+		return nil, errors.New("credentials file header is truncated")
+	}
+
+	headerLen := len(data) - r.Len()
+	gcm, err := credentialsCipher(pbkdf2SHA256([]byte(passphrase), salt, int(iterations), credentialsKeySize))
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Len() < gcm.NonceSize() {
+		return nil, errors.New("credentials file is truncated")
+	}
+	nonce := data[headerLen : headerLen+gcm.NonceSize()]
+
+	if dat, err := gcm.Open(nil, nonce, data[headerLen+gcm.NonceSize():], data[:headerLen]); err != nil {
+		return nil, errors.New("credentials file could not be decrypted: wrong passphrase or corrupted file")
+	} else {
+		return dat, nil
+	}
+}
+
+// IsVersionedCredentialsData Whether the data is in the versioned credentials file format
+func IsVersionedCredentialsData(data []byte) bool {
+	return bytes.HasPrefix(data, credentialsFileMagic)
+}
+
+func decryptLegacyCredentialsData(ciphertext []byte, pass string) ([]byte, error) {
+	if len(pass) != 32 {
+		return []byte{}, errors.New("legacy credentials file requires the password of exactly 32 characters")
+	}
+
+	gcm, err := credentialsCipher([]byte(pass))
+	if err != nil {
+		return []byte{}, err
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return []byte{}, errors.New("credentials file is truncated")
+	}
+
+	nonce, encryptedMessage := ciphertext[:nonceSize], ciphertext[nonceSize:]
+	return gcm.Open(nil, nonce, encryptedMessage, nil)
+}
+
+func credentialsCipher(key []byte) (cipher.AEAD, error) {
+	if chr, err := aes.NewCipher(key); err != nil {
+		return nil, &errwrap.WrappedError{Context: "initializing cipher", Cause: err}
+	} else if gcm, err := cipher.NewGCM(chr); err != nil {
+		return nil, &errwrap.WrappedError{Context: "initializing counter", Cause: err}
+	} else {
+		return gcm, nil
+	}
+}
+
+// pbkdf2SHA256 derives the key with PBKDF2 (RFC 8018) using HMAC-SHA256 as the pseudo-random function.
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	return pbkdf2.Key(password, salt, iterations, keyLen, sha256.New)
+}
+
+// WriteCredentialsFile Encrypts the data into the versioned format and replaces the file atomically. The file is
+// written with CredentialsFilePermissions.
+func WriteCredentialsFile(path string, plain []byte, passphrase string) error {
+	dat, err := EncryptCredentialsData(plain, passphrase)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return &errwrap.WrappedError{Context: "creating temporary file", Cause: err}
+	}
+	defer os.Remove(tmp.Name())
+
+	if err = tmp.Chmod(CredentialsFilePermissions); err != nil {
+		_ = tmp.Close()
+		return &errwrap.WrappedError{Context: "setting permissions", Cause: err}
+	} else if _, err = tmp.Write(dat); err != nil {
+		_ = tmp.Close()
+		return &errwrap.WrappedError{Context: "writing", Cause: err}
+	} else if err = tmp.Close(); err != nil {
+		return &errwrap.WrappedError{Context: "writing", Cause: err}
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadCredentialsFile Reads the credentials from the encrypted file, either versioned or legacy.
+func LoadCredentialsFile(path string, passphrase string) (*MasheryV3Credentials, error) {
+	dat, err := ReadCiphertext(path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := MasheryV3Credentials{}
+	if err = yaml.Unmarshal(dat, &rv); err != nil {
+		return nil, &errwrap.WrappedError{Context: "parsing credentials", Cause: err}
+	}
+
+	return &rv, nil
+}
+
+// SaveCredentialsFile Writes the credentials to the file in the versioned format; a legacy file is thereby
+// migrated.
+func SaveCredentialsFile(path string, creds *MasheryV3Credentials, passphrase string) error {
+	if dat, err := yaml.Marshal(creds); err != nil {
+		return &errwrap.WrappedError{Context: "serializing credentials", Cause: err}
+	} else {
+		return WriteCredentialsFile(path, dat, passphrase)
+	}
+}
+
+// RekeyCredentialsFile Re-encrypts the file with the new passphrase, migrating a legacy file to the versioned
+// format.
+func RekeyCredentialsFile(path string, passphrase, newPassphrase string) error {
+	if dat, err := ReadCiphertext(path, passphrase); err != nil {
+		return err
+	} else {
+		return WriteCredentialsFile(path, dat, newPassphrase)
+	}
+}
@@ -0,0 +1,74 @@
+package v3client
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCredentialsFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "creds")
+	creds := MasheryV3Credentials{AreaId: "area", ApiKey: "key", Secret: "secret", Username: "user", Password: "pwd", MaxQPS: 5}
+
+	assert.Nil(t, SaveCredentialsFile(path, &creds, "short"))
+
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+	assert.Equal(t, CredentialsFilePermissions, info.Mode().Perm())
+
+	raw, _ := os.ReadFile(path)
+	assert.True(t, IsVersionedCredentialsData(raw))
+
+	loaded, err := LoadCredentialsFile(path, "short")
+	assert.Nil(t, err)
+	assert.Equal(t, creds, *loaded)
+
+	_, err = LoadCredentialsFile(path, "wrong")
+	assert.NotNil(t, err)
+
+	// Tampering with the header is detected
+	raw[len(credentialsFileMagic)+3]++
+	_, err = DecryptCredentialsData(raw, "short")
+	assert.NotNil(t, err)
+}
+
+func TestLegacyCredentialsFileIsMigratedOnRekey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "creds")
+	legacyPass := "0123456789abcdef0123456789abcdef"
+
+	chr, _ := aes.NewCipher([]byte(legacyPass))
+	gcm, _ := cipher.NewGCM(chr)
+	nonce := make([]byte, gcm.NonceSize())
+	_, _ = io.ReadFull(rand.Reader, nonce)
+	assert.Nil(t, os.WriteFile(path, gcm.Seal(nonce, nonce, []byte("areaId: legacy\n"), nil), 0600))
+
+	loaded, err := LoadCredentialsFile(path, legacyPass)
+	assert.Nil(t, err)
+	assert.Equal(t, "legacy", loaded.AreaId)
+
+	assert.Nil(t, RekeyCredentialsFile(path, legacyPass, "new passphrase"))
+
+	raw, _ := os.ReadFile(path)
+	assert.True(t, IsVersionedCredentialsData(raw))
+
+	loaded, err = LoadCredentialsFile(path, "new passphrase")
+	assert.Nil(t, err)
+	assert.Equal(t, "legacy", loaded.AreaId)
+}
+
+func TestEncryptInPlaceRefusesEncryptedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "creds")
+	assert.Nil(t, os.WriteFile(path, []byte("areaId: plain\n"), 0600))
+
+	assert.Nil(t, EncryptInPlace(path, "passphrase"))
+	assert.NotNil(t, EncryptInPlace(path, "passphrase"))
+
+	dat, err := ReadCiphertext(path, "passphrase")
+	assert.Nil(t, err)
+	assert.Equal(t, "areaId: plain\n", string(dat))
+}
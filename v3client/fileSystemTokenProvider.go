@@ -5,23 +5,45 @@ import (
 	"errors"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
 	"os"
+	"sync"
 	"time"
 )
 
+// TokenSource Retrieves the new access token where the token file contains no valid token. The current token is
+// the one last read from the file, possibly expired, or nil.
+type TokenSource func(ctx context.Context, current *masherytypes.TimedAccessTokenResponse) (*masherytypes.TimedAccessTokenResponse, error)
+
 // FileSystemTokenProvider
 // An access token provider that  serve access token that has been pre-saved and persisted. The file will be periodically
 // checked for the modification. The provider will retain the most recent successfully read response.
+//
+// Where the Source is set, the file is the token cache shared by the processes: once the token has expired, the
+// first process to lock the file retrieves the new token from the Source and saves it, while the other processes
+// wait for the lock and re-read the file.
 type FileSystemTokenProvider struct {
 	FixedTokenProvider
 
+	Source TokenSource
+
 	path               string
 	Response           *masherytypes.TimedAccessTokenResponse
 	lastFSCheck        time.Time
 	sourceLastModified time.Time
 	syncInterval       time.Duration
+
+	mutex sync.Mutex
 }
 
-func (f *FileSystemTokenProvider) HeaderAuthorization(_ context.Context) (map[string]string, error) {
+func (f *FileSystemTokenProvider) HeaderAuthorization(ctx context.Context) (map[string]string, error) {
+	if f.Source != nil {
+		if _, err := f.AccessToken(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
 	f.checkFileSync()
 
 	return map[string]string{
@@ -30,8 +52,17 @@ func (f *FileSystemTokenProvider) HeaderAuthorization(_ context.Context) (map[st
 }
 
 func (f *FileSystemTokenProvider) AccessToken(ctx context.Context) (string, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
 	f.checkFileSync()
 
+	if f.Source != nil && (f.Response == nil || f.Response.Expired()) {
+		if err := f.renew(ctx); err != nil {
+			return "", err
+		}
+	}
+
 	if f.Response == nil {
 		return "", errors.New("no saved token data found")
 	} else if f.Response.Expired() {
@@ -41,10 +72,43 @@ func (f *FileSystemTokenProvider) AccessToken(ctx context.Context) (string, erro
 	return f.FixedTokenProvider.AccessToken(ctx)
 }
 
+// renew re-reads the file under the lock, and retrieves the new token from the Source where the file still
+// contains no valid token.
+func (f *FileSystemTokenProvider) renew(ctx context.Context) error {
+	lck, err := lockTokenFile(ctx, f.path)
+	if err != nil {
+		return err
+	}
+	defer lck.Unlock()
+
+	if resp, err := LoadV3TokenResponse(f.path); err == nil && resp != nil {
+		f.accept(resp)
+		if !resp.Expired() {
+			return nil
+		}
+	}
+
+	resp, err := f.Source(ctx, f.Response)
+	if err != nil {
+		return err
+	}
+
+	f.accept(resp)
+	return writeV3TokenResponse(resp, f.path)
+}
+
+func (f *FileSystemTokenProvider) accept(resp *masherytypes.TimedAccessTokenResponse) {
+	f.Response = resp
+	f.UpdateToken(resp.AccessToken)
+	if info, err := os.Stat(f.path); err == nil {
+		f.sourceLastModified = info.ModTime()
+	}
+}
+
 func (f *FileSystemTokenProvider) checkFileSync() {
 	now := time.Now()
 	if now.Sub(f.lastFSCheck) > f.syncInterval {
-		if info, err := os.Stat(f.path); (err == nil || os.IsNotExist(err)) &&
+		if info, err := os.Stat(f.path); err == nil &&
 			!info.IsDir() &&
 			f.sourceLastModified.Before(info.ModTime()) {
 
@@ -55,9 +119,9 @@ func (f *FileSystemTokenProvider) checkFileSync() {
 
 			f.sourceLastModified = info.ModTime()
 		}
-	}
 
-	f.lastFSCheck = now
+		f.lastFSCheck = now
+	}
 }
 
 func (f *FileSystemTokenProvider) Close() {
@@ -69,9 +133,46 @@ func NewFileSystemTokenProvider() V3AccessTokenProvider {
 }
 
 func NewFileSystemTokenProviderFrom(path string) V3AccessTokenProvider {
+	return newFileSystemTokenProvider(path, nil)
+}
+
+// NewSharedFileTokenProvider Creates the provider using the file as the token cache shared across the processes;
+// the tokens are retrieved from the source once the token in the file has expired.
+func NewSharedFileTokenProvider(path string, source TokenSource) V3AccessTokenProvider {
+	return newFileSystemTokenProvider(path, source)
+}
+
+// NewCredentialsTokenSource Creates the token source retrieving the tokens with the credentials. The refresh token
+// of the current token is exchanged where possible, and the password grant is used otherwise. Each attempt is
+// reported to the OnTokenRefresh listener of the params.
+func NewCredentialsTokenSource(creds MasheryV3Credentials, params OAuthHelperParams) TokenSource {
+	helper := NewOAuthHelper(params)
+	notify := func(source string, err error) {
+		if params.OnTokenRefresh != nil {
+			params.OnTokenRefresh(source, err)
+		}
+	}
+
+	return func(_ context.Context, current *masherytypes.TimedAccessTokenResponse) (*masherytypes.TimedAccessTokenResponse, error) {
+		if current != nil && len(current.RefreshToken) > 0 {
+			resp, err := helper.ExchangeRefreshToken(&creds, current.RefreshToken)
+			notify("refresh_token", err)
+			if err == nil {
+				return resp, nil
+			}
+		}
+
+		resp, err := helper.RetrieveAccessTokenFor(&creds)
+		notify("password", err)
+		return resp, err
+	}
+}
+
+func newFileSystemTokenProvider(path string, source TokenSource) *FileSystemTokenProvider {
 	syncInterval, _ := time.ParseDuration("1m")
 
 	return &FileSystemTokenProvider{
+		Source:             source,
 		path:               path,
 		lastFSCheck:        time.Unix(0, 0),
 		sourceLastModified: time.Unix(0, 0),
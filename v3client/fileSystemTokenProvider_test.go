@@ -3,23 +3,27 @@ package v3client_test
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/stretchr/testify/assert"
 	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
-const savedFileName = "../out/sampleSavedAccessToken.json"
-
-func saveTestFile(inp *masherytypes.TimedAccessTokenResponse) bool {
+// saveTestFile saves the token into a file of the test's temporary directory, returning its path
+func saveTestFile(t *testing.T, inp *masherytypes.TimedAccessTokenResponse) (string, bool) {
+	path := filepath.Join(t.TempDir(), "sampleSavedAccessToken.json")
 	if data, err := json.Marshal(inp); err == nil {
-		err = os.WriteFile(savedFileName, data, 0644)
-		return err == nil
+		err = os.WriteFile(path, data, 0644)
+		return path, err == nil
 	} else {
-		return false
+		return path, false
 	}
-
 }
 
 func TestNewFileSystemTokenProvider(t *testing.T) {
@@ -35,7 +39,7 @@ func TestNewFileSystemTokenProvider(t *testing.T) {
 		},
 	}
 
-	saved := saveTestFile(&ref)
+	savedFileName, saved := saveTestFile(t, &ref)
 	if !saved {
 		t.Log("Test file could not be saved")
 		t.FailNow()
@@ -64,7 +68,7 @@ func TestNewFileSystemTokenProviderWithExpiredToken(t *testing.T) {
 		},
 	}
 
-	saved := saveTestFile(&ref)
+	savedFileName, saved := saveTestFile(t, &ref)
 	if !saved {
 		t.Log("Test file could not be saved")
 		t.FailNow()
@@ -77,3 +81,66 @@ func TestNewFileSystemTokenProviderWithExpiredToken(t *testing.T) {
 	}
 
 }
+
+func TestSharedFileTokenProviderRetrievesTokenOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+
+	var calls int32
+	source := func(_ context.Context, current *masherytypes.TimedAccessTokenResponse) (*masherytypes.TimedAccessTokenResponse, error) {
+		n := atomic.AddInt32(&calls, 1)
+		// Simulate the latency of the token endpoint
+		time.Sleep(50 * time.Millisecond)
+
+		return &masherytypes.TimedAccessTokenResponse{
+			Obtained: time.Now(),
+			AccessTokenResponse: masherytypes.AccessTokenResponse{
+				AccessToken: fmt.Sprintf("token-%d", n),
+				ExpiresIn:   3600,
+			},
+		}, nil
+	}
+
+	// Each provider simulates a separate process: it holds its own lock of the file.
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			p := v3client.NewSharedFileTokenProvider(path, source)
+			hdr, err := p.HeaderAuthorization(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, "Bearer token-1", hdr["Authorization"])
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// The expired token is replaced, and the source receives it
+	expired := masherytypes.TimedAccessTokenResponse{
+		Obtained: time.Now().Add(-2 * time.Hour),
+		AccessTokenResponse: masherytypes.AccessTokenResponse{
+			AccessToken:  "expired",
+			RefreshToken: "refresh",
+			ExpiresIn:    3600,
+		},
+	}
+	assert.Nil(t, v3client.PersistV3TokenResponse(&expired, path))
+	if info, err := os.Stat(path); assert.Nil(t, err) {
+		assert.Equal(t, v3client.CredentialsFilePermissions, info.Mode().Perm())
+	}
+
+	var received string
+	p := v3client.NewSharedFileTokenProvider(path, func(ctx context.Context, current *masherytypes.TimedAccessTokenResponse) (*masherytypes.TimedAccessTokenResponse, error) {
+		received = current.RefreshToken
+		return source(ctx, current)
+	})
+	tkn, err := p.AccessToken(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "token-2", tkn)
+	assert.Equal(t, "refresh", received)
+
+	saved, err := v3client.LoadV3TokenResponse(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "token-2", saved.AccessToken)
+}
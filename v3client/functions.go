@@ -1,16 +1,11 @@
 package v3client
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/errwrap"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
-	"gopkg.in/yaml.v2"
-	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -22,6 +17,7 @@ const ApiKeyEnv = "MASHERY_V3API_KEY"
 const ApiKeySecretEnv = "MASHERY_V3API_SECRET"
 const UserNameEnv = "MASHERY_USER"
 const UserPassEnv = "MASHERY_PASS"
+const ProfileEnv = "MASHERY_PROFILE"
 
 const userSettingsFile = ".mashery-v3-credentials"
 
@@ -53,84 +49,87 @@ func DefaultCredentialsFile() string {
 	}
 }
 
+// EncryptInPlace Encrypts the plain-text file with the passphrase, in the versioned credentials file format. The file
+// that is already encrypted in this format is not changed, and an error is returned.
 func EncryptInPlace(path string, pass string) error {
-	if len(pass) != 32 {
-		return errors.New("password should be 32 characters")
-	}
-
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
 		return &errwrap.WrappedError{Context: "reading", Cause: err}
+	} else if IsVersionedCredentialsData(data) {
+		return errors.New("file is already encrypted; use rekey to change its passphrase")
 	}
 
-	if cphr, err := aes.NewCipher([]byte(pass)); err != nil {
-		return &errwrap.WrappedError{Context: "obtaining cipher", Cause: err}
-	} else if gcm, err := cipher.NewGCM(cphr); err != nil {
-		return &errwrap.WrappedError{Context: "failed to initialize counter", Cause: err}
-	} else {
-		nonce := make([]byte, gcm.NonceSize())
-		if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
-			return &errwrap.WrappedError{Context: "cannot initialize nonce", Cause: err}
-		} else {
-			return ioutil.WriteFile(path, gcm.Seal(nonce, nonce, data, nil), 0777)
-		}
-	}
+	return WriteCredentialsFile(path, data, pass)
 }
 
+// ReadCiphertext Decrypts the credentials file, either in the versioned or the legacy format.
 func ReadCiphertext(fileName string, pass string) ([]byte, error) {
-	if len(pass) != 32 {
-		return []byte{}, errors.New("password must be exactly 32 characters")
-	}
-
 	if ciphertext, err := ioutil.ReadFile(fileName); err != nil {
 		return []byte{}, &errwrap.WrappedError{Context: "reading source file", Cause: err}
-	} else if chr, err := aes.NewCipher([]byte(pass)); err != nil {
-		return []byte{}, &errwrap.WrappedError{Context: "initializing cipher", Cause: err}
-	} else if gcmDecrypt, err := cipher.NewGCM(chr); err != nil {
-		return []byte{}, &errwrap.WrappedError{Context: "initializing counter", Cause: err}
 	} else {
-		nonceSize := gcmDecrypt.NonceSize()
-		nonce, encryptedMessage := ciphertext[:nonceSize], ciphertext[nonceSize:]
-
-		dat, err := gcmDecrypt.Open(nil, nonce, encryptedMessage, nil)
-		return dat, err
+		return DecryptCredentialsData(ciphertext, pass)
 	}
 }
 
-func tryReadCredentialSettingsFromYamlFile(m *MasheryV3Credentials, file, pass string) {
-	if _, err := os.Stat(file); err == nil || os.IsExist(err) {
-		if dat, err := ReadCiphertext(file, pass); err == nil {
-			var fsCreds MasheryV3Credentials
-			if err = yaml.Unmarshal(dat, &fsCreds); err == nil {
-				m.Inherit(&fsCreds)
-			}
-		} else {
-			fmt.Println(err)
-		}
+// tryReadCredentialSettingsFromYamlFile returns the selected profile of the credentials file, or nil where the file
+// doesn't exist.
+func tryReadCredentialSettingsFromYamlFile(file, pass string) (*CredentialsProfile, error) {
+	if _, err := os.Stat(file); err != nil && !os.IsExist(err) {
+		// The settings file doesn't exist.
+		return nil, nil
+	}
+
+	if dat, err := ReadCiphertext(file, pass); err != nil {
+		return nil, &errwrap.WrappedError{Context: "reading credentials file", Cause: err}
+	} else if profiles, err := ParseCredentialsProfiles(dat); err != nil {
+		return nil, &errwrap.WrappedError{Context: "parsing credentials file", Cause: err}
+	} else if profile, err := profiles.Profile(""); err != nil {
+		return nil, &errwrap.WrappedError{Context: "selecting credentials profile", Cause: err}
+	} else {
+		return profile, nil
 	}
-	// else: the settings file doesn't exist.
 }
 
 // DeriveAccessCredentials derives credentials from all applicable sources, including the command line
 // The sequence of derivation is:
 // - Environment variables, overridden by
-// - User settings file, overridden by
+// - User settings file, using the profile selected by the MASHERY_PROFILE variable, overridden by
 // - Credentials file in the working directory, overriden by
 // - Command line arguments
+//
+// A credentials file that cannot be read is skipped; use DeriveAccessProfile to receive the error.
 func DeriveAccessCredentials(customFile, filePass string, fallbackCreds *MasheryV3Credentials) MasheryV3Credentials {
-	creds := MasheryV3Credentials{
-		AreaId:   os.Getenv(AreaIdEnv),
-		ApiKey:   os.Getenv(ApiKeyEnv),
-		Secret:   os.Getenv(ApiKeySecretEnv),
-		Username: os.Getenv(UserNameEnv),
-		Password: os.Getenv(UserPassEnv),
+	rv, _ := DeriveAccessProfile(customFile, filePass, fallbackCreds)
+	return rv.MasheryV3Credentials
+}
+
+// DeriveAccessProfile derives the credentials in the same sequence as DeriveAccessCredentials, and returns them
+// together with the endpoints and the TLS options of the profile selected in the credentials file.
+//
+// An error is returned where the credentials file exists, but cannot be read, or has no selected profile; the
+// credentials derived from the other sources are returned with it.
+func DeriveAccessProfile(customFile, filePass string, fallbackCreds *MasheryV3Credentials) (CredentialsProfile, error) {
+	rv := CredentialsProfile{
+		MasheryV3Credentials: MasheryV3Credentials{
+			AreaId:   os.Getenv(AreaIdEnv),
+			ApiKey:   os.Getenv(ApiKeyEnv),
+			Secret:   os.Getenv(ApiKeySecretEnv),
+			Username: os.Getenv(UserNameEnv),
+			Password: os.Getenv(UserPassEnv),
+		},
 	}
 
-	tryReadCredentialSettingsFromYamlFile(&creds, customFile, filePass)
+	profile, err := tryReadCredentialSettingsFromYamlFile(customFile, filePass)
+	if profile != nil {
+		rv.Inherit(&profile.MasheryV3Credentials)
+		rv.Endpoint = profile.Endpoint
+		rv.TokenEndpoint = profile.TokenEndpoint
+		rv.TLS = profile.TLS
+	}
 
 	if fallbackCreds != nil {
-		creds.Inherit(fallbackCreds)
+		rv.Inherit(fallbackCreds)
 	}
 
-	return creds
+	return rv, err
 }
@@ -280,19 +280,10 @@ func (crud *GenericCRUD[TParent, TIdent, T]) FetchAll(ctx context.Context, id TP
 }
 
 func (crud *GenericCRUD[TParent, TIdent, T]) FetchFiltered(ctx context.Context, id TParent, filter map[string]string, c *transport.HttpTransport) ([]T, error) {
-	if resourceURL, err := crud.Decorator.ResourceForParent(id); err != nil {
+	if spec, err := crud.listSpec(ctx, id, filter); err != nil {
 		return nil, err
 	} else {
-		objectListSpecBuilder := transport.ObjectListFetchSpecBuilder[T]{}
-		objectListSpecBuilder.
-			WithValueFactory(crud.Decorator.ValueArraySupplier).
-			WithResource(resourceURL).
-			WithQuery(crud.querySupplier(ctx)).
-			WithMergedQuery(crud.toFilterQuery(filter)).
-			WithAppContext(crud.AppContext).
-			WithPagination(crud.Decorator.Pagination)
-
-		if all, fetchAllErr := crud.doFetchAll(ctx, objectListSpecBuilder.Build(), c); fetchAllErr != nil {
+		if all, fetchAllErr := crud.doFetchAll(ctx, spec, c); fetchAllErr != nil {
 			return nil, fetchAllErr
 		} else {
 			if len(all) > 0 && crud.Decorator.AcceptParentIdent != nil {
@@ -307,6 +298,40 @@ func (crud *GenericCRUD[TParent, TIdent, T]) FetchFiltered(ctx context.Context,
 	}
 }
 
+// Paginate Returns the paginator streaming the objects of the parent page by page.
+func (crud *GenericCRUD[TParent, TIdent, T]) Paginate(ctx context.Context, id TParent, filter map[string]string, c *transport.HttpTransport, opts transport.PaginatorOptions) (*transport.Paginator[T], error) {
+	if spec, err := crud.listSpec(ctx, id, filter); err != nil {
+		return nil, err
+	} else {
+		rv := transport.NewPaginator(spec, c, opts)
+		if crud.Decorator.AcceptParentIdent != nil {
+			rv.Accept = func(t *T) {
+				crud.Decorator.AcceptParentIdent(id, t)
+			}
+		}
+
+		return rv, nil
+	}
+}
+
+// listSpec Builds the specification fetching the objects of the parent matching the filter.
+func (crud *GenericCRUD[TParent, TIdent, T]) listSpec(ctx context.Context, id TParent, filter map[string]string) (transport.ObjectListFetchSpec[T], error) {
+	if resourceURL, err := crud.Decorator.ResourceForParent(id); err != nil {
+		return transport.ObjectListFetchSpec[T]{}, err
+	} else {
+		objectListSpecBuilder := transport.ObjectListFetchSpecBuilder[T]{}
+		objectListSpecBuilder.
+			WithValueFactory(crud.Decorator.ValueArraySupplier).
+			WithResource(resourceURL).
+			WithQuery(crud.querySupplier(ctx)).
+			WithMergedQuery(crud.toFilterQuery(filter)).
+			WithAppContext(crud.AppContext).
+			WithPagination(crud.Decorator.Pagination)
+
+		return objectListSpecBuilder.Build(), nil
+	}
+}
+
 func (crud *GenericCRUD[TParent, TIdent, T]) toFilterQuery(filter map[string]string) url.Values {
 	if len(filter) > 0 {
 		srchAtoms := make([]string, len(filter))
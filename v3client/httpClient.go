@@ -2,6 +2,7 @@ package v3client
 
 import (
 	"context"
+	"errors"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
 	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
 	"net/http"
@@ -21,6 +22,8 @@ const tokenFile string = ".mashery-logon"
 // hour, and repeated logon would be necessary.
 // - ClientCredentialsProvider can support operations of exceeding 1 hour by using Mashery V3 API to retrieve and refresh
 // the access token.
+// - NewSharedFileTokenProvider shares a single access token between the processes via a locked cache file, so that
+// parallel jobs do not each log on.
 //
 // The calling code has to pick an appropriate provider depending on the context.
 type V3AccessTokenProvider interface {
@@ -47,7 +50,32 @@ type Params struct {
 	Authorizer    transport.Authorizer
 	QPS           int64
 	AvgNetLatency time.Duration
-
+	// RateLimiter paces the calls made by the client. Supply the same limiter to all clients using the same
+	// API key. If not set, a token bucket limiter observing QPS is created.
+	RateLimiter transport.RateLimiter
+	// RetryPolicy, if set, replaces the fixed back-off and 400 retries of the default pipeline.
+	RetryPolicy *transport.RetryPolicy
+	// Metrics, if set, is added to the end of the default pipeline.
+	Metrics *transport.Metrics
+	// Coalescer, if set, collapses identical concurrent GET calls into one exchange. The same coalescer
+	// can be shared between clients.
+	Coalescer *transport.RequestCoalescer
+	// Tracer, if set, receives the spans of each call
+	Tracer transport.Tracer
+	// AdaptiveQPS, if set, tunes the rate of its limiter, which replaces the RateLimiter, to the allowance observed
+	// from Mashery. The QPS is not used then. AdaptiveQPS cannot be combined with the KeyPool.
+	AdaptiveQPS *transport.AdaptiveQPS
+	// KeyPool, if set, dispatches the calls across the keys of the pool. The pool paces the calls with the limiters of
+	// its keys instead of the RateLimiter, and replaces the Authorizer.
+	KeyPool *transport.KeyPool
+	// DryRun, if set, records the POST, PUT and DELETE calls into the plan instead of sending them to Mashery.
+	DryRun *transport.DryRunPlan
+	// CircuitBreaker, if set, is added to the default pipeline after the retries, so that it considers the final
+	// outcome of each call.
+	CircuitBreaker *transport.CircuitBreaker
+
+	// Pipeline, if set, replaces the default pipeline. The middlewares of the AdaptiveQPS, KeyPool, RetryPolicy,
+	// CircuitBreaker, Coalescer and Metrics are then not added: include them in the Pipeline where needed.
 	Pipeline []transport.ChainedMiddlewareFunc
 }
 
@@ -65,6 +93,19 @@ func (p *Params) FillDefaults() {
 	if p.AvgNetLatency <= 0 {
 		p.AvgNetLatency = time.Millisecond * 147
 	}
+	// The calls dispatched by the key pool are authorized with, and paced by, the key they are dispatched to.
+	if p.KeyPool != nil {
+		p.Authorizer = p.KeyPool
+	}
+	if p.AdaptiveQPS != nil {
+		p.RateLimiter = p.AdaptiveQPS.Limiter
+		if p.Metrics != nil && p.Metrics.EffectiveQPS == nil {
+			p.Metrics.EffectiveQPS = p.AdaptiveQPS.QPS
+		}
+	}
+	if p.RateLimiter == nil {
+		p.RateLimiter = transport.NewTokenBucketRateLimiter(float64(p.QPS), 1)
+	}
 
 	// Default is set if no TLS configuration is supplied, and where no explicit flag is set
 	// to delegate the trust to the system settings.
@@ -72,28 +113,83 @@ func (p *Params) FillDefaults() {
 		p.TLSConfig = transport.DefaultTLSConfig()
 	}
 
-	if len(p.Pipeline) == 0 {
-		p.Pipeline = []transport.ChainedMiddlewareFunc{
-			transport.ThrottleFunc,
-			transport.BreakOnDeveloperOverRateFunc,
-			transport.BackOffOnDeveloperOverQPSFunc,
-			transport.ErrorOn404Func,
-			transport.RetryOn400Func,
-			transport.EnsureBodyWasRead,
-			transport.UnmarshalServerError,
-		}
+	if len(p.Pipeline) > 0 {
+		return
+	}
+
+	if p.KeyPool != nil {
+		p.Pipeline = []transport.ChainedMiddlewareFunc{p.KeyPool.Middleware()}
+	} else {
+		p.Pipeline = []transport.ChainedMiddlewareFunc{transport.ThrottleFunc}
+	}
+	if p.AdaptiveQPS != nil {
+		p.Pipeline = append(p.Pipeline, p.AdaptiveQPS.Middleware())
+	}
+	p.Pipeline = append(p.Pipeline, transport.BreakOnDeveloperOverRateFunc)
+
+	if p.RetryPolicy != nil {
+		p.Pipeline = append(p.Pipeline, p.RetryPolicy.Middleware())
+	} else {
+		p.Pipeline = append(p.Pipeline, transport.BackOffOnDeveloperOverQPSFunc)
+	}
+
+	if p.CircuitBreaker != nil {
+		p.Pipeline = append(p.Pipeline, p.CircuitBreaker.Middleware())
+	}
+
+	if p.Coalescer != nil {
+		p.Pipeline = append(p.Pipeline, p.Coalescer.Middleware())
 	}
+
+	p.Pipeline = append(p.Pipeline, transport.ErrorOn404Func)
+	if p.RetryPolicy == nil {
+		p.Pipeline = append(p.Pipeline, transport.RetryOn400Func)
+	}
+
+	p.Pipeline = append(p.Pipeline,
+		transport.EnsureBodyWasRead,
+		transport.UnmarshalServerError,
+	)
+
+	if p.Metrics != nil {
+		p.Pipeline = append(p.Pipeline, p.Metrics.Middleware())
+	}
+}
+
+// Validate Checks that the client can be created from these params. The TLS options that cannot be applied are
+// reported here, rather than by each call of the client.
+func (p *Params) Validate() error {
+	if p.KeyPool != nil && p.AdaptiveQPS != nil {
+		return errors.New("adaptive qps cannot be combined with the key pool, which paces the calls with the limiters of its keys")
+	}
+
+	return p.HTTPClientParams.Validate()
 }
 
+// NewHttpClient Creates the client. Where the params are not valid, the calls of the client fail; use
+// NewValidatedHttpClient to receive the error when the client is created.
 func NewHttpClient(p Params) Client {
 	return newHttpClientWithSchema(p, StandardClientMethodSchema())
 }
 
+// NewValidatedHttpClient Creates the client, returning the error where the params are not valid.
+func NewValidatedHttpClient(p Params) (Client, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return newHttpClientWithSchema(p, StandardClientMethodSchema()), nil
+}
+
 func NewHttpClientWithBadRequestAutoRetries(p Params) Client {
 	return newHttpClientWithSchema(p, AutoRetryOnBadRequestMethodSchema())
 }
 
 func newHttpClientWithSchema(p Params, s *ClientMethodSchema) Client {
+	// The client created from the invalid params fails each call with the validation error.
+	if err := p.Validate(); err != nil {
+		p.ExplicitHttpExecutor = transport.NewFailingExecutor(err)
+	}
 	p.FillDefaults()
 
 	impl := createHTTPTransport(p)
@@ -116,11 +212,14 @@ func createHTTPTransport(p Params) transport.HttpTransport {
 		AvgNetLatency: p.AvgNetLatency,
 		Mutex:         &sync.Mutex{},
 		MaxQPS:        p.QPS,
+		RateLimiter:   p.RateLimiter,
 
 		HttpExecutor: p.CreateHttpExecutor(),
 
 		ExchangeListener: p.ExchangeListener,
 		Pipeline:         transport.BuildPipeline(transport.ExecuteFunction, p.Pipeline),
+		Tracer:           p.Tracer,
+		DryRun:           p.DryRun,
 	}
 }
 
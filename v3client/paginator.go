@@ -0,0 +1,47 @@
+package v3client
+
+import (
+	"context"
+	"errors"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+)
+
+// PaginatingClient Streams the large Mashery collections page by page instead of materializing them in memory.
+// The clients created with NewHttpClient implement this interface.
+type PaginatingClient interface {
+	PaginateServices(ctx context.Context, filter map[string]string, opts transport.PaginatorOptions) (*transport.Paginator[masherytypes.Service], error)
+	PaginateMembers(ctx context.Context, filter map[string]string, opts transport.PaginatorOptions) (*transport.Paginator[masherytypes.Member], error)
+	PaginateApplications(ctx context.Context, filter map[string]string, opts transport.PaginatorOptions) (*transport.Paginator[masherytypes.Application], error)
+	PaginatePackageKeys(ctx context.Context, filter map[string]string, opts transport.PaginatorOptions) (*transport.Paginator[masherytypes.PackageKey], error)
+}
+
+var errNoTransport = errors.New("this client has no transport to paginate with")
+
+func (c *PluggableClient) PaginateServices(ctx context.Context, filter map[string]string, opts transport.PaginatorOptions) (*transport.Paginator[masherytypes.Service], error) {
+	if c.transport == nil {
+		return nil, errNoTransport
+	}
+	return serviceCRUD.Paginate(ctx, 0, filter, c.transport, opts)
+}
+
+func (c *PluggableClient) PaginateMembers(ctx context.Context, filter map[string]string, opts transport.PaginatorOptions) (*transport.Paginator[masherytypes.Member], error) {
+	if c.transport == nil {
+		return nil, errNoTransport
+	}
+	return memberCRUD.Paginate(ctx, 0, filter, c.transport, opts)
+}
+
+func (c *PluggableClient) PaginateApplications(ctx context.Context, filter map[string]string, opts transport.PaginatorOptions) (*transport.Paginator[masherytypes.Application], error) {
+	if c.transport == nil {
+		return nil, errNoTransport
+	}
+	return applicationCRUD.Paginate(ctx, masherytypes.MemberIdentifier{}, filter, c.transport, opts)
+}
+
+func (c *PluggableClient) PaginatePackageKeys(ctx context.Context, filter map[string]string, opts transport.PaginatorOptions) (*transport.Paginator[masherytypes.PackageKey], error) {
+	if c.transport == nil {
+		return nil, errNoTransport
+	}
+	return packageKeyCRUD.Paginate(ctx, 0, filter, c.transport, opts)
+}
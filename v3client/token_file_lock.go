@@ -0,0 +1,34 @@
+package v3client
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// tokenFileLockPoll Interval at which the process waiting for the lock of the token file re-tries it
+const tokenFileLockPoll = 50 * time.Millisecond
+
+// tokenFileLock Exclusive lock of the token file held across the processes. The lock is taken on the companion
+// file with the .lock suffix, since the token file itself is replaced on each write.
+type tokenFileLock struct {
+	path string
+	file *os.File
+}
+
+// lockTokenFile waits until the lock of the token file is obtained, or the context is done.
+func lockTokenFile(ctx context.Context, path string) (*tokenFileLock, error) {
+	lck := &tokenFileLock{path: path + ".lock"}
+
+	for {
+		if ok, err := lck.tryLock(); err != nil {
+			return nil, err
+		} else if ok {
+			return lck, nil
+		}
+
+		if err := sleepUntilDone(ctx, tokenFileLockPoll); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,36 @@
+//go:build !unix
+
+package v3client
+
+import (
+	"os"
+	"time"
+)
+
+// staleTokenFileLockAge Age after which the lock file is considered left behind by a crashed process
+const staleTokenFileLockAge = time.Minute
+
+// The lock is held by the process that created the lock file; the file is removed on unlock.
+func (l *tokenFileLock) tryLock() (bool, error) {
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err == nil {
+		l.file = f
+		return true, nil
+	} else if !os.IsExist(err) {
+		return false, err
+	}
+
+	if info, statErr := os.Stat(l.path); statErr == nil && time.Since(info.ModTime()) > staleTokenFileLockAge {
+		_ = os.Remove(l.path)
+	}
+
+	return false, nil
+}
+
+func (l *tokenFileLock) Unlock() error {
+	err := l.file.Close()
+	if rmErr := os.Remove(l.path); err == nil {
+		err = rmErr
+	}
+	return err
+}
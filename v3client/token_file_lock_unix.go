@@ -0,0 +1,33 @@
+//go:build unix
+
+package v3client
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+func (l *tokenFileLock) tryLock() (bool, error) {
+	f, err := os.OpenFile(l.path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return false, err
+	}
+
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	l.file = f
+	return true, nil
+}
+
+// Unlock Releases the lock. The lock is released by the operating system as well should the process exit.
+func (l *tokenFileLock) Unlock() error {
+	_ = syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	return l.file.Close()
+}
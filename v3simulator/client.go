@@ -0,0 +1,37 @@
+package v3simulator
+
+import (
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+)
+
+// ClientEndpoint Endpoint of the clients created with ClientParams; matches the BasePath of NewSimulator
+const ClientEndpoint = "http://localhost/v3/rest"
+
+// ClientOption Modifies the parameters of the client created with ClientParams
+type ClientOption func(p *v3client.Params)
+
+// ClientParams Returns the parameters of the client sending the calls to the executor, e.g. the Simulator or a
+// transport.Cassette, instead of Mashery. The calls are authorized with a fixed bearer token and paced to 1000 QPS;
+// the options are applied afterwards, in order.
+func ClientParams(exec transport.HttpExecutor, opts ...ClientOption) v3client.Params {
+	rv := v3client.Params{
+		MashEndpoint: ClientEndpoint,
+		Authorizer:   transport.NewBearerAuthorizer("sim-token"),
+		QPS:          1000,
+		HTTPClientParams: transport.HTTPClientParams{
+			ExplicitHttpExecutor: exec,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(&rv)
+	}
+
+	return rv
+}
+
+// NewClient Creates the client with the ClientParams
+func NewClient(exec transport.HttpExecutor, opts ...ClientOption) v3client.Client {
+	return v3client.NewHttpClient(ClientParams(exec, opts...))
+}
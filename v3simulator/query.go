@@ -0,0 +1,90 @@
+package v3simulator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// fieldSelection is a parsed `fields` query parameter. Nested fields (e.g. `services.endpoints`) are
+// represented as sub-selections; an empty sub-selection selects the whole value.
+type fieldSelection map[string]fieldSelection
+
+func parseFields(str string) fieldSelection {
+	if len(str) == 0 {
+		return nil
+	}
+
+	rv := fieldSelection{}
+	for _, f := range strings.Split(str, ",") {
+		f = strings.TrimSpace(f)
+		if len(f) == 0 {
+			continue
+		}
+
+		cur := rv
+		for _, part := range strings.Split(f, ".") {
+			next, ok := cur[part]
+			if !ok || next == nil {
+				next = fieldSelection{}
+				cur[part] = next
+			}
+			cur = next
+		}
+	}
+
+	return rv
+}
+
+// project applies the selection to the attribute value.
+func (fs fieldSelection) project(v interface{}) interface{} {
+	if len(fs) == 0 {
+		return v
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		rv := map[string]interface{}{}
+		for k, sub := range fs {
+			if val, ok := t[k]; ok {
+				rv[k] = sub.project(val)
+			}
+		}
+		return rv
+	case []interface{}:
+		rv := make([]interface{}, len(t))
+		for i, val := range t {
+			rv[i] = fs.project(val)
+		}
+		return rv
+	default:
+		return v
+	}
+}
+
+// objectFilter is a parsed `filter` query parameter, e.g. `name:foo,status:active`.
+type objectFilter map[string]string
+
+func parseFilter(str string) objectFilter {
+	rv := objectFilter{}
+	if len(str) == 0 {
+		return rv
+	}
+
+	for _, atom := range strings.Split(str, ",") {
+		if k, v, found := strings.Cut(atom, ":"); found {
+			rv[k] = v
+		}
+	}
+
+	return rv
+}
+
+func (of objectFilter) matches(attrs map[string]interface{}) bool {
+	for k, v := range of {
+		if attrVal, ok := attrs[k]; !ok || attrVal == nil || fmt.Sprint(attrVal) != v {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,153 @@
+package v3simulator
+
+import (
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"strings"
+)
+
+type collectionKind int
+
+const (
+	// ownedCollection objects are stored under the path of the collection itself.
+	ownedCollection collectionKind = iota
+	// linkCollection objects are references to objects stored elsewhere, e.g. services of a package plan.
+	linkCollection
+	// aliasCollection is a view over a root collection, e.g. applications of a member.
+	aliasCollection
+)
+
+// collectionSpec describes a V3 collection resource that the simulator understands.
+type collectionSpec struct {
+	pattern    []string
+	kind       collectionKind
+	pagination transport.PaginationType
+
+	// linkTarget returns the canonical path of the object a link collection entry refers to.
+	linkTarget func(seg []string, id string) string
+	// aliasRoot is the root collection an alias collection is a view over.
+	aliasRoot string
+
+	// prepare is applied to the attributes of a newly created object.
+	prepare func(attrs map[string]interface{})
+}
+
+var collections = []*collectionSpec{
+	{pattern: p("services"), pagination: transport.PerItem},
+	{pattern: p("services", "*", "endpoints"), pagination: transport.PerPage},
+	{pattern: p("services", "*", "endpoints", "*", "methods"), pagination: transport.PerPage},
+	{pattern: p("services", "*", "endpoints", "*", "methods", "*", "responseFilters"), pagination: transport.PerPage},
+
+	{pattern: p("packages"), pagination: transport.PerItem},
+	{pattern: p("packages", "*", "plans"), pagination: transport.PerPage},
+	{
+		pattern:    p("packages", "*", "plans", "*", "services"),
+		kind:       linkCollection,
+		pagination: transport.PerPage,
+		linkTarget: func(_ []string, id string) string {
+			return fmt.Sprintf("/services/%s", id)
+		},
+	},
+	{
+		pattern:    p("packages", "*", "plans", "*", "services", "*", "endpoints"),
+		kind:       linkCollection,
+		pagination: transport.PerItem,
+		linkTarget: func(seg []string, id string) string {
+			return fmt.Sprintf("/services/%s/endpoints/%s", seg[5], id)
+		},
+	},
+
+	{pattern: p("members"), pagination: transport.PerPage, prepare: prepareMember},
+	{pattern: p("applications"), pagination: transport.PerPage},
+	{pattern: p("members", "*", "applications"), kind: aliasCollection, aliasRoot: "/applications", pagination: transport.PerPage},
+	{pattern: p("packageKeys"), pagination: transport.PerPage, prepare: preparePackageKey},
+	{pattern: p("applications", "*", "packageKeys"), kind: aliasCollection, aliasRoot: "/packageKeys", pagination: transport.PerPage},
+}
+
+func p(seg ...string) []string {
+	return seg
+}
+
+func splitPath(path string) []string {
+	trimmed := strings.Trim(path, "/")
+	if len(trimmed) == 0 {
+		return nil
+	}
+	return strings.Split(trimmed, "/")
+}
+
+func joinPath(seg []string) string {
+	return "/" + strings.Join(seg, "/")
+}
+
+// match returns the path segments if these match this collection's pattern.
+func (cs *collectionSpec) match(seg []string) ([]string, bool) {
+	if len(seg) != len(cs.pattern) {
+		return nil, false
+	}
+
+	for i, pat := range cs.pattern {
+		if pat != "*" && pat != seg[i] {
+			return nil, false
+		}
+	}
+
+	return seg, true
+}
+
+// name of the collection, as it appears in the `fields` parameter of the parent object.
+func (cs *collectionSpec) name() string {
+	return cs.pattern[len(cs.pattern)-1]
+}
+
+// isChildOf checks whether this collection is nested directly under objects of the specified collection.
+func (cs *collectionSpec) isChildOf(parent *collectionSpec) bool {
+	if len(cs.pattern) != len(parent.pattern)+2 {
+		return false
+	}
+	for i, v := range parent.pattern {
+		if cs.pattern[i] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func findCollection(seg []string) (*collectionSpec, []string) {
+	for _, cs := range collections {
+		if m, ok := cs.match(seg); ok {
+			return cs, m
+		}
+	}
+
+	return nil, nil
+}
+
+// canonicalCollection returns the collection where objects of the alias collection are actually stored
+func canonicalCollection(cs *collectionSpec) *collectionSpec {
+	if cs.kind != aliasCollection {
+		return cs
+	}
+
+	c, _ := findCollection(splitPath(cs.aliasRoot))
+	return c
+}
+
+func prepareMember(attrs map[string]interface{}) {
+	delete(attrs, "passwdNew")
+	if _, ok := attrs["areaStatus"]; !ok {
+		attrs["areaStatus"] = "active"
+	}
+}
+
+func preparePackageKey(attrs map[string]interface{}) {
+	if v, ok := attrs["apikey"].(string); !ok || len(v) == 0 {
+		attrs["apikey"] = randomString(24)
+	}
+	if v, ok := attrs["secret"].(string); !ok || len(v) == 0 {
+		attrs["secret"] = randomString(10)
+	}
+	if _, ok := attrs["status"]; !ok {
+		attrs["status"] = "active"
+	}
+}
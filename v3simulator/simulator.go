@@ -0,0 +1,495 @@
+package v3simulator
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/transport"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const masheryTimeFormat = "2006-01-02T15:04:05.000-0700"
+
+// Interceptor allows tests to short-circuit the simulator for selected requests, e.g. to inject a
+// developer-over-QPS response. Returning nil lets the simulator process the request as usual.
+type Interceptor func(r *http.Request) *http.Response
+
+type record struct {
+	path string
+	// collection path where this record is stored
+	collection string
+	// owner of the record stored in a root collection, e.g. member of an application
+	owner string
+	// target is the canonical path of the object a link record refers to
+	target string
+	seq    int64
+	attrs  map[string]interface{}
+}
+
+// Simulator An in-memory, stateful simulation of the Mashery V3 API. The simulator implements transport.HttpExecutor
+// and can be supplied to the clients via transport.HTTPClientParams ExplicitHttpExecutor.
+type Simulator struct {
+	// BasePath is stripped from the path of each incoming request. Should match the path of the client's MashEndpoint.
+	BasePath string
+	// PageSize is the number of objects returned per page where the request doesn't specify the limit
+	PageSize int
+	// AccessToken, if set, is required to be sent as a bearer token with each request.
+	AccessToken string
+	// Interceptor is consulted before each request
+	Interceptor Interceptor
+
+	// Clock supplies current time for created and updated timestamps.
+	Clock func() time.Time
+
+	mutex   sync.Mutex
+	records map[string]*record
+	seq     int64
+}
+
+// NewSimulator Creates an empty simulator suitable for the client created with default MashEndpoint
+func NewSimulator() *Simulator {
+	return &Simulator{
+		BasePath: "/v3/rest",
+		PageSize: 100,
+		Clock:    time.Now,
+		records:  map[string]*record{},
+	}
+}
+
+func (s *Simulator) CloseIdleConnections() {
+	// Nothing to close
+}
+
+// Do Processes the request against the in-memory state.
+func (s *Simulator) Do(r *http.Request) (*http.Response, error) {
+	if s.Interceptor != nil {
+		if rv := s.Interceptor(r); rv != nil {
+			rv.Request = r
+			return rv, nil
+		}
+	}
+
+	if len(s.AccessToken) > 0 && r.Header.Get("Authorization") != fmt.Sprintf("Bearer %s", s.AccessToken) {
+		return errorResponse(r, 403, "ERR_403_NOT_AUTHORIZED", "Not Authorized"), nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	seg := splitPath(strings.TrimPrefix(r.URL.Path, s.BasePath))
+	meth := strings.ToUpper(r.Method)
+
+	if cs, _ := findCollection(seg); cs != nil {
+		switch meth {
+		case http.MethodGet:
+			return s.listCollection(r, cs, seg), nil
+		case http.MethodPost:
+			return s.createObject(r, cs, seg), nil
+		}
+	} else if len(seg) > 1 {
+		if cs, _ := findCollection(seg[:len(seg)-1]); cs != nil {
+			switch meth {
+			case http.MethodGet:
+				return s.getObject(r, seg), nil
+			case http.MethodPut:
+				if cs.kind != linkCollection {
+					return s.updateObject(r, seg), nil
+				}
+			case http.MethodDelete:
+				return s.deleteObject(r, seg), nil
+			}
+		}
+	}
+
+	return errorResponse(r, 403, "ERR_403_NOT_AUTHORIZED", "Not Authorized"), nil
+}
+
+// Create Stores the object in the collection, assigning it an identifier and timestamps as the V3 API would do.
+// Returns the identifier of the created object.
+func (s *Simulator) Create(collection string, obj interface{}) (string, error) {
+	dat, err := json.Marshal(obj)
+	if err != nil {
+		return "", err
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, s.BasePath+collection, bytes.NewReader(dat))
+	resp, _ := s.Do(req)
+
+	wr := transport.WrappedResponse{Response: resp}
+	body := wr.MustBody()
+	if resp.StatusCode != 200 {
+		return "", &masherytypes.V3UndeterminedError{Code: resp.StatusCode, Header: resp.Header, Body: body}
+	}
+
+	created := map[string]interface{}{}
+	if err = json.Unmarshal(body, &created); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprint(created["id"]), nil
+}
+
+// Object Returns a copy of the object stored at the specified path, including all stored attributes.
+func (s *Simulator) Object(path string) (map[string]interface{}, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if rec := s.resolve(splitPath(path)); rec != nil {
+		return s.view(rec, nil), true
+	}
+
+	return nil, false
+}
+
+func (s *Simulator) now() string {
+	return s.Clock().UTC().Format(masheryTimeFormat)
+}
+
+func (s *Simulator) nextSeq() int64 {
+	s.seq++
+	return s.seq
+}
+
+// resolve the record addressed by the path segments, or nil if it doesn't exist.
+func (s *Simulator) resolve(seg []string) *record {
+	if len(seg) < 2 {
+		return nil
+	}
+
+	cs, _ := findCollection(seg[:len(seg)-1])
+	if cs == nil {
+		return nil
+	}
+
+	if cs.kind == aliasCollection {
+		rec := s.records[fmt.Sprintf("%s/%s", cs.aliasRoot, seg[len(seg)-1])]
+		if rec != nil && rec.owner == joinPath(seg[:len(seg)-2]) {
+			return rec
+		}
+		return nil
+	}
+
+	rec := s.records[joinPath(seg)]
+	if rec != nil && rec.target != "" && s.records[rec.target] == nil {
+		return nil
+	}
+	return rec
+}
+
+// parentExists checks that the object owning the collection exists.
+func (s *Simulator) parentExists(seg []string) bool {
+	if len(seg) <= 1 {
+		return true
+	}
+
+	return s.resolve(seg[:len(seg)-1]) != nil
+}
+
+func (s *Simulator) collectionRecords(cs *collectionSpec, seg []string) []*record {
+	var rv []*record
+
+	collPath := joinPath(seg)
+	for _, rec := range s.records {
+		if cs.kind == aliasCollection {
+			if rec.collection == cs.aliasRoot && rec.owner == joinPath(seg[:len(seg)-1]) {
+				rv = append(rv, rec)
+			}
+		} else if rec.collection == collPath {
+			if rec.target == "" || s.records[rec.target] != nil {
+				rv = append(rv, rec)
+			}
+		}
+	}
+
+	sort.Slice(rv, func(i, j int) bool {
+		return rv[i].seq < rv[j].seq
+	})
+
+	return rv
+}
+
+func (s *Simulator) listCollection(r *http.Request, cs *collectionSpec, seg []string) *http.Response {
+	if !s.parentExists(seg) {
+		return errorResponse(r, 404, "ERR_404_NOT_FOUND", "Not Found")
+	}
+
+	qs := r.URL.Query()
+	filter := parseFilter(qs.Get("filter"))
+	fields := parseFields(qs.Get("fields"))
+
+	var matched []*record
+	for _, rec := range s.collectionRecords(cs, seg) {
+		if filter.matches(s.view(rec, nil)) {
+			matched = append(matched, rec)
+		}
+	}
+
+	limit := s.PageSize
+	if v, err := strconv.Atoi(qs.Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+	offset, _ := strconv.Atoi(qs.Get("offset"))
+	if cs.pagination == transport.PerPage {
+		offset *= limit
+	}
+
+	rv := []map[string]interface{}{}
+	for i := offset; i < len(matched) && i < offset+limit; i++ {
+		rv = append(rv, s.view(matched[i], fields))
+	}
+
+	resp := jsonResponse(r, 200, rv)
+	resp.Header.Set("X-Total-Count", strconv.Itoa(len(matched)))
+	return resp
+}
+
+func (s *Simulator) createObject(r *http.Request, cs *collectionSpec, seg []string) *http.Response {
+	if !s.parentExists(seg) {
+		return errorResponse(r, 404, "ERR_404_NOT_FOUND", "Not Found")
+	}
+
+	attrs, err := readAttributes(r)
+	if err != nil {
+		return errorResponse(r, 400, "ERR_400_BAD_REQUEST", err.Error())
+	}
+
+	var rec *record
+	if cs.kind == linkCollection {
+		id, _ := attrs["id"].(string)
+		target := cs.linkTarget(seg, id)
+		if len(id) == 0 || s.records[target] == nil {
+			return propertyErrorResponse(r, "id", "referenced object does not exist")
+		}
+
+		rec = &record{
+			path:       fmt.Sprintf("%s/%s", joinPath(seg), id),
+			collection: joinPath(seg),
+			target:     target,
+			seq:        s.nextSeq(),
+		}
+	} else {
+		canonical := canonicalCollection(cs)
+		delete(attrs, "id")
+		delete(attrs, "created")
+		delete(attrs, "updated")
+		if canonical.prepare != nil {
+			canonical.prepare(attrs)
+		}
+
+		id := newIdentifier()
+		attrs["id"] = id
+		attrs["created"] = s.now()
+		attrs["updated"] = attrs["created"]
+
+		rec = &record{
+			collection: joinPath(seg),
+			seq:        s.nextSeq(),
+			attrs:      attrs,
+		}
+		if cs.kind == aliasCollection {
+			rec.collection = cs.aliasRoot
+			rec.owner = joinPath(seg[:len(seg)-1])
+		}
+		rec.path = fmt.Sprintf("%s/%s", rec.collection, id)
+	}
+
+	s.records[rec.path] = rec
+	return jsonResponse(r, 200, s.view(rec, parseFields(r.URL.Query().Get("fields"))))
+}
+
+func (s *Simulator) getObject(r *http.Request, seg []string) *http.Response {
+	if rec := s.resolve(seg); rec == nil {
+		return errorResponse(r, 404, "ERR_404_NOT_FOUND", "Not Found")
+	} else {
+		return jsonResponse(r, 200, s.view(rec, parseFields(r.URL.Query().Get("fields"))))
+	}
+}
+
+func (s *Simulator) updateObject(r *http.Request, seg []string) *http.Response {
+	rec := s.resolve(seg)
+	if rec == nil {
+		return errorResponse(r, 404, "ERR_404_NOT_FOUND", "Not Found")
+	}
+
+	attrs, err := readAttributes(r)
+	if err != nil {
+		return errorResponse(r, 400, "ERR_400_BAD_REQUEST", err.Error())
+	}
+
+	for k, v := range attrs {
+		if k != "id" && k != "created" && k != "updated" && k != "passwdNew" {
+			rec.attrs[k] = v
+		}
+	}
+	rec.attrs["updated"] = s.now()
+
+	return jsonResponse(r, 200, s.view(rec, parseFields(r.URL.Query().Get("fields"))))
+}
+
+func (s *Simulator) deleteObject(r *http.Request, seg []string) *http.Response {
+	if rec := s.resolve(seg); rec == nil {
+		return errorResponse(r, 404, "ERR_404_NOT_FOUND", "Not Found")
+	} else {
+		s.remove(rec.path)
+		return emptyResponse(r, 200)
+	}
+}
+
+// remove the record together with the nested, owned and linking records.
+func (s *Simulator) remove(path string) {
+	delete(s.records, path)
+
+	for k, rec := range s.records {
+		if strings.HasPrefix(k, path+"/") {
+			delete(s.records, k)
+		} else if rec.target == path || strings.HasPrefix(rec.target, path+"/") {
+			delete(s.records, k)
+		}
+	}
+
+	for k, rec := range s.records {
+		if rec.owner == path {
+			s.remove(k)
+		}
+	}
+}
+
+// view renders the record for the response, applying fields selection.
+func (s *Simulator) view(rec *record, fields fieldSelection) map[string]interface{} {
+	attrs := rec.attrs
+	if rec.target != "" {
+		attrs = s.records[rec.target].attrs
+	}
+
+	if fields == nil {
+		rv := make(map[string]interface{}, len(attrs))
+		for k, v := range attrs {
+			rv[k] = v
+		}
+		return rv
+	}
+
+	cs, _ := findCollection(splitPath(rec.collection))
+
+	rv := map[string]interface{}{}
+	for k, sub := range fields {
+		if child := childCollection(cs, k); child != nil {
+			childSeg := append(splitPath(rec.path), k)
+
+			childViews := []map[string]interface{}{}
+			for _, childRec := range s.collectionRecords(child, childSeg) {
+				childViews = append(childViews, s.view(childRec, sub))
+			}
+			rv[k] = childViews
+		} else if v, ok := attrs[k]; ok {
+			rv[k] = sub.project(v)
+		}
+	}
+
+	return rv
+}
+
+// childCollection returns the collection nested under the objects of the specified collection
+func childCollection(cs *collectionSpec, name string) *collectionSpec {
+	if cs == nil {
+		return nil
+	}
+
+	for _, c := range collections {
+		if c.name() == name && c.isChildOf(cs) {
+			return c
+		}
+	}
+
+	return nil
+}
+
+func readAttributes(r *http.Request) (map[string]interface{}, error) {
+	rv := map[string]interface{}{}
+	if r.Body == nil {
+		return rv, nil
+	}
+
+	dat, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(dat) == 0 {
+		return rv, nil
+	}
+
+	err = json.Unmarshal(dat, &rv)
+	return rv, err
+}
+
+func newIdentifier() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	h := hex.EncodeToString(b)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:])
+}
+
+func randomString(l int) string {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+	b := make([]byte, l)
+	_, _ = rand.Read(b)
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+
+	return string(b)
+}
+
+func jsonResponse(r *http.Request, code int, v interface{}) *http.Response {
+	dat, _ := json.Marshal(v)
+	rv := emptyResponse(r, code)
+	rv.Header.Set("Content-Type", "application/json")
+	rv.Body = io.NopCloser(bytes.NewReader(dat))
+	rv.ContentLength = int64(len(dat))
+
+	return rv
+}
+
+func emptyResponse(r *http.Request, code int) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", code, http.StatusText(code)),
+		StatusCode: code,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{},
+		Body:       io.NopCloser(bytes.NewReader(nil)),
+		Request:    r,
+	}
+}
+
+func errorResponse(r *http.Request, code int, masheryCode, msg string) *http.Response {
+	rv := jsonResponse(r, code, masherytypes.V3GenericErrorResponse{
+		ErrorCode:    masheryCode,
+		ErrorMessage: msg,
+	})
+	rv.Header.Set("X-Mashery-Error-Code", masheryCode)
+
+	return rv
+}
+
+func propertyErrorResponse(r *http.Request, prop, msg string) *http.Response {
+	return jsonResponse(r, 400, masherytypes.V3PropertyErrorMessages{
+		Errors: []masherytypes.V3PropertyErrorMessage{
+			{Property: prop, Message: msg},
+		},
+	})
+}
@@ -0,0 +1,178 @@
+package v3simulator_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/masherytypes"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3client"
+	"github.com/aliakseiyanchuk/mashery-v3-go-client/v3simulator"
+	"github.com/stretchr/testify/assert"
+	"net/url"
+	"testing"
+)
+
+func clientFor(sim *v3simulator.Simulator) v3client.Client {
+	return v3simulator.NewClient(sim)
+}
+
+func TestServiceLifecycle(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	cl := clientFor(sim)
+	ctx := context.Background()
+
+	created, err := cl.CreateService(ctx, masherytypes.Service{
+		AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"},
+		Description:         "desc",
+	})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, created.Id)
+	assert.NotNil(t, created.Created)
+
+	created.Description = "updated"
+	_, err = cl.UpdateService(ctx, created)
+	assert.Nil(t, err)
+
+	read, exists, err := cl.GetService(ctx, created.Identifier())
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "updated", read.Description)
+
+	endp, err := cl.CreateEndpoint(ctx, created.Identifier(), masherytypes.Endpoint{
+		AddressableV3Object: masherytypes.AddressableV3Object{Name: "endp"},
+	})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, endp.Id)
+
+	cnt, err := cl.CountEndpointsOf(ctx, created.Identifier())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), cnt)
+
+	assert.Nil(t, cl.DeleteService(ctx, created.Identifier()))
+
+	_, exists, err = cl.GetService(ctx, created.Identifier())
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	_, exists, err = cl.GetEndpoint(ctx, endp.Identifier())
+	assert.Nil(t, err)
+	assert.False(t, exists)
+
+	assert.NotNil(t, cl.DeleteService(ctx, created.Identifier()))
+}
+
+func TestPaginationAndFilter(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	sim.PageSize = 3
+	cl := clientFor(sim)
+	ctx := context.Background()
+
+	for i := 0; i < 7; i++ {
+		_, err := sim.Create("/services", masherytypes.Service{
+			AddressableV3Object: masherytypes.AddressableV3Object{Name: fmt.Sprintf("svc-%d", i)},
+		})
+		assert.Nil(t, err)
+
+		_, err = sim.Create("/members", masherytypes.Member{
+			Username: fmt.Sprintf("user-%d", i),
+		})
+		assert.Nil(t, err)
+	}
+
+	services, err := cl.ListServices(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, len(services))
+
+	members, err := cl.ListMembers(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, len(members))
+
+	filtered, err := cl.ListServicesFiltered(ctx, map[string]string{"name": "svc-4"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(filtered))
+	assert.Equal(t, "svc-4", filtered[0].Name)
+}
+
+func TestFieldsSelection(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	cl := clientFor(sim)
+	ctx := context.Background()
+
+	svcId, _ := sim.Create("/services", masherytypes.Service{
+		AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"},
+		Description:         "desc",
+	})
+	_, _ = sim.Create(fmt.Sprintf("/services/%s/endpoints", svcId), masherytypes.Endpoint{
+		AddressableV3Object: masherytypes.AddressableV3Object{Name: "endp"},
+	})
+
+	wcl := v3client.NewWildcardClient(v3simulator.ClientParams(sim))
+
+	resp, err := wcl.FetchAny(ctx, fmt.Sprintf("/services/%s", svcId), nil)
+	assert.Nil(t, err)
+	assert.Contains(t, string(resp.MustBody()), "desc")
+
+	resp, err = wcl.FetchAny(ctx, fmt.Sprintf("/services/%s", svcId), &url.Values{"fields": {"id,endpoints.name"}})
+	assert.Nil(t, err)
+	assert.NotContains(t, string(resp.MustBody()), "desc")
+	assert.Contains(t, string(resp.MustBody()), "\"endpoints\":[{\"name\":\"endp\"}]")
+
+	endpoints, err := cl.ListEndpoints(ctx, masherytypes.ServiceIdentityFrom(svcId))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(endpoints))
+}
+
+func TestPlanServicesAndApplications(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	cl := clientFor(sim)
+	ctx := context.Background()
+
+	svcId, _ := sim.Create("/services", masherytypes.Service{AddressableV3Object: masherytypes.AddressableV3Object{Name: "svc"}})
+	pack, err := cl.CreatePackage(ctx, masherytypes.Package{AddressableV3Object: masherytypes.AddressableV3Object{Name: "pack"}})
+	assert.Nil(t, err)
+
+	plan, err := cl.CreatePlan(ctx, pack.Identifier(), masherytypes.Plan{AddressableV3Object: masherytypes.AddressableV3Object{Name: "plan"}})
+	assert.Nil(t, err)
+
+	planSvc := masherytypes.PackagePlanServiceIdentifier{
+		PackagePlanIdentifier: plan.Identifier(),
+		ServiceIdentifier:     masherytypes.ServiceIdentityFrom(svcId),
+	}
+	_, err = cl.CreatePlanService(ctx, planSvc)
+	assert.Nil(t, err)
+
+	planServices, err := cl.ListPlanServices(ctx, plan.Identifier())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(planServices))
+	assert.Equal(t, "svc", planServices[0].Name)
+
+	member, err := cl.CreateMember(ctx, masherytypes.Member{Username: "user"})
+	assert.Nil(t, err)
+
+	app, err := cl.CreateApplication(ctx, member.Identifier(), masherytypes.Application{AddressableV3Object: masherytypes.AddressableV3Object{Name: "app"}})
+	assert.Nil(t, err)
+
+	key, err := cl.CreateApplicationPackageKey(ctx, app.Identifier(), masherytypes.ApplicationPackageKey{})
+	assert.Nil(t, err)
+	assert.NotNil(t, key.Apikey)
+	assert.NotNil(t, key.Secret)
+
+	keys, err := cl.ListPackageKeys(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(keys))
+
+	assert.Nil(t, cl.DeleteMember(ctx, member.Identifier()))
+
+	keys, err = cl.ListPackageKeys(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(keys))
+}
+
+func TestUnauthorizedAccess(t *testing.T) {
+	sim := v3simulator.NewSimulator()
+	sim.AccessToken = "other-token"
+	cl := clientFor(sim)
+
+	_, err := cl.ListServices(context.Background())
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "ERR_403_NOT_AUTHORIZED")
+}